@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"strings"
 
@@ -22,6 +23,7 @@ type API struct {
 	db        *db.Database
 	twitchAPI twitch.ITwitchAPI
 	twitchIRC *twitchirc.Client
+	listeners []StreamListener
 }
 
 func New(config Config, database *db.Database, twitchAPI twitch.ITwitchAPI, twitchIRC *twitchirc.Client) *API {
@@ -33,14 +35,33 @@ func New(config Config, database *db.Database, twitchAPI twitch.ITwitchAPI, twit
 	}
 }
 
+// AddStreamListener
+// Registers a listener for stream lifecycle events, must be called before InitAPIAndListen
+func (api *API) AddStreamListener(listener StreamListener) {
+	api.listeners = append(api.listeners, listener)
+}
+
 func (api *API) InitAPIAndListen() error {
-	poller := NewStreamPoller(api.db, api.twitchAPI)
+	poller := NewStreamPoller(api.db, api.twitchAPI, api.listeners)
 	poller.RestartStreamStatusPolls()
 
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/question/", api.getQuestion)
+	mux.HandleFunc("/question/", api.handleQuestionByID)
 	mux.HandleFunc("/question", api.handleQuestionWrites)
+	mux.HandleFunc("/questions", api.listQuestions)
+	mux.HandleFunc("/questions/search", api.searchQuestions)
+	mux.HandleFunc("/questions/schedule", api.handleQuestionSchedule)
+	mux.HandleFunc("/questions/import", api.importQuestions)
+	mux.HandleFunc("/questions/export", api.exportQuestions)
+	mux.HandleFunc("/trivia/import", api.importTrivia)
+	mux.HandleFunc("/dice/history", api.diceHistory)
+	mux.HandleFunc("/dice/verify/", api.verifyRoll)
+	mux.HandleFunc("/users/", api.userWatchTime)
+	mux.HandleFunc("/timers", api.handleTimers)
+	mux.HandleFunc("/timers/", api.deleteTimer)
+	mux.HandleFunc("/quotes", api.listQuotes)
+	mux.HandleFunc("/quotes/export", api.exportQuotes)
 	mux.HandleFunc("/register", api.handleRegisterUser)
 	mux.HandleFunc("/oauth2/register", api.handleOAuthRegisterUser)
 	mux.HandleFunc("/golive", poller.goliveHandler)
@@ -52,23 +73,41 @@ func (api *API) AuthenticateRequest(res http.ResponseWriter, req *http.Request)
 	authHeader := req.Header.Get("Authorization")
 	split := strings.Split(authHeader, " ")
 	if split[0] != "Basic" || len(split) != 2 {
-		res.WriteHeader(http.StatusBadRequest)
-		res.Write([]byte("Invalid Authorization Header"))
+		res.Header().Set("WWW-Authenticate", `Basic realm="soulxbot"`)
+		writeError(res, http.StatusUnauthorized, "Invalid Authorization Header")
 		return false
 	}
 
 	decoded, err := base64.StdEncoding.DecodeString(split[1])
 	if err != nil {
-		res.WriteHeader(http.StatusUnauthorized)
-		res.Write([]byte("Authentication Failed"))
+		writeError(res, http.StatusUnauthorized, "Authentication Failed")
 		return false
 	}
 
 	if api.config.BasicAuth != string(decoded) {
-		res.WriteHeader(http.StatusUnauthorized)
-		res.Write([]byte("Authentication Failed"))
+		writeError(res, http.StatusUnauthorized, "Authentication Failed")
 		return false
 	}
 
 	return true
 }
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError
+// Writes the status code with a JSON error body
+func writeError(res http.ResponseWriter, status int, message string) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(ErrorResponse{Error: message})
+}
+
+// writeJSON
+// Writes the status code with the value encoded as the JSON body
+func writeJSON(res http.ResponseWriter, status int, value any) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(value)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/soulxburn/soulxbot/dice"
+)
+
+type DiceHistoryResponse struct {
+	Games []db.DiceGame `json:"games"`
+	Total int           `json:"total"`
+	Page  int           `json:"page"`
+	Limit int           `json:"limit"`
+}
+
+// diceHistory
+// GET /dice/history?username=channel&mode=evenodd&page=n&limit=n
+// Lists the channel's played dice games, newest first
+func (api *API) diceHistory(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	params := req.URL.Query()
+	streamUser, ok := api.findStreamUser(res, params.Get("username"))
+	if !ok {
+		return
+	}
+	page, limit, ok := parsePagination(res, req)
+	if !ok {
+		return
+	}
+
+	filter := db.DiceGameFilter{
+		UserId: streamUser.UserId,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	}
+	if mode := strings.ToLower(params.Get("mode")); mode != "" {
+		filter.Mode = &mode
+	}
+
+	games, total, err := api.db.FindDiceGames(filter)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to list dice games")
+		return
+	}
+
+	writeJSON(res, http.StatusOK, DiceHistoryResponse{
+		Games: games,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+// verifyRoll
+// GET /dice/verify/{id}
+// Replays a finished dice prediction from its revealed seed, so anyone can check the roll was fair
+func (api *API) verifyRoll(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(req.URL.Path, "/dice/verify/"))
+	if err != nil {
+		writeError(res, http.StatusBadRequest, "Unable to parse id")
+		return
+	}
+
+	record, ok := api.db.FindPredictionByID(id)
+	if !ok {
+		writeError(res, http.StatusNotFound, "No roll found with that id")
+		return
+	}
+
+	verification, err := dice.VerifyPrediction(*record)
+	if errors.Is(err, dice.ErrSeedNotRevealed) || errors.Is(err, dice.ErrNoSeed) {
+		writeError(res, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to replay the roll")
+		return
+	}
+
+	writeJSON(res, http.StatusOK, verification)
+}
@@ -10,13 +10,20 @@ import (
 	"github.com/soulxburn/soulxbot/twitch"
 )
 
+// StreamListener is notified when a stream goes live, or is seen to have ended
+type StreamListener interface {
+	OnStreamStart(stream *db.Stream, streamUser *db.User)
+	OnStreamEnd(stream *db.Stream, streamUser *db.User)
+}
+
 type StreamPoller struct {
 	db        *db.Database
 	twitchAPI twitch.ITwitchAPI
+	listeners []StreamListener
 }
 
-func NewStreamPoller(db *db.Database, twitchAPI twitch.ITwitchAPI) StreamPoller {
-	return StreamPoller{db, twitchAPI}
+func NewStreamPoller(db *db.Database, twitchAPI twitch.ITwitchAPI, listeners []StreamListener) StreamPoller {
+	return StreamPoller{db, twitchAPI, listeners}
 }
 
 func (sp StreamPoller) goliveHandler(res http.ResponseWriter, req *http.Request) {
@@ -56,6 +63,10 @@ func (sp StreamPoller) PollStreamStatus(stream *db.Stream, streamUser *db.User,
 		}
 	}
 
+	for _, listener := range sp.listeners {
+		listener.OnStreamStart(stream, streamUser)
+	}
+
 	tick := time.NewTicker(5 * time.Minute)
 	for {
 		select {
@@ -66,6 +77,9 @@ func (sp StreamPoller) PollStreamStatus(stream *db.Stream, streamUser *db.User,
 				continue
 			} else if !live {
 				tick.Stop()
+				for _, listener := range sp.listeners {
+					listener.OnStreamEnd(stream, streamUser)
+				}
 				return
 			}
 		}
@@ -6,46 +6,191 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/soulxburn/soulxbot/db"
+)
+
+const (
+	DEFAULT_PAGE_LIMIT = 50
+	MAX_PAGE_LIMIT     = 200
 )
 
 type QuestionRequestBody struct {
-	Question string `json:"question"`
+	Question string   `json:"question"`
+	Tags     []string `json:"tags"`
+	// Creates the question even when similar questions already exist
+	Force bool `json:"force"`
+}
+
+type DuplicateQuestionResponse struct {
+	Error      string               `json:"error"`
+	Duplicates []db.SimilarQuestion `json:"duplicates"`
 }
 
+// QuestionPatchBody
+// Only the fields that are present are updated. The id may be omitted when it is in the path.
 type QuestionPatchBody struct {
-	ID       int  `json:"id"`
-	Disabled bool `json:"disabled"`
+	ID       int     `json:"id"`
+	Text     *string `json:"text"`
+	Disabled *bool   `json:"disabled"`
+}
+
+type QuestionListResponse struct {
+	Questions []db.Question `json:"questions"`
+	Total     int           `json:"total"`
+	Page      int           `json:"page"`
+	Limit     int           `json:"limit"`
 }
 
 func (api *API) handleQuestionWrites(res http.ResponseWriter, req *http.Request) {
 	switch req.Method {
-	case "PATCH":
-		api.patchQuestion(res, req)
-	case "POST":
+	case http.MethodPatch:
+		api.patchQuestion(res, req, 0)
+	case http.MethodPost:
 		api.createQuestion(res, req)
 	default:
-		log.Println("Invalid verb encountered")
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
 	}
 }
 
-func (api *API) getQuestion(res http.ResponseWriter, req *http.Request) {
-	id, err := strconv.Atoi(strings.Split(req.URL.Path, "/")[2])
+// handleQuestionByID
+// GET, PATCH and DELETE /question/{id}
+func (api *API) handleQuestionByID(res http.ResponseWriter, req *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(req.URL.Path, "/question/"))
 	if err != nil {
-		res.WriteHeader(http.StatusBadRequest)
-		res.Write([]byte("Unable to parse id"))
+		writeError(res, http.StatusBadRequest, "Unable to parse id")
 		return
 	}
 
+	switch req.Method {
+	case http.MethodGet:
+		api.getQuestion(res, req, id)
+	case http.MethodPatch:
+		api.patchQuestion(res, req, id)
+	case http.MethodDelete:
+		api.deleteQuestion(res, req, id)
+	default:
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (api *API) getQuestion(res http.ResponseWriter, req *http.Request, id int) {
 	question, ok := api.db.FindQuestionByID(id)
 	if !ok {
-		res.WriteHeader(http.StatusNotFound)
-		res.Write([]byte("No question found with that id"))
+		writeError(res, http.StatusNotFound, "No question found with that id")
+		return
 	}
 
-	json.NewEncoder(res).Encode(question)
+	writeJSON(res, http.StatusOK, question)
 }
 
-func (api *API) patchQuestion(res http.ResponseWriter, req *http.Request) {
+// listQuestions
+// GET /questions?disabled=true|false&q=text&minSkips=n&tag=t&page=n&limit=n
+func (api *API) listQuestions(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	params := req.URL.Query()
+	var filter db.QuestionFilter
+	if disabled := params.Get("disabled"); disabled != "" {
+		value, err := strconv.ParseBool(disabled)
+		if err != nil {
+			writeError(res, http.StatusBadRequest, "disabled must be true or false")
+			return
+		}
+		filter.Disabled = &value
+	}
+	if text := params.Get("q"); text != "" {
+		filter.Text = &text
+	}
+	if minSkips := params.Get("minSkips"); minSkips != "" {
+		value, err := strconv.Atoi(minSkips)
+		if err != nil || value < 0 {
+			writeError(res, http.StatusBadRequest, "minSkips must be a positive number")
+			return
+		}
+		filter.MinSkips = &value
+	}
+	if tag := params.Get("tag"); tag != "" {
+		tag = strings.ToLower(tag)
+		filter.Tag = &tag
+	}
+
+	page, limit, ok := parsePagination(res, req)
+	if !ok {
+		return
+	}
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	questions, total, err := api.db.FindQuestions(filter)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to list questions")
+		return
+	}
+
+	writeJSON(res, http.StatusOK, QuestionListResponse{
+		Questions: questions,
+		Total:     total,
+		Page:      page,
+		Limit:     limit,
+	})
+}
+
+// searchQuestions
+// GET /questions/search?q=words&limit=n
+func (api *API) searchQuestions(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := strings.TrimSpace(req.URL.Query().Get("q"))
+	if query == "" {
+		writeError(res, http.StatusBadRequest, "q is required")
+		return
+	}
+	_, limit, ok := parsePagination(res, req)
+	if !ok {
+		return
+	}
+
+	results, err := api.db.SearchQuestions(query, limit)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to search questions")
+		return
+	}
+
+	writeJSON(res, http.StatusOK, results)
+}
+
+// parsePagination
+// Reads the 1-based page and page size, writing a bad request if either is invalid
+func parsePagination(res http.ResponseWriter, req *http.Request) (int, int, bool) {
+	params := req.URL.Query()
+	page, limit := 1, DEFAULT_PAGE_LIMIT
+	if value := params.Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			writeError(res, http.StatusBadRequest, "page must be a number greater than zero")
+			return 0, 0, false
+		}
+		page = parsed
+	}
+	if value := params.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > MAX_PAGE_LIMIT {
+			writeError(res, http.StatusBadRequest, "limit must be a number from 1 to 200")
+			return 0, 0, false
+		}
+		limit = parsed
+	}
+	return page, limit, true
+}
+
+func (api *API) patchQuestion(res http.ResponseWriter, req *http.Request, id int) {
 	authenticated := api.AuthenticateRequest(res, req)
 	if !authenticated {
 		return
@@ -54,27 +199,73 @@ func (api *API) patchQuestion(res http.ResponseWriter, req *http.Request) {
 	var body QuestionPatchBody
 	err := json.NewDecoder(req.Body).Decode(&body)
 	if err != nil {
-		res.WriteHeader(http.StatusBadRequest)
-		res.Write([]byte("Invalid Request"))
+		writeError(res, http.StatusBadRequest, "Invalid Request")
+		return
+	}
+	if id == 0 {
+		id = body.ID
+	} else if body.ID != 0 && body.ID != id {
+		writeError(res, http.StatusBadRequest, "Body id does not match the path")
+		return
+	}
+	if body.Text == nil && body.Disabled == nil {
+		writeError(res, http.StatusBadRequest, "Nothing to update, expected text or disabled")
 		return
 	}
 
-	_, ok := api.db.FindQuestionByID(body.ID)
+	question, ok := api.db.FindQuestionByID(id)
 	if !ok {
-		res.WriteHeader(http.StatusNotFound)
-		res.Write([]byte("Question id does not exist"))
+		writeError(res, http.StatusNotFound, "Question id does not exist")
 		return
 	}
 
-	// TODO: Allow for ENABLING a disabled question
-	err = api.db.DisableQuestion(body.ID)
-	if err != nil {
-		res.WriteHeader(http.StatusConflict)
-		res.Write([]byte(strings.TrimSpace(err.Error())))
+	if body.Text != nil {
+		text := strings.TrimSpace(*body.Text)
+		if text == "" || len(text) > db.MAX_QUESTION_LENGTH {
+			writeError(res, http.StatusBadRequest, "Question text must be 1 to 500 characters")
+			return
+		}
+		if text != question.Text {
+			if err := api.db.UpdateQuestionText(id, text); err != nil {
+				writeError(res, http.StatusConflict, strings.TrimSpace(err.Error()))
+				return
+			}
+		}
+	}
+
+	if body.Disabled != nil && *body.Disabled != question.Disabled {
+		if *body.Disabled {
+			err = api.db.DisableQuestion(id)
+		} else {
+			err = api.db.EnableQuestion(id)
+		}
+		if err != nil {
+			writeError(res, http.StatusInternalServerError, "Unable to update question")
+			return
+		}
+	}
+
+	question, _ = api.db.FindQuestionByID(id)
+	writeJSON(res, http.StatusOK, question)
+}
+
+func (api *API) deleteQuestion(res http.ResponseWriter, req *http.Request, id int) {
+	authenticated := api.AuthenticateRequest(res, req)
+	if !authenticated {
+		return
 	}
 
-	question, _ := api.db.FindQuestionByID(body.ID)
-	json.NewEncoder(res).Encode(question)
+	if _, ok := api.db.FindQuestionByID(id); !ok {
+		writeError(res, http.StatusNotFound, "Question id does not exist")
+		return
+	}
+
+	if err := api.db.DeleteQuestion(id); err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to delete question")
+		return
+	}
+	log.Printf("Deleted question id=%d", id)
+	res.WriteHeader(http.StatusNoContent)
 }
 
 func (api *API) createQuestion(res http.ResponseWriter, req *http.Request) {
@@ -86,16 +277,30 @@ func (api *API) createQuestion(res http.ResponseWriter, req *http.Request) {
 	var body QuestionRequestBody
 	err := json.NewDecoder(req.Body).Decode(&body)
 	if err != nil || body.Question == "" {
-		res.WriteHeader(http.StatusBadRequest)
-		res.Write([]byte("Invalid Request"))
+		writeError(res, http.StatusBadRequest, "Invalid Request")
 		return
 	}
 
+	if !body.Force {
+		similar, err := api.db.FindSimilarQuestions(body.Question)
+		if err != nil {
+			writeError(res, http.StatusInternalServerError, "Unable to check for duplicate questions")
+			return
+		}
+		if len(similar) > 0 {
+			writeJSON(res, http.StatusConflict, DuplicateQuestionResponse{
+				Error:      "Similar questions already exist, set force to create it anyway",
+				Duplicates: similar,
+			})
+			return
+		}
+	}
+
 	question, err := api.db.CreateQuestion(body.Question)
 	if err != nil {
-		res.WriteHeader(http.StatusConflict)
-		res.Write([]byte(strings.TrimSpace(err.Error())))
+		writeError(res, http.StatusConflict, strings.TrimSpace(err.Error()))
+		return
 	}
 
-	json.NewEncoder(res).Encode(question)
+	writeJSON(res, http.StatusCreated, question)
 }
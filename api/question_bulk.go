@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/soulxburn/soulxbot/db"
+)
+
+type QuestionImportResponse struct {
+	Created   int                       `json:"created"`
+	Duplicate int                       `json:"duplicate"`
+	Invalid   int                       `json:"invalid"`
+	Results   []db.QuestionImportResult `json:"results"`
+}
+
+// importQuestions
+// POST /questions/import
+// Accepts a JSON array of questions, or CSV when sent as text/csv or with ?format=csv
+func (api *API) importQuestions(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	authenticated := api.AuthenticateRequest(res, req)
+	if !authenticated {
+		return
+	}
+
+	var imports []db.QuestionImport
+	var err error
+	if isCSVRequest(req) {
+		imports, err = parseQuestionCSV(req.Body)
+	} else {
+		imports, err = parseQuestionJSON(req.Body)
+	}
+	if err != nil {
+		writeError(res, http.StatusBadRequest, "Invalid Request: "+err.Error())
+		return
+	}
+
+	results, err := api.db.ImportQuestions(imports)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Question import failed, no questions were created")
+		return
+	}
+
+	response := QuestionImportResponse{Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case db.IMPORT_CREATED:
+			response.Created++
+		case db.IMPORT_DUPLICATE:
+			response.Duplicate++
+		case db.IMPORT_INVALID:
+			response.Invalid++
+		}
+	}
+	log.Printf("Imported questions: %d created, %d duplicate, %d invalid", response.Created, response.Duplicate, response.Invalid)
+
+	writeJSON(res, http.StatusOK, response)
+}
+
+// exportQuestions
+// GET /questions/export?format=csv|json
+func (api *API) exportQuestions(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		writeError(res, http.StatusBadRequest, "Invalid format, expected csv or json")
+		return
+	}
+
+	questions, err := api.db.FindAllQuestions()
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to export questions")
+		return
+	}
+
+	if format == "json" {
+		writeJSON(res, http.StatusOK, questions)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/csv")
+	res.Header().Set("Content-Disposition", `attachment; filename="questions.csv"`)
+	writer := csv.NewWriter(res)
+	writer.Write([]string{"id", "question", "tags", "disabled", "skipCount"})
+	for _, question := range questions {
+		writer.Write([]string{
+			strconv.Itoa(question.ID),
+			question.Text,
+			strings.Join(question.Tags, ";"),
+			strconv.FormatBool(question.Disabled),
+			strconv.Itoa(question.SkipCount),
+		})
+	}
+	writer.Flush()
+}
+
+func isCSVRequest(req *http.Request) bool {
+	if req.URL.Query().Get("format") == "csv" {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return mediaType == "text/csv"
+}
+
+func parseQuestionJSON(body io.Reader) ([]db.QuestionImport, error) {
+	var questions []QuestionRequestBody
+	if err := json.NewDecoder(body).Decode(&questions); err != nil {
+		return nil, err
+	}
+
+	imports := make([]db.QuestionImport, len(questions))
+	for i, question := range questions {
+		imports[i] = db.QuestionImport{Row: i + 1, Text: question.Question, Tags: question.Tags}
+	}
+	return imports, nil
+}
+
+// parseQuestionCSV
+// Reads the question and tags columns by header name, the same format as the export.
+// Without a header row, the first column is the question and the second is the tags.
+func parseQuestionCSV(body io.Reader) ([]db.QuestionImport, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	questionCol, tagsCol := 0, 1
+	imports := []db.QuestionImport{}
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if first {
+			if q, t, ok := findQuestionCSVHeader(record); ok {
+				questionCol, tagsCol = q, t
+				continue
+			}
+		}
+
+		line, _ := reader.FieldPos(0)
+		imp := db.QuestionImport{Row: line}
+		if questionCol < len(record) {
+			imp.Text = record[questionCol]
+		}
+		if tagsCol >= 0 && tagsCol < len(record) {
+			imp.Tags = []string{record[tagsCol]}
+		}
+		imports = append(imports, imp)
+	}
+	return imports, nil
+}
+
+func findQuestionCSVHeader(record []string) (int, int, bool) {
+	questionCol, tagsCol := -1, -1
+	for i, column := range record {
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "question", "text":
+			questionCol = i
+		case "tags":
+			tagsCol = i
+		}
+	}
+	return questionCol, tagsCol, questionCol >= 0
+}
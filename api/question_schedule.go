@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/soulxburn/soulxbot/db"
+)
+
+// ScheduleQuestionBody
+// Queues a question for a date, or the next stream when the date is omitted
+type ScheduleQuestionBody struct {
+	Username   string  `json:"username"`
+	QuestionID int     `json:"questionId"`
+	Date       *string `json:"date"`
+}
+
+// handleQuestionSchedule
+// GET and POST /questions/schedule
+func (api *API) handleQuestionSchedule(res http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		api.getQuestionSchedule(res, req)
+	case http.MethodPost:
+		api.scheduleQuestion(res, req)
+	default:
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// getQuestionSchedule
+// GET /questions/schedule?username=channel
+func (api *API) getQuestionSchedule(res http.ResponseWriter, req *http.Request) {
+	streamUser, ok := api.findStreamUser(res, req.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+
+	scheduled, err := api.db.FindPendingScheduledQuestions(streamUser.UserId)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to find scheduled questions")
+		return
+	}
+
+	writeJSON(res, http.StatusOK, scheduled)
+}
+
+func (api *API) scheduleQuestion(res http.ResponseWriter, req *http.Request) {
+	authenticated := api.AuthenticateRequest(res, req)
+	if !authenticated {
+		return
+	}
+
+	var body ScheduleQuestionBody
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(res, http.StatusBadRequest, "Invalid Request")
+		return
+	}
+	if body.Date != nil {
+		if _, err := time.Parse(db.SCHEDULE_DATE_FORMAT, *body.Date); err != nil {
+			writeError(res, http.StatusBadRequest, "date must be formatted as YYYY-MM-DD")
+			return
+		}
+	}
+
+	streamUser, ok := api.findStreamUser(res, body.Username)
+	if !ok {
+		return
+	}
+	question, ok := api.db.FindQuestionByID(body.QuestionID)
+	if !ok {
+		writeError(res, http.StatusNotFound, "Question id does not exist")
+		return
+	}
+
+	scheduled, err := api.db.ScheduleQuestion(streamUser.UserId, body.QuestionID, body.Date)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to schedule question")
+		return
+	}
+	scheduled.Text = question.Text
+
+	writeJSON(res, http.StatusCreated, scheduled)
+}
+
+// findStreamUser
+// Finds the registered stream user, writing an error response if they can't be found
+func (api *API) findStreamUser(res http.ResponseWriter, username string) (*db.StreamUser, bool) {
+	if username == "" {
+		writeError(res, http.StatusBadRequest, "username is required")
+		return nil, false
+	}
+
+	streamUser, err := api.db.FindStreamUserByUserName(strings.ToLower(username))
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to find stream user")
+		return nil, false
+	}
+	if streamUser == nil {
+		writeError(res, http.StatusNotFound, "No registered stream user with that username")
+		return nil, false
+	}
+	return streamUser, true
+}
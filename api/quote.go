@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/soulxburn/soulxbot/db"
+)
+
+type QuoteListResponse struct {
+	Quotes []db.Quote `json:"quotes"`
+	Total  int        `json:"total"`
+	Page   int        `json:"page"`
+	Limit  int        `json:"limit"`
+}
+
+// listQuotes
+// GET /quotes?username=channel&page=n&limit=n
+func (api *API) listQuotes(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	streamUser, ok := api.findStreamUser(res, req.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+	page, limit, ok := parsePagination(res, req)
+	if !ok {
+		return
+	}
+
+	quotes, total, err := api.db.FindQuotes(streamUser.UserId, limit, (page-1)*limit)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to list quotes")
+		return
+	}
+
+	writeJSON(res, http.StatusOK, QuoteListResponse{
+		Quotes: quotes,
+		Total:  total,
+		Page:   page,
+		Limit:  limit,
+	})
+}
+
+// exportQuotes
+// GET /quotes/export?username=channel&format=csv|json
+func (api *API) exportQuotes(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		writeError(res, http.StatusBadRequest, "Invalid format, expected csv or json")
+		return
+	}
+	streamUser, ok := api.findStreamUser(res, req.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+
+	quotes, _, err := api.db.FindQuotes(streamUser.UserId, -1, 0)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to export quotes")
+		return
+	}
+
+	if format == "json" {
+		writeJSON(res, http.StatusOK, quotes)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/csv")
+	res.Header().Set("Content-Disposition", `attachment; filename="quotes.csv"`)
+	writer := csv.NewWriter(res)
+	writer.Write([]string{"number", "text", "quotee", "game", "streamTitle", "createdAt"})
+	for _, quote := range quotes {
+		writer.Write([]string{
+			strconv.Itoa(quote.Number),
+			quote.Text,
+			quote.Quotee,
+			valueOrEmpty(quote.Game),
+			valueOrEmpty(quote.StreamTitle),
+			quote.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	writer.Flush()
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/soulxburn/soulxbot/db"
+)
+
+// TimedMessageBody
+// Adds a timed message to the channel. Mode defaults to always.
+type TimedMessageBody struct {
+	Username        string `json:"username"`
+	Message         string `json:"message"`
+	IntervalMinutes int    `json:"intervalMinutes"`
+	MinLines        int    `json:"minLines"`
+	Mode            string `json:"mode"`
+}
+
+// handleTimers
+// GET and POST /timers
+func (api *API) handleTimers(res http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		api.listTimers(res, req)
+	case http.MethodPost:
+		api.createTimer(res, req)
+	default:
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// listTimers
+// GET /timers?username=channel
+func (api *API) listTimers(res http.ResponseWriter, req *http.Request) {
+	streamUser, ok := api.findStreamUser(res, req.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+
+	messages, err := api.db.FindTimedMessages(streamUser.UserId)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to find timers")
+		return
+	}
+
+	writeJSON(res, http.StatusOK, messages)
+}
+
+func (api *API) createTimer(res http.ResponseWriter, req *http.Request) {
+	authenticated := api.AuthenticateRequest(res, req)
+	if !authenticated {
+		return
+	}
+
+	var body TimedMessageBody
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(res, http.StatusBadRequest, "Invalid Request")
+		return
+	}
+	streamUser, ok := api.findStreamUser(res, body.Username)
+	if !ok {
+		return
+	}
+
+	message := db.TimedMessage{
+		ChannelId:       streamUser.UserId,
+		Message:         strings.TrimSpace(body.Message),
+		IntervalMinutes: body.IntervalMinutes,
+		MinLines:        body.MinLines,
+		Mode:            body.Mode,
+	}
+	if message.Mode == "" {
+		message.Mode = db.TIMED_MESSAGE_ALWAYS
+	}
+	if problem := db.ValidateTimedMessage(message); problem != "" {
+		writeError(res, http.StatusBadRequest, problem)
+		return
+	}
+
+	inserted, err := api.db.InsertTimedMessage(message)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to create timer")
+		return
+	}
+
+	writeJSON(res, http.StatusCreated, inserted)
+}
+
+// deleteTimer
+// DELETE /timers/{id}?username=channel
+func (api *API) deleteTimer(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	authenticated := api.AuthenticateRequest(res, req)
+	if !authenticated {
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(req.URL.Path, "/timers/"))
+	if err != nil {
+		writeError(res, http.StatusBadRequest, "Unable to parse id")
+		return
+	}
+	streamUser, ok := api.findStreamUser(res, req.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+
+	deleted, err := api.db.DeleteTimedMessage(streamUser.UserId, id)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to delete timer")
+		return
+	}
+	if !deleted {
+		writeError(res, http.StatusNotFound, "No timer with that id in the channel")
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/soulxburn/soulxbot/db"
+)
+
+type TriviaRequestBody struct {
+	Question   string   `json:"question"`
+	Answers    []string `json:"answers"`
+	Category   string   `json:"category"`
+	Difficulty string   `json:"difficulty"`
+}
+
+type TriviaImportResponse struct {
+	Created   int                     `json:"created"`
+	Duplicate int                     `json:"duplicate"`
+	Invalid   int                     `json:"invalid"`
+	Results   []db.TriviaImportResult `json:"results"`
+}
+
+// importTrivia
+// POST /trivia/import
+// Accepts a trivia pack, a JSON array of questions with their accepted answers
+func (api *API) importTrivia(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	authenticated := api.AuthenticateRequest(res, req)
+	if !authenticated {
+		return
+	}
+
+	var pack []TriviaRequestBody
+	if err := json.NewDecoder(req.Body).Decode(&pack); err != nil {
+		writeError(res, http.StatusBadRequest, "Invalid Request: "+err.Error())
+		return
+	}
+
+	imports := make([]db.TriviaImport, len(pack))
+	for i, trivia := range pack {
+		imports[i] = db.TriviaImport{
+			Row:        i + 1,
+			Question:   trivia.Question,
+			Answers:    trivia.Answers,
+			Category:   trivia.Category,
+			Difficulty: trivia.Difficulty,
+		}
+	}
+
+	results, err := api.db.ImportTrivia(imports)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Trivia import failed, no questions were created")
+		return
+	}
+
+	response := TriviaImportResponse{Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case db.IMPORT_CREATED:
+			response.Created++
+		case db.IMPORT_DUPLICATE:
+			response.Duplicate++
+		case db.IMPORT_INVALID:
+			response.Invalid++
+		}
+	}
+	log.Printf("Imported trivia: %d created, %d duplicate, %d invalid", response.Created, response.Duplicate, response.Invalid)
+
+	writeJSON(res, http.StatusOK, response)
+}
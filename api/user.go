@@ -31,7 +31,7 @@ func (api *API) handleRegisterUser(res http.ResponseWriter, req *http.Request) {
 	query.Add("client_id", api.config.ClientID)
 	query.Add("redirect_uri", api.config.RedirectURI)
 	query.Add("response_type", "code")
-	query.Add("scope", "openid channel:read:redemptions channel:manage:predictions moderator:manage:banned_users")
+	query.Add("scope", "openid channel:read:redemptions channel:manage:predictions moderator:manage:banned_users moderation:read channel:read:vips moderator:read:followers channel:manage:polls")
 	query.Add("claims", string(claimsJson))
 	redirect.RawQuery = query.Encode()
 
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/soulxburn/soulxbot/db"
+)
+
+type WatchTimeResponse struct {
+	UserId  int                  `json:"userId"`
+	Seconds int                  `json:"seconds"`
+	Streams []db.StreamWatchTime `json:"streams"`
+}
+
+// userWatchTime
+// GET /users/{id}/watchtime?username=channel
+// Returns how long the viewer has watched each stream, newest first, and in total.
+// Without a channel username, streams from every channel are included.
+func (api *API) userWatchTime(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	path := strings.TrimPrefix(req.URL.Path, "/users/")
+	idPath, ok := strings.CutSuffix(path, "/watchtime")
+	if !ok {
+		writeError(res, http.StatusNotFound, "Not found")
+		return
+	}
+	userId, err := strconv.Atoi(idPath)
+	if err != nil {
+		writeError(res, http.StatusBadRequest, "Unable to parse id")
+		return
+	}
+	if _, ok := api.db.FindUserByID(userId); !ok {
+		writeError(res, http.StatusNotFound, "No user found with that id")
+		return
+	}
+
+	var channelId *int
+	if username := req.URL.Query().Get("username"); username != "" {
+		streamUser, ok := api.findStreamUser(res, username)
+		if !ok {
+			return
+		}
+		channelId = &streamUser.UserId
+	}
+
+	streams, err := api.db.FindUserWatchTime(userId, channelId)
+	if err != nil {
+		writeError(res, http.StatusInternalServerError, "Unable to find watch time")
+		return
+	}
+
+	response := WatchTimeResponse{UserId: userId, Streams: streams}
+	for _, stream := range streams {
+		response.Seconds += stream.Seconds
+	}
+	writeJSON(res, http.StatusOK, response)
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// InsertBotAdmin
+// Makes the user a bot admin, in every channel
+func (d *Database) InsertBotAdmin(userId int, addedBy int) error {
+	statement, err := d.db.Prepare(INSERT_BOT_ADMIN)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing insert bot admin statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(userId, addedBy, time.Now())
+	if err != nil {
+		log.Printf("Error adding bot admin userId(%d): %v\n", userId, err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteBotAdmin
+func (d *Database) DeleteBotAdmin(userId int) error {
+	statement, err := d.db.Prepare(DELETE_BOT_ADMIN)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing delete bot admin statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(userId)
+	if err != nil {
+		log.Printf("Error removing bot admin userId(%d): %v\n", userId, err)
+		return err
+	}
+
+	return nil
+}
+
+// FindBotAdminIDs
+// Returns the user ids of the bot admins added in chat
+func (d *Database) FindBotAdminIDs() ([]int, error) {
+	rows, err := d.db.Query(FIND_BOT_ADMIN_IDS)
+	if err != nil {
+		log.Println("Error finding bot admins: ", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			log.Println("Error scanning bot admin: ", err)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// UpdateBotDisabled
+// Turns the bot off or on in the channel. Disabled channels aren't joined when the bot starts.
+func (d *Database) UpdateBotDisabled(userId int, disabled bool) error {
+	statement, err := d.db.Prepare(UPDATE_BOT_DISABLED)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing update bot disabled statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(disabled, time.Now(), userId)
+	if err != nil {
+		log.Printf("Error updating bot disabled for userId(%d): %v\n", userId, err)
+		return err
+	}
+
+	return nil
+}
+
+const INSERT_BOT_ADMIN string = `
+INSERT OR IGNORE INTO bot_admin (userId, addedBy, addedAt)
+VALUES (?,?,?)
+`
+
+const DELETE_BOT_ADMIN string = `
+DELETE FROM bot_admin
+WHERE userId=?
+`
+
+const FIND_BOT_ADMIN_IDS string = `
+SELECT userId
+FROM bot_admin
+ORDER BY addedAt
+`
+
+const UPDATE_BOT_DISABLED string = `
+UPDATE stream_config
+SET botDisabled=?, dateUpdated=?
+WHERE userId=?
+`
+
+const bot_admin_table string = `
+CREATE TABLE IF NOT EXISTS bot_admin (
+    userId INTEGER PRIMARY KEY,
+    addedBy INTEGER,
+    addedAt DATETIME NOT NULL,
+    FOREIGN KEY (userId)
+    REFERENCES user (id),
+    FOREIGN KEY (addedBy)
+    REFERENCES user (id)
+    )`
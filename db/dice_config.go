@@ -0,0 +1,131 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+)
+
+const (
+	DEFAULT_DICE_COUNT        = 2
+	DEFAULT_DICE_SIDES        = 6
+	DEFAULT_PREDICTION_WINDOW = 120
+	DEFAULT_ROLL_COOLDOWN     = 180
+	DEFAULT_PREDICTION_TITLE  = "Dice Roll Prediction!"
+	DEFAULT_EVEN_TITLE        = "Even"
+	DEFAULT_ODD_TITLE         = "Odd"
+)
+
+// DiceConfig
+// A channel's dice game settings, windows and cooldowns are in seconds
+type DiceConfig struct {
+	UserId           int
+	DiceCount        int
+	Sides            int
+	PredictionWindow int
+	Cooldown         int
+	Title            string
+	EvenTitle        string
+	OddTitle         string
+}
+
+// DefaultDiceConfig
+// Returns the settings used by channels that haven't configured the dice game
+func DefaultDiceConfig(userId int) DiceConfig {
+	return DiceConfig{
+		UserId:           userId,
+		DiceCount:        DEFAULT_DICE_COUNT,
+		Sides:            DEFAULT_DICE_SIDES,
+		PredictionWindow: DEFAULT_PREDICTION_WINDOW,
+		Cooldown:         DEFAULT_ROLL_COOLDOWN,
+		Title:            DEFAULT_PREDICTION_TITLE,
+		EvenTitle:        DEFAULT_EVEN_TITLE,
+		OddTitle:         DEFAULT_ODD_TITLE,
+	}
+}
+
+// FindDiceConfig
+// Returns the channel's dice settings, or the defaults if it has none
+func (d *Database) FindDiceConfig(userId int) (DiceConfig, error) {
+	config := DiceConfig{UserId: userId}
+	err := d.db.QueryRow(FIND_DICE_CONFIG, userId).Scan(
+		&config.DiceCount,
+		&config.Sides,
+		&config.PredictionWindow,
+		&config.Cooldown,
+		&config.Title,
+		&config.EvenTitle,
+		&config.OddTitle,
+	)
+	if err == sql.ErrNoRows {
+		return DefaultDiceConfig(userId), nil
+	}
+	if err != nil {
+		log.Printf("Error finding dice config for userId(%d): %v\n", userId, err)
+		return DefaultDiceConfig(userId), err
+	}
+	return config, nil
+}
+
+// UpdateDiceConfig
+// Saves the channel's dice settings
+func (d *Database) UpdateDiceConfig(config DiceConfig) error {
+	statement, err := d.db.Prepare(UPSERT_DICE_CONFIG)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing dice config statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(
+		config.UserId,
+		config.DiceCount,
+		config.Sides,
+		config.PredictionWindow,
+		config.Cooldown,
+		config.Title,
+		config.EvenTitle,
+		config.OddTitle,
+	)
+	if err != nil {
+		log.Printf("Error updating dice config for userId(%d): %v\n", config.UserId, err)
+		return err
+	}
+	return nil
+}
+
+const FIND_DICE_CONFIG string = `
+SELECT diceCount, sides, predictionWindow, cooldown, title, evenTitle, oddTitle
+FROM dice_config
+WHERE userId=?
+`
+
+const UPSERT_DICE_CONFIG string = `
+INSERT INTO dice_config (userId, diceCount, sides, predictionWindow, cooldown, title, evenTitle, oddTitle)
+VALUES (?,?,?,?,?,?,?,?)
+ON CONFLICT (userId) DO UPDATE SET
+    diceCount=excluded.diceCount,
+    sides=excluded.sides,
+    predictionWindow=excluded.predictionWindow,
+    cooldown=excluded.cooldown,
+    title=excluded.title,
+    evenTitle=excluded.evenTitle,
+    oddTitle=excluded.oddTitle
+`
+
+const dice_config_table string = `
+CREATE TABLE IF NOT EXISTS dice_config (
+    userId INTEGER PRIMARY KEY,
+    diceCount INTEGER NOT NULL,
+    sides INTEGER NOT NULL,
+    predictionWindow INTEGER NOT NULL,
+    cooldown INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    evenTitle TEXT NOT NULL,
+    oddTitle TEXT NOT NULL,
+    FOREIGN KEY (userId)
+    REFERENCES user (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    )`
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// DiceGame is a played dice game, with the totals of the prediction bet on it
+type DiceGame struct {
+	ID             int       `json:"id"`
+	UserId         int       `json:"userId"`
+	StreamId       *int      `json:"streamId"`
+	PredictionId   *int      `json:"predictionId"`
+	Mode           string    `json:"mode"`
+	Rolls          []int     `json:"rolls"`
+	Total          int       `json:"total"`
+	WinningOutcome string    `json:"winningOutcome"`
+	Status         string    `json:"status"`
+	Users          int       `json:"users"`
+	ChannelPoints  int       `json:"channelPoints"`
+	PlayedAt       time.Time `json:"playedAt"`
+}
+
+// DiceGameFilter
+// Selects a channel's games, newest first. A negative limit returns every game.
+type DiceGameFilter struct {
+	UserId int
+	Mode   *string
+	Limit  int
+	Offset int
+}
+
+// InsertDiceGame
+func (d *Database) InsertDiceGame(game DiceGame) (*DiceGame, error) {
+	statement, err := d.db.Prepare(INSERT_DICE_GAME)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing insert dice game statement: ", err)
+		return nil, err
+	}
+
+	rolls, err := json.Marshal(game.Rolls)
+	if err != nil {
+		return nil, err
+	}
+	game.PlayedAt = time.Now()
+	result, err := statement.Exec(
+		game.UserId,
+		game.StreamId,
+		game.PredictionId,
+		game.Mode,
+		string(rolls),
+		game.Total,
+		game.WinningOutcome,
+		game.Status,
+		game.Users,
+		game.ChannelPoints,
+		game.PlayedAt,
+	)
+	if err != nil {
+		log.Printf("Error inserting dice game for userId(%d): %v\n", game.UserId, err)
+		return nil, err
+	}
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	game.ID = int(newID)
+	return &game, nil
+}
+
+// FindDiceGames
+// Returns a page of the channel's games, and the total number of games matching the filter
+func (d *Database) FindDiceGames(filter DiceGameFilter) ([]DiceGame, int, error) {
+	filterArgs := []any{filter.UserId, filter.Mode, filter.Mode}
+
+	var total int
+	if err := d.db.QueryRow(COUNT_DICE_GAMES, filterArgs...).Scan(&total); err != nil {
+		log.Println("Error counting dice games: ", err)
+		return nil, 0, err
+	}
+
+	rows, err := d.db.Query(FIND_DICE_GAMES, append(filterArgs, filter.Limit, filter.Offset)...)
+	if err != nil {
+		log.Println("Error finding dice games: ", err)
+		return nil, 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	games := []DiceGame{}
+	for rows.Next() {
+		games = append(games, scanDiceGame(rows))
+	}
+	return games, total, nil
+}
+
+func scanDiceGame(rows *sql.Rows) DiceGame {
+	var game DiceGame
+	var rolls string
+	err := rows.Scan(
+		&game.ID,
+		&game.UserId,
+		&game.StreamId,
+		&game.PredictionId,
+		&game.Mode,
+		&rolls,
+		&game.Total,
+		&game.WinningOutcome,
+		&game.Status,
+		&game.Users,
+		&game.ChannelPoints,
+		&game.PlayedAt,
+	)
+	if err != nil {
+		log.Println("Error scanning dice game: ", err)
+	}
+	if err := json.Unmarshal([]byte(rolls), &game.Rolls); err != nil {
+		game.Rolls = []int{}
+	}
+	return game
+}
+
+const INSERT_DICE_GAME string = `
+INSERT INTO dice_game (userId, streamId, predictionId, mode, rolls, total, winningOutcome, status, users, channelPoints, playedAt)
+VALUES (?,?,?,?,?,?,?,?,?,?,?)
+`
+
+const DICE_GAME_FILTER string = `
+FROM dice_game
+WHERE userId=?
+AND (? IS NULL OR mode=?)
+`
+
+const COUNT_DICE_GAMES string = `
+SELECT count(*)
+` + DICE_GAME_FILTER
+
+const FIND_DICE_GAMES string = `
+SELECT id, userId, streamId, predictionId, mode, rolls, total, winningOutcome, status, users, channelPoints, playedAt
+` + DICE_GAME_FILTER + `
+ORDER BY playedAt DESC, id DESC
+LIMIT ? OFFSET ?
+`
+
+const dice_game_table string = `
+CREATE TABLE IF NOT EXISTS dice_game (
+    id INTEGER PRIMARY KEY,
+    userId INTEGER NOT NULL,
+    streamId INTEGER,
+    predictionId INTEGER,
+    mode TEXT NOT NULL,
+    rolls TEXT NOT NULL,
+    total INTEGER NOT NULL,
+    winningOutcome TEXT NOT NULL,
+    status TEXT NOT NULL,
+    users INTEGER NOT NULL DEFAULT 0,
+    channelPoints INTEGER NOT NULL DEFAULT 0,
+    playedAt DATETIME NOT NULL,
+    FOREIGN KEY (userId)
+    REFERENCES user (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+    FOREIGN KEY (streamId)
+    REFERENCES stream (id),
+    FOREIGN KEY (predictionId)
+    REFERENCES prediction (id)
+    )`
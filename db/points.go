@@ -0,0 +1,313 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// Points every viewer starts with in a channel
+const DEFAULT_STARTING_POINTS = 1000
+
+// Reasons for points_ledger entries. The ledger is only ever appended to,
+// so balances are the sum of a viewer's entries and every change can be traced.
+const (
+	POINTS_REASON_START      = "start"
+	POINTS_REASON_BET        = "bet"
+	POINTS_REASON_BET_PAYOUT = "bet-payout"
+	POINTS_REASON_BET_REFUND = "bet-refund"
+	POINTS_REASON_MESSAGE    = "message"
+	POINTS_REASON_FIRST      = "first"
+	POINTS_REASON_QOTD       = "qotd"
+	POINTS_REASON_WATCH      = "watch"
+	POINTS_REASON_GIVE       = "give"
+	POINTS_REASON_MOD        = "mod"
+)
+
+var ErrInsufficientPoints = errors.New("Not enough points")
+
+// PointsEntry
+// A change to a viewer's points in a channel. StreamId, ActorId and PredictionId are
+// set when the points were earned during a stream, given by someone else, or bet.
+type PointsEntry struct {
+	ID           int       `json:"id"`
+	ChannelId    int       `json:"channelId"`
+	UserId       int       `json:"userId"`
+	Amount       int       `json:"amount"`
+	Reason       string    `json:"reason"`
+	StreamId     *int      `json:"streamId"`
+	ActorId      *int      `json:"actorId"`
+	PredictionId *int      `json:"predictionId"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// PredictionBet is the points a viewer has bet on a chat prediction and not had refunded
+type PredictionBet struct {
+	UserId int
+	Amount int
+}
+
+// PointsLeader is a viewer and their points in a channel
+type PointsLeader struct {
+	User   User
+	Points int
+}
+
+// AddPoints
+// Appends the entry to the ledger, a negative amount takes points away.
+// Viewers new to the channel are given the starting points first.
+func (d *Database) AddPoints(entry PointsEntry) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println("Error starting add points transaction: ", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := insertPointsEntries(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SpendPoints
+// Takes the entry's amount from the viewer, failing with ErrInsufficientPoints if their balance is too low.
+// Viewers new to the channel are given the starting points first.
+func (d *Database) SpendPoints(entry PointsEntry) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println("Error starting spend points transaction: ", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := spendPoints(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TransferPoints
+// Moves points between two viewers in a channel, failing with ErrInsufficientPoints if the giver has too few
+func (d *Database) TransferPoints(channelId int, fromUserId int, toUserId int, amount int) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println("Error starting transfer points transaction: ", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := spendPoints(tx, PointsEntry{
+		ChannelId: channelId,
+		UserId:    fromUserId,
+		Amount:    amount,
+		Reason:    POINTS_REASON_GIVE,
+		ActorId:   &toUserId,
+	}); err != nil {
+		return err
+	}
+	if err := insertPointsEntries(tx, PointsEntry{
+		ChannelId: channelId,
+		UserId:    toUserId,
+		Amount:    amount,
+		Reason:    POINTS_REASON_GIVE,
+		ActorId:   &fromUserId,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func spendPoints(tx *sql.Tx, entry PointsEntry) error {
+	if err := insertStartingPoints(tx, entry.ChannelId, entry.UserId); err != nil {
+		return err
+	}
+
+	var balance int
+	if err := tx.QueryRow(FIND_POINTS_BALANCE, entry.ChannelId, entry.UserId).Scan(&balance); err != nil {
+		log.Printf("Error finding points for userId(%d) in channel(%d): %v\n", entry.UserId, entry.ChannelId, err)
+		return err
+	}
+	if balance < entry.Amount {
+		return ErrInsufficientPoints
+	}
+
+	entry.Amount = -entry.Amount
+	return insertPointsEntry(tx, entry)
+}
+
+// insertPointsEntries
+// Inserts the viewer's starting points if they have none, then the entry
+func insertPointsEntries(tx *sql.Tx, entry PointsEntry) error {
+	if err := insertStartingPoints(tx, entry.ChannelId, entry.UserId); err != nil {
+		return err
+	}
+	return insertPointsEntry(tx, entry)
+}
+
+func insertStartingPoints(tx *sql.Tx, channelId int, userId int) error {
+	_, err := tx.Exec(INSERT_STARTING_POINTS, channelId, userId, DEFAULT_STARTING_POINTS, POINTS_REASON_START, time.Now(), channelId, userId)
+	if err != nil {
+		log.Printf("Error giving starting points to userId(%d) in channel(%d): %v\n", userId, channelId, err)
+	}
+	return err
+}
+
+func insertPointsEntry(tx *sql.Tx, entry PointsEntry) error {
+	_, err := tx.Exec(INSERT_POINTS_ENTRY,
+		entry.ChannelId,
+		entry.UserId,
+		entry.Amount,
+		entry.Reason,
+		entry.StreamId,
+		entry.ActorId,
+		entry.PredictionId,
+		time.Now(),
+	)
+	if err != nil {
+		log.Printf("Error adding %d points for userId(%d) in channel(%d): %v\n", entry.Amount, entry.UserId, entry.ChannelId, err)
+	}
+	return err
+}
+
+// FindPointsBalance
+// Returns the viewer's points in the channel, the starting points if they've never used any
+func (d *Database) FindPointsBalance(channelId int, userId int) (int, error) {
+	var entries, balance int
+	if err := d.db.QueryRow(FIND_POINTS_ENTRY_COUNT, channelId, userId).Scan(&entries); err != nil {
+		log.Printf("Error finding points for userId(%d) in channel(%d): %v\n", userId, channelId, err)
+		return 0, err
+	}
+	if entries == 0 {
+		return DEFAULT_STARTING_POINTS, nil
+	}
+	if err := d.db.QueryRow(FIND_POINTS_BALANCE, channelId, userId).Scan(&balance); err != nil {
+		log.Printf("Error finding points for userId(%d) in channel(%d): %v\n", userId, channelId, err)
+		return 0, err
+	}
+	return balance, nil
+}
+
+// HasStreamPoints
+// Reports if the viewer has already earned points for the reason during the stream
+func (d *Database) HasStreamPoints(channelId int, userId int, streamId int, reason string) bool {
+	var count int
+	if err := d.db.QueryRow(COUNT_STREAM_POINTS, channelId, userId, streamId, reason).Scan(&count); err != nil {
+		log.Printf("Error checking %s points for userId(%d) in stream(%d): %v\n", reason, userId, streamId, err)
+		return true
+	}
+	return count > 0
+}
+
+// FindPointsLeaders
+// Returns the viewers with the most points in the channel
+func (d *Database) FindPointsLeaders(channelId int, limit int) ([]PointsLeader, error) {
+	rows, err := d.db.Query(FIND_POINTS_LEADERS, channelId, limit)
+	if err != nil {
+		log.Printf("Error finding points leaders in channel(%d): %v\n", channelId, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	leaders := []PointsLeader{}
+	for rows.Next() {
+		var leader PointsLeader
+		if err := rows.Scan(&leader.User.ID, &leader.User.Username, &leader.User.DisplayName, &leader.Points); err != nil {
+			log.Println("Error scanning points leader: ", err)
+			continue
+		}
+		leaders = append(leaders, leader)
+	}
+	return leaders, nil
+}
+
+// FindPredictionBets
+// Returns the points each viewer has bet on the prediction, less anything paid out or refunded
+func (d *Database) FindPredictionBets(predictionId int) ([]PredictionBet, error) {
+	rows, err := d.db.Query(FIND_PREDICTION_BETS, predictionId)
+	if err != nil {
+		log.Printf("Error finding bets on prediction(%d): %v\n", predictionId, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	bets := []PredictionBet{}
+	for rows.Next() {
+		var bet PredictionBet
+		if err := rows.Scan(&bet.UserId, &bet.Amount); err != nil {
+			log.Println("Error scanning prediction bet: ", err)
+			continue
+		}
+		bets = append(bets, bet)
+	}
+	return bets, nil
+}
+
+const INSERT_POINTS_ENTRY string = `
+INSERT INTO points_ledger (channelId, userId, amount, reason, streamId, actorId, predictionId, createdAt)
+VALUES (?,?,?,?,?,?,?,?)
+`
+
+const INSERT_STARTING_POINTS string = `
+INSERT INTO points_ledger (channelId, userId, amount, reason, createdAt)
+SELECT ?,?,?,?,?
+WHERE NOT EXISTS (SELECT 1 FROM points_ledger WHERE channelId=? AND userId=?)
+`
+
+const FIND_POINTS_ENTRY_COUNT string = `
+SELECT count(*)
+FROM points_ledger
+WHERE channelId=? AND userId=?
+`
+
+const FIND_POINTS_BALANCE string = `
+SELECT coalesce(sum(amount), 0)
+FROM points_ledger
+WHERE channelId=? AND userId=?
+`
+
+const COUNT_STREAM_POINTS string = `
+SELECT count(*)
+FROM points_ledger
+WHERE channelId=? AND userId=? AND streamId=? AND reason=?
+`
+
+const FIND_POINTS_LEADERS string = `
+SELECT u.id, u.username, u.displayName, sum(pl.amount) AS points
+FROM points_ledger pl
+JOIN user u ON u.id = pl.userId
+WHERE pl.channelId=?
+GROUP BY u.id
+ORDER BY points DESC, u.username
+LIMIT ?
+`
+
+const FIND_PREDICTION_BETS string = `
+SELECT userId, -sum(amount)
+FROM points_ledger
+WHERE predictionId=?
+GROUP BY userId
+HAVING sum(amount) < 0
+`
+
+const points_ledger_table string = `
+CREATE TABLE IF NOT EXISTS points_ledger (
+    id INTEGER PRIMARY KEY,
+    channelId INTEGER NOT NULL,
+    userId INTEGER NOT NULL,
+    amount INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    predictionId INTEGER,
+    createdAt DATETIME NOT NULL,
+    FOREIGN KEY (channelId)
+    REFERENCES user (id),
+    FOREIGN KEY (userId)
+    REFERENCES user (id),
+    FOREIGN KEY (predictionId)
+    REFERENCES prediction (id)
+    )`
+
+const points_ledger_index string = `
+CREATE INDEX IF NOT EXISTS points_ledger_channel_user ON points_ledger (channelId, userId)
+`
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+// Users seeded by InitDatabase
+const (
+	testChannelId = 31568083
+	testViewerId  = 236797464
+)
+
+func newTestDatabase(t *testing.T) *Database {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	t.Cleanup(func() { os.Chdir(wd) })
+	return InitDatabase()
+}
+
+func TestPointsLedger(t *testing.T) {
+	d := newTestDatabase(t)
+
+	balance, err := d.FindPointsBalance(testChannelId, testViewerId)
+	assert.NoError(t, err)
+	assert.Equal(t, DEFAULT_STARTING_POINTS, balance)
+
+	assert.NoError(t, d.AddPoints(PointsEntry{ChannelId: testChannelId, UserId: testViewerId, Amount: 5, Reason: POINTS_REASON_MESSAGE}))
+	balance, _ = d.FindPointsBalance(testChannelId, testViewerId)
+	assert.Equal(t, DEFAULT_STARTING_POINTS+5, balance)
+
+	err = d.SpendPoints(PointsEntry{ChannelId: testChannelId, UserId: testViewerId, Amount: 2000, Reason: POINTS_REASON_BET})
+	assert.ErrorIs(t, err, ErrInsufficientPoints)
+
+	err = d.TransferPoints(testChannelId, testViewerId, testChannelId, DEFAULT_STARTING_POINTS)
+	assert.NoError(t, err)
+	err = d.TransferPoints(testChannelId, testViewerId, testChannelId, 10)
+	assert.ErrorIs(t, err, ErrInsufficientPoints)
+
+	leaders, err := d.FindPointsLeaders(testChannelId, 5)
+	assert.NoError(t, err)
+	if assert.Len(t, leaders, 2) {
+		assert.Equal(t, testChannelId, leaders[0].User.ID)
+		assert.Equal(t, 2*DEFAULT_STARTING_POINTS, leaders[0].Points)
+		assert.Equal(t, 5, leaders[1].Points)
+	}
+
+	// Balances are per channel
+	balance, _ = d.FindPointsBalance(testViewerId, testViewerId)
+	assert.Equal(t, DEFAULT_STARTING_POINTS, balance)
+}
+
+func TestHasStreamPoints(t *testing.T) {
+	d := newTestDatabase(t)
+	stream, err := d.InsertStream(testChannelId, time.Now())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	assert.False(t, d.HasStreamPoints(testChannelId, testViewerId, stream.ID, POINTS_REASON_QOTD))
+	d.AddPoints(PointsEntry{ChannelId: testChannelId, UserId: testViewerId, Amount: 25, Reason: POINTS_REASON_QOTD, StreamId: &stream.ID})
+	assert.True(t, d.HasStreamPoints(testChannelId, testViewerId, stream.ID, POINTS_REASON_QOTD))
+	assert.False(t, d.HasStreamPoints(testChannelId, testViewerId, stream.ID, POINTS_REASON_FIRST))
+}
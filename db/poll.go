@@ -0,0 +1,197 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Poll statuses
+const (
+	POLL_STATUS_ACTIVE   = "active"
+	POLL_STATUS_ENDED    = "ended"
+	POLL_STATUS_CANCELED = "canceled"
+)
+
+// Poll
+// A poll run in a channel, as a Twitch poll or by voting in chat when TwitchID is nil
+type Poll struct {
+	ID        int          `json:"id"`
+	ChannelId int          `json:"channelId"`
+	StreamId  *int         `json:"streamId"`
+	TwitchID  *string      `json:"twitchId"`
+	Title     string       `json:"title"`
+	Choices   []PollChoice `json:"choices"`
+	Status    string       `json:"status"`
+	Duration  int          `json:"duration"`
+	StartedAt time.Time    `json:"startedAt"`
+	EndedAt   *time.Time   `json:"endedAt"`
+}
+
+// PollChoice is a choice in a poll and how many votes it got
+type PollChoice struct {
+	Title string `json:"title"`
+	Votes int    `json:"votes"`
+}
+
+// InsertPoll
+func (d *Database) InsertPoll(poll Poll) (*Poll, error) {
+	statement, err := d.db.Prepare(INSERT_POLL)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing insert poll statement: ", err)
+		return nil, err
+	}
+
+	choices, err := json.Marshal(poll.Choices)
+	if err != nil {
+		return nil, err
+	}
+	poll.Status = POLL_STATUS_ACTIVE
+	poll.StartedAt = time.Now()
+	result, err := statement.Exec(
+		poll.ChannelId,
+		poll.StreamId,
+		poll.TwitchID,
+		poll.Title,
+		string(choices),
+		poll.Status,
+		poll.Duration,
+		poll.StartedAt,
+	)
+	if err != nil {
+		log.Printf("Error inserting poll for channel(%d): %v\n", poll.ChannelId, err)
+		return nil, err
+	}
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	poll.ID = int(newID)
+	return &poll, nil
+}
+
+// EndPoll
+// Records the poll's results and when it ended
+func (d *Database) EndPoll(id int, status string, choices []PollChoice) error {
+	statement, err := d.db.Prepare(END_POLL)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing end poll statement: ", err)
+		return err
+	}
+
+	results, err := json.Marshal(choices)
+	if err != nil {
+		return err
+	}
+	_, err = statement.Exec(status, string(results), time.Now(), id)
+	if err != nil {
+		log.Printf("Error ending poll(%d): %v\n", id, err)
+		return err
+	}
+
+	return nil
+}
+
+// FindActivePolls
+// Returns the polls that hadn't ended when the bot was last shut down
+func (d *Database) FindActivePolls() ([]Poll, error) {
+	return d.findPolls(FIND_POLLS_BY_STATUS, POLL_STATUS_ACTIVE)
+}
+
+// FindStreamPolls
+// Returns the polls run during a stream, in the order they started
+func (d *Database) FindStreamPolls(streamId int) ([]Poll, error) {
+	return d.findPolls(FIND_POLLS_BY_STREAM, streamId)
+}
+
+func (d *Database) findPolls(query string, args ...any) ([]Poll, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		log.Println("Error finding polls: ", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	polls := []Poll{}
+	for rows.Next() {
+		polls = append(polls, scanPoll(rows))
+	}
+	return polls, nil
+}
+
+func scanPoll(rows *sql.Rows) Poll {
+	var poll Poll
+	var choices string
+	err := rows.Scan(
+		&poll.ID,
+		&poll.ChannelId,
+		&poll.StreamId,
+		&poll.TwitchID,
+		&poll.Title,
+		&choices,
+		&poll.Status,
+		&poll.Duration,
+		&poll.StartedAt,
+		&poll.EndedAt,
+	)
+	if err != nil {
+		log.Println("Error scanning poll: ", err)
+	}
+	if err := json.Unmarshal([]byte(choices), &poll.Choices); err != nil {
+		poll.Choices = []PollChoice{}
+	}
+	return poll
+}
+
+const INSERT_POLL string = `
+INSERT INTO poll (channelId, streamId, twitchId, title, choices, status, duration, startedAt)
+VALUES (?,?,?,?,?,?,?,?)
+`
+
+const END_POLL string = `
+UPDATE poll
+SET status=?, choices=?, endedAt=?
+WHERE id=?
+`
+
+const POLL_COLUMNS string = `id, channelId, streamId, twitchId, title, choices, status, duration, startedAt, endedAt`
+
+const FIND_POLLS_BY_STATUS string = `
+SELECT ` + POLL_COLUMNS + `
+FROM poll
+WHERE status=?
+ORDER BY startedAt
+`
+
+const FIND_POLLS_BY_STREAM string = `
+SELECT ` + POLL_COLUMNS + `
+FROM poll
+WHERE streamId=?
+ORDER BY startedAt, id
+`
+
+const poll_table string = `
+CREATE TABLE IF NOT EXISTS poll (
+    id INTEGER PRIMARY KEY,
+    channelId INTEGER NOT NULL,
+    streamId INTEGER,
+    twitchId TEXT,
+    title TEXT NOT NULL,
+    choices TEXT NOT NULL,
+    status TEXT NOT NULL,
+    duration INTEGER NOT NULL,
+    startedAt DATETIME NOT NULL,
+    endedAt DATETIME,
+    FOREIGN KEY (channelId)
+    REFERENCES user (id),
+    FOREIGN KEY (streamId)
+    REFERENCES stream (id)
+    )`
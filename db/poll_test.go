@@ -0,0 +1,42 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPollResults(t *testing.T) {
+	d := newTestDatabase(t)
+	stream, err := d.InsertStream(testChannelId, time.Now())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	poll, err := d.InsertPoll(Poll{
+		ChannelId: testChannelId,
+		StreamId:  &stream.ID,
+		Title:     "Next game?",
+		Choices:   []PollChoice{{Title: "Celeste"}, {Title: "Hades"}},
+		Duration:  60,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	active, _ := d.FindActivePolls()
+	assert.Len(t, active, 1)
+
+	assert.NoError(t, d.EndPoll(poll.ID, POLL_STATUS_ENDED, []PollChoice{{Title: "Celeste", Votes: 3}, {Title: "Hades", Votes: 5}}))
+	active, _ = d.FindActivePolls()
+	assert.Empty(t, active)
+
+	polls, err := d.FindStreamPolls(stream.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, polls, 1) {
+		assert.Nil(t, polls[0].TwitchID)
+		assert.Equal(t, POLL_STATUS_ENDED, polls[0].Status)
+		assert.Equal(t, []PollChoice{{Title: "Celeste", Votes: 3}, {Title: "Hades", Votes: 5}}, polls[0].Choices)
+		assert.NotNil(t, polls[0].EndedAt)
+	}
+}
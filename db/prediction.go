@@ -0,0 +1,199 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Prediction is a Twitch prediction started by a dice game, kept so it can be
+// resolved or cancelled if the bot restarts before the game is played
+type Prediction struct {
+	ID             int
+	TwitchID       string
+	UserId         int
+	Mode           string
+	Status         string
+	RollAt         time.Time
+	CreatedAt      time.Time
+	EndedAt        *time.Time
+	WinningOutcome *string
+	// The server seed is kept secret until the prediction ends, only its hash is posted in chat
+	Seed     *string
+	SeedHash *string
+	// Dice settings the game was started with, so its rolls can be replayed
+	DiceCount int
+	Sides     int
+	EvenTitle string
+	OddTitle  string
+	// Rolls the game was played with, comma separated
+	Rolls *string
+}
+
+// InsertPrediction
+// Saves the new prediction, setting its ID and creation time
+func (d *Database) InsertPrediction(prediction Prediction) (*Prediction, error) {
+	statement, err := d.db.Prepare(INSERT_PREDICTION)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing insert prediction statement: ", err)
+		return nil, err
+	}
+
+	prediction.CreatedAt = time.Now()
+	result, err := statement.Exec(
+		prediction.TwitchID,
+		prediction.UserId,
+		prediction.Mode,
+		prediction.Status,
+		prediction.RollAt,
+		prediction.CreatedAt,
+		prediction.Seed,
+		prediction.SeedHash,
+		prediction.DiceCount,
+		prediction.Sides,
+		prediction.EvenTitle,
+		prediction.OddTitle,
+	)
+	if err != nil {
+		log.Printf("Error inserting prediction(%s) for userId(%d): %v\n", prediction.TwitchID, prediction.UserId, err)
+		return nil, err
+	}
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	prediction.ID = int(newID)
+	return &prediction, nil
+}
+
+// UpdatePredictionStatus
+// Sets the prediction's status, ending it with the winning outcome and rolls when it is resolved or cancelled
+func (d *Database) UpdatePredictionStatus(id int, status string, ended bool, winningOutcome *string, rolls *string) error {
+	statement, err := d.db.Prepare(UPDATE_PREDICTION_STATUS)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing update prediction statement: ", err)
+		return err
+	}
+
+	var endedAt *time.Time
+	if ended {
+		now := time.Now()
+		endedAt = &now
+	}
+	if _, err := statement.Exec(status, endedAt, winningOutcome, rolls, id); err != nil {
+		log.Printf("Error updating prediction(%d) to %s: %v\n", id, status, err)
+		return err
+	}
+	return nil
+}
+
+// FindOpenPredictions
+// Returns predictions that haven't been resolved or cancelled
+func (d *Database) FindOpenPredictions() ([]Prediction, error) {
+	rows, err := d.db.Query(FIND_OPEN_PREDICTIONS)
+	if err != nil {
+		log.Println("Error finding open predictions: ", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	predictions := []Prediction{}
+	for rows.Next() {
+		predictions = append(predictions, scanPrediction(rows))
+	}
+	return predictions, nil
+}
+
+// FindPredictionByID
+func (d *Database) FindPredictionByID(ID int) (*Prediction, bool) {
+	rows, err := d.db.Query(FIND_PREDICTION_BY_ID, ID)
+	if err != nil {
+		log.Printf("Error finding prediction(%d): %v\n", ID, err)
+		return nil, false
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return nil, false
+	}
+	prediction := scanPrediction(rows)
+	return &prediction, true
+}
+
+func scanPrediction(rows *sql.Rows) Prediction {
+	var prediction Prediction
+	err := rows.Scan(
+		&prediction.ID,
+		&prediction.TwitchID,
+		&prediction.UserId,
+		&prediction.Mode,
+		&prediction.Status,
+		&prediction.RollAt,
+		&prediction.CreatedAt,
+		&prediction.EndedAt,
+		&prediction.WinningOutcome,
+		&prediction.Seed,
+		&prediction.SeedHash,
+		&prediction.DiceCount,
+		&prediction.Sides,
+		&prediction.EvenTitle,
+		&prediction.OddTitle,
+		&prediction.Rolls,
+	)
+	if err != nil {
+		log.Println("Error scanning prediction: ", err)
+	}
+	return prediction
+}
+
+const INSERT_PREDICTION string = `
+INSERT INTO prediction (twitchId, userId, mode, status, rollAt, createdAt, seed, seedHash, diceCount, sides, evenTitle, oddTitle)
+VALUES (?,?,?,?,?,?,?,?,?,?,?,?)
+`
+
+const UPDATE_PREDICTION_STATUS string = `
+UPDATE prediction
+SET status=?, endedAt=?, winningOutcome=?, rolls=?
+WHERE id=?
+`
+
+const PREDICTION_COLUMNS string = `
+id, twitchId, userId, mode, status, rollAt, createdAt, endedAt, winningOutcome,
+seed, seedHash, diceCount, sides, evenTitle, oddTitle, rolls`
+
+const FIND_OPEN_PREDICTIONS string = `
+SELECT ` + PREDICTION_COLUMNS + `
+FROM prediction
+WHERE endedAt IS NULL
+ORDER BY rollAt
+`
+
+const FIND_PREDICTION_BY_ID string = `
+SELECT ` + PREDICTION_COLUMNS + `
+FROM prediction
+WHERE id=?
+`
+
+const prediction_table string = `
+CREATE TABLE IF NOT EXISTS prediction (
+    id INTEGER PRIMARY KEY,
+    twitchId TEXT UNIQUE NOT NULL,
+    userId INTEGER NOT NULL,
+    mode TEXT NOT NULL,
+    status TEXT NOT NULL,
+    rollAt DATETIME NOT NULL,
+    createdAt DATETIME NOT NULL,
+    endedAt DATETIME,
+    winningOutcome TEXT,
+    FOREIGN KEY (userId)
+    REFERENCES user (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    )`
@@ -72,20 +72,20 @@ VALUES (?,?)
 `
 
 const FIND_STREAM_BY_ID string = `
-SELECT id, twid, title, startedAt, endedAt, userId, first_userId, qotdId
+SELECT id, twid, title, startedAt, endedAt, userId, first_userId, qotdId, qotdAskedAt
 FROM stream
 WHERE id=?
 `
 
 const FIND_CURRENT_STREAM_BY_USERID string = `
-SELECT id, twid, title, startedAt, endedAt, userId, first_userId, qotdId
+SELECT id, twid, title, startedAt, endedAt, userId, first_userId, qotdId, qotdAskedAt
 FROM stream
 WHERE endedAt IS NULL AND userId=?
 LIMIT 1
 `
 
 const FIND_ALL_CURRENT_STREAMS string = `
-SELECT id, twid, title, startedAt, endedAt, userId, first_userId, qotdId
+SELECT id, twid, title, startedAt, endedAt, userId, first_userId, qotdId, qotdAskedAt
 FROM stream
 WHERE endedAt IS NULL
 `
@@ -104,7 +104,7 @@ WHERE id=?
 
 const UPDATE_STREAM_QUESTION string = `
 UPDATE stream
-SET qotdId=?
+SET qotdId=?, qotdAskedAt=?
 WHERE id=?
 `
 
@@ -137,12 +137,54 @@ FROM question
 WHERE text=?
 `
 
+const FIND_ALL_QUESTIONS string = `
+SELECT q.id, q.text, q.disabled, q.skipCount, group_concat(t.tag)
+FROM question q
+LEFT JOIN question_tag t ON t.questionId=q.id
+GROUP BY q.id
+ORDER BY q.id
+`
+
+// Shared by FIND_QUESTIONS and COUNT_QUESTIONS, each filter is skipped when its parameters are NULL
+const QUESTION_FILTERS string = `
+WHERE (? IS NULL OR q.disabled=?)
+    AND (? IS NULL OR q.text LIKE '%' || ? || '%' ESCAPE '\')
+    AND (? IS NULL OR q.skipCount>=?)
+    AND (? IS NULL OR EXISTS (SELECT 1 FROM question_tag WHERE questionId=q.id AND tag=?))
+`
+
+const FIND_QUESTIONS string = `
+SELECT q.id, q.text, q.disabled, q.skipCount, group_concat(t.tag)
+FROM question q
+LEFT JOIN question_tag t ON t.questionId=q.id` + QUESTION_FILTERS + `
+GROUP BY q.id
+ORDER BY q.id
+LIMIT ? OFFSET ?
+`
+
+const COUNT_QUESTIONS string = `
+SELECT count(*)
+FROM question q` + QUESTION_FILTERS
+
+const INSERT_QUESTION_TAG string = `
+INSERT OR IGNORE INTO question_tag (questionId, tag)
+VALUES (?, ?)
+`
+
+// Excludes questions asked on the channel since its qotdEpoch,
+// preferring questions never asked, then the least recently asked.
 const FIND_RANDOM_QUESTION string = `
-SELECT id, text, disabled, skipCount
-FROM question
-WHERE NOT EXISTS (SELECT qotdId FROM stream WHERE qotdId=question.id AND userId=?)
-    AND disabled = false
-ORDER BY RANDOM()
+SELECT q.id, q.text, q.disabled, q.skipCount
+FROM question q
+LEFT JOIN (
+    SELECT qotdId, max(coalesce(qotdAskedAt, startedAt)) AS lastAsked
+    FROM stream
+    WHERE userId=? AND qotdId IS NOT NULL
+    GROUP BY qotdId
+    ) asked ON asked.qotdId=q.id
+WHERE q.disabled = false
+    AND (asked.lastAsked IS NULL OR asked.lastAsked<=(SELECT qotdEpoch FROM stream_config WHERE userId=?))
+ORDER BY asked.lastAsked IS NOT NULL, asked.lastAsked, RANDOM()
 LIMIT 1
 `
 
@@ -152,12 +194,55 @@ SET disabled = true
 WHERE id=?
 `
 
+const ENABLE_QUESTION string = `
+UPDATE question
+SET disabled = false, skipCount = 0
+WHERE id=?
+`
+
+const UPDATE_QUESTION_TEXT string = `
+UPDATE question
+SET text=?
+WHERE id=?
+`
+
+const CLEAR_STREAM_QUESTION string = `
+UPDATE stream
+SET qotdId=NULL
+WHERE qotdId=?
+`
+
+const DELETE_QUESTION_SKIPS string = `
+DELETE FROM question_skip
+WHERE questionId=?
+`
+
+const DELETE_QUESTION_TAGS string = `
+DELETE FROM question_tag
+WHERE questionId=?
+`
+
+const DELETE_QUESTION_SCHEDULES string = `
+DELETE FROM question_schedule
+WHERE questionId=?
+`
+
+const DELETE_QUESTION string = `
+DELETE FROM question
+WHERE id=?
+`
+
 const INCREMENT_QUESTION_SKIP string = `
 UPDATE question
 SET skipCount = skipCount + 1
 WHERE id=?
 `
 
+const INSERT_QUESTION_SKIP string = `
+INSERT INTO question_skip (questionId, streamId, userId, skippedAt)
+VALUES (?,?,?,?)
+`
+
 const CREATE_STREAM_CONFIG string = `
 INSERT INTO stream_config (
     userId,
@@ -180,26 +265,64 @@ SET firstEpoch=?
 WHERE userId=?
 `
 
+const UPDATE_QOTD_EPOCH string = `
+UPDATE stream_config
+SET qotdEpoch=?
+WHERE userId=?
+`
+
 const UPDATE_TWITCHAUTH_BY_USERID string = `
 UPDATE stream_config
 SET twitchAuthToken=?, twitchRefreshToken=?
 WHERE userId=?
 `
 
+const UPDATE_QOTD_AUTO_POST string = `
+UPDATE stream_config
+SET qotdAutoDelay=?, qotdRepeatMinutes=?, qotdRepeatMessages=?
+WHERE userId=?
+`
+
+const UPDATE_QOTD_SKIP_VOTE_SHARE string = `
+UPDATE stream_config
+SET qotdSkipVoteShare=?
+WHERE userId=?
+`
+
+const UPDATE_THANOS string = `
+UPDATE stream_config
+SET thanosEnabled=?, thanosDuration=?
+WHERE userId=?
+`
+
+const UPDATE_RAID_ENABLED string = `
+UPDATE stream_config
+SET raidEnabled=?
+WHERE userId=?
+`
+
+const STREAM_USER_COLUMNS string = `
+u.id, u.username, u.displayName,
+sc.id, sc.userId, sc.botDisabled, sc.firstEnabled, sc.firstEpoch, sc.qotdEnabled, sc.qotdEpoch, sc.dateUpdated,
+sc.apiKey, sc.twitchAuthToken, sc.twitchRefreshToken,
+sc.qotdAutoDelay, sc.qotdRepeatMinutes, sc.qotdRepeatMessages, sc.qotdSkipVoteShare,
+sc.qotdDaily, sc.qotdTimezone,
+sc.thanosEnabled, sc.thanosDuration, sc.raidEnabled`
+
 const FIND_STREAM_USER_BY_USERID string = `
-SELECT u.id, u.username, u.displayName, sc.id, sc.userId, sc.botDisabled, sc.firstEnabled, sc.firstEpoch, sc.qotdEnabled, sc.qotdEpoch, sc.dateUpdated, sc.apiKey, sc.twitchAuthToken, sc.twitchRefreshToken
+SELECT ` + STREAM_USER_COLUMNS + `
 FROM user u, stream_config sc
 WHERE u.id = sc.userId AND userId=?
 `
 
 const FIND_STREAM_USER_BY_USERNAME string = `
-SELECT u.id, u.username, u.displayName, sc.id, sc.userId, sc.botDisabled, sc.firstEnabled, sc.firstEpoch, sc.qotdEnabled, sc.qotdEpoch, sc.dateUpdated, sc.apiKey, sc.twitchAuthToken, sc.twitchRefreshToken
+SELECT ` + STREAM_USER_COLUMNS + `
 FROM user u, stream_config sc
 WHERE u.id = sc.userId AND u.username=?
 `
 
 const FIND_ALL_STREAM_USERS string = `
-SELECT u.id, u.username, u.displayName, sc.id, sc.userId, sc.botDisabled, sc.firstEnabled, sc.firstEpoch, sc.qotdEnabled, sc.qotdEpoch, sc.dateUpdated, sc.apiKey, sc.twitchAuthToken, sc.twitchRefreshToken
+SELECT ` + STREAM_USER_COLUMNS + `
 FROM user u, stream_config sc
 WHERE u.id = sc.userId
 `
@@ -1,35 +1,52 @@
 package db
 
 import (
+	"database/sql"
 	"errors"
 	"log"
+	"strings"
+	"time"
 )
 
 type Question struct {
-	ID        int    `json:"id"`
-	Text      string `json:"text"`
-	Disabled  bool   `json:"disabled"`
-	SkipCount int    `json:"skipCount"`
+	ID        int      `json:"id"`
+	Text      string   `json:"text"`
+	Disabled  bool     `json:"disabled"`
+	SkipCount int      `json:"skipCount"`
+	Tags      []string `json:"tags,omitempty"`
 }
 
 // IncrementQuestionSkip
-func (d *Database) IncrementQuestionSkip(questionId int) (int, error) {
-	statement, err := d.db.Prepare(INCREMENT_QUESTION_SKIP)
-	if statement != nil {
-		defer func() { _ = statement.Close() }()
-	}
+// Records that the question was skipped on a stream, and returns the new skip count
+func (d *Database) IncrementQuestionSkip(questionId int, streamId int, userId int) (int, error) {
+	tx, err := d.db.Begin()
 	if err != nil {
-		log.Println("Error preparing increment question skip statement: ", err)
+		log.Println("Error starting question skip transaction: ", err)
 		return 0, err
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	_, err = statement.Exec(questionId)
+	if _, err := tx.Exec(INSERT_QUESTION_SKIP, questionId, streamId, userId, time.Now()); err != nil {
+		log.Printf("Error inserting question skip for question(%d): %v\n", questionId, err)
+		return 0, err
+	}
+	result, err := tx.Exec(INCREMENT_QUESTION_SKIP, questionId)
 	if err != nil {
 		log.Printf("Error incrementing question: %x\n", err)
 		return 0, err
 	}
-	question, _ := d.FindQuestionByID(questionId)
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return 0, errors.New("That question does not exist")
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing question skip for question(%d): %v\n", questionId, err)
+		return 0, err
+	}
 
+	question, ok := d.FindQuestionByID(questionId)
+	if !ok {
+		return 0, errors.New("That question does not exist")
+	}
 	return question.SkipCount, nil
 }
 
@@ -53,6 +70,144 @@ func (d *Database) DisableQuestion(questionId int) error {
 	return nil
 }
 
+// EnableQuestion
+// Re-enables a disabled question, and resets its skip count
+func (d *Database) EnableQuestion(questionId int) error {
+	statement, err := d.db.Prepare(ENABLE_QUESTION)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing enable question statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(questionId)
+	if err != nil {
+		log.Printf("Error marking question as enabled: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateQuestionText
+func (d *Database) UpdateQuestionText(questionId int, text string) error {
+	if existing, ok := d.findQuestionByText(text); ok && existing.ID != questionId {
+		return errors.New("That question already exists")
+	}
+
+	statement, err := d.db.Prepare(UPDATE_QUESTION_TEXT)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing update question text statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(text, questionId)
+	if err != nil {
+		log.Printf("Error updating text for question(%d): %v\n", questionId, err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteQuestion
+// Deletes the question with its tags, skips, schedules and days, and clears it from any streams it was asked on
+func (d *Database) DeleteQuestion(questionId int) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println("Error starting delete question transaction: ", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, query := range []string{CLEAR_STREAM_QUESTION, DELETE_QUESTION_SKIPS, DELETE_QUESTION_TAGS, DELETE_QUESTION_SCHEDULES, DELETE_QUESTION_QOTD_DAYS, DELETE_QUESTION} {
+		if _, err := tx.Exec(query, questionId); err != nil {
+			log.Printf("Error deleting question(%d): %v\n", questionId, err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type QuestionFilter struct {
+	Disabled *bool
+	Text     *string
+	MinSkips *int
+	Tag      *string
+	Limit    int
+	Offset   int
+}
+
+// FindQuestions
+// Returns a page of questions matching the filter, and the total number of matching questions
+func (d *Database) FindQuestions(filter QuestionFilter) ([]Question, int, error) {
+	var text *string
+	if filter.Text != nil {
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(*filter.Text)
+		text = &escaped
+	}
+	filterArgs := []any{
+		filter.Disabled, filter.Disabled,
+		text, text,
+		filter.MinSkips, filter.MinSkips,
+		filter.Tag, filter.Tag,
+	}
+
+	var total int
+	if err := d.db.QueryRow(COUNT_QUESTIONS, filterArgs...).Scan(&total); err != nil {
+		log.Println("Error counting questions: ", err)
+		return nil, 0, err
+	}
+
+	rows, err := d.db.Query(FIND_QUESTIONS, append(filterArgs, filter.Limit, filter.Offset)...)
+	if err != nil {
+		log.Println("Error finding questions: ", err)
+		return nil, 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	questions := []Question{}
+	for rows.Next() {
+		questions = append(questions, scanQuestionWithTags(rows))
+	}
+
+	return questions, total, nil
+}
+
+// Helper for scanning a question row, with its tags concatenated in the last column
+func scanQuestionWithTags(rows *sql.Rows) Question {
+	var question Question
+	var tags sql.NullString
+	rows.Scan(&question.ID, &question.Text, &question.Disabled, &question.SkipCount, &tags)
+	if tags.Valid {
+		question.Tags = strings.Split(tags.String, ",")
+	}
+	return question
+}
+
+func (d *Database) findQuestionByText(text string) (*Question, bool) {
+	rows, err := d.db.Query(FIND_QUESTION_BY_TEXT, text)
+	if err != nil {
+		log.Println("Error finding question by text: ", err)
+		return nil, false
+	}
+	defer func() { _ = rows.Close() }()
+	if !rows.Next() {
+		return nil, false
+	}
+
+	var question Question
+	rows.Scan(&question.ID, &question.Text, &question.Disabled, &question.SkipCount)
+
+	return &question, true
+}
+
 // FindQuestionByID
 func (d *Database) FindQuestionByID(ID int) (*Question, bool) {
 	rows, _ := d.db.Query(FIND_QUESTION_BY_ID, ID)
@@ -68,27 +223,69 @@ func (d *Database) FindQuestionByID(ID int) (*Question, bool) {
 }
 
 // FindRandomQuestion
-func (d *Database) FindRandomQuestion(streamId int) (*Question, error) {
+// Finds a question that has not been asked on the channel since its qotd epoch.
+// When every question has been asked, the epoch is reset so the pool is recycled.
+func (d *Database) FindRandomQuestion(userId int) (*Question, error) {
 	defaultQuestion := &Question{
 		ID:   0,
 		Text: "Go ask ChatGPT for your question!",
 	}
-	rows, err := d.db.Query(FIND_RANDOM_QUESTION, streamId)
-	defer func() { _ = rows.Close() }()
+
+	question, err := d.findRandomQuestion(userId)
+	if err == nil && question == nil {
+		log.Printf("Question pool exhausted for userId=%d, resetting qotd epoch", userId)
+		if err = d.ResetQotdEpoch(userId); err == nil {
+			question, err = d.findRandomQuestion(userId)
+		}
+	}
 	if err != nil {
-		log.Println("Error finding random question: ", err)
 		return defaultQuestion, err
 	}
-	if !rows.Next() {
+	if question == nil {
 		return defaultQuestion, errors.New("no questions found")
 	}
 
+	return question, nil
+}
+
+func (d *Database) findRandomQuestion(userId int) (*Question, error) {
+	rows, err := d.db.Query(FIND_RANDOM_QUESTION, userId, userId)
+	if err != nil {
+		log.Println("Error finding random question: ", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	if !rows.Next() {
+		return nil, nil
+	}
+
 	var question Question
 	rows.Scan(&question.ID, &question.Text, &question.Disabled, &question.SkipCount)
 
 	return &question, nil
 }
 
+// ResetQotdEpoch
+// Allows every question to be asked again on the channel
+func (d *Database) ResetQotdEpoch(userId int) error {
+	statement, err := d.db.Prepare(UPDATE_QOTD_EPOCH)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing update qotd epoch statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(time.Now(), userId)
+	if err != nil {
+		log.Println("Error resetting qotd epoch: ", err)
+		return err
+	}
+
+	return nil
+}
+
 // CreateQuestion
 func (d *Database) CreateQuestion(text string) (*Question, error) {
 	rows, _ := d.db.Query(FIND_QUESTION_BY_TEXT, text)
@@ -125,3 +322,24 @@ CREATE TABLE IF NOT EXISTS question (
     id INTEGER PRIMARY KEY,
     text TEXT UNIQUE
     )`
+
+const question_skip_table string = `
+CREATE TABLE IF NOT EXISTS question_skip (
+    id INTEGER PRIMARY KEY,
+    questionId INTEGER NOT NULL,
+    streamId INTEGER,
+    userId INTEGER NOT NULL,
+    skippedAt DATETIME,
+    FOREIGN KEY (questionId)
+    REFERENCES question (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    FOREIGN KEY (streamId)
+    REFERENCES stream (id)
+        ON UPDATE SET NULL
+        ON DELETE SET NULL
+    FOREIGN KEY (userId)
+    REFERENCES user (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    )`
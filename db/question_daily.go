@@ -0,0 +1,125 @@
+package db
+
+import (
+	"log"
+	"time"
+)
+
+// QotdLocation
+// Returns the channel's configured timezone, falling back to UTC if it can't be loaded
+func (c StreamConfig) QotdLocation() *time.Location {
+	location, err := time.LoadLocation(c.QotdTimezone)
+	if err != nil {
+		log.Printf("Invalid qotd timezone %q for userId(%d): %v\n", c.QotdTimezone, c.UserId, err)
+		return time.UTC
+	}
+	return location
+}
+
+// QotdDate
+// Returns the channel's local calendar day at the time, formatted like scheduled questions
+func (c StreamConfig) QotdDate(t time.Time) string {
+	return t.In(c.QotdLocation()).Format(SCHEDULE_DATE_FORMAT)
+}
+
+// UpdateQotdDaily
+// Sets whether the qotd is shared by every stream on the same day, and the timezone the day is in
+func (d *Database) UpdateQotdDaily(userId int, daily bool, timezone string) error {
+	statement, err := d.db.Prepare(UPDATE_QOTD_DAILY)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing update qotd daily statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(daily, timezone, userId)
+	if err != nil {
+		log.Printf("Error updating qotd daily for userId(%d): %v\n", userId, err)
+		return err
+	}
+
+	return nil
+}
+
+// FindDailyQuestion
+// Finds the question of the day already chosen for the channel on a date
+func (d *Database) FindDailyQuestion(userId int, date string) (*Question, bool) {
+	var questionId int
+	err := d.db.QueryRow(FIND_QOTD_DAY, userId, date).Scan(&questionId)
+	if err != nil {
+		return nil, false
+	}
+	return d.FindQuestionByID(questionId)
+}
+
+// SetDailyQuestion
+// Sets the question of the day for the channel on a date, nil clears it so a new one is chosen
+func (d *Database) SetDailyQuestion(userId int, date string, questionId *int) error {
+	query := UPSERT_QOTD_DAY
+	args := []interface{}{userId, date, questionId}
+	if questionId == nil {
+		query = DELETE_QOTD_DAY
+		args = args[:2]
+	}
+
+	statement, err := d.db.Prepare(query)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing qotd day statement: ", err)
+		return err
+	}
+
+	if _, err := statement.Exec(args...); err != nil {
+		log.Printf("Error setting qotd for userId(%d) on %s: %v\n", userId, date, err)
+		return err
+	}
+	return nil
+}
+
+const UPDATE_QOTD_DAILY string = `
+UPDATE stream_config
+SET qotdDaily=?, qotdTimezone=?
+WHERE userId=?
+`
+
+const FIND_QOTD_DAY string = `
+SELECT questionId
+FROM qotd_day
+WHERE userId=? AND date=?
+`
+
+const UPSERT_QOTD_DAY string = `
+INSERT INTO qotd_day (userId, date, questionId)
+VALUES (?,?,?)
+ON CONFLICT (userId, date) DO UPDATE SET questionId=excluded.questionId
+`
+
+const DELETE_QOTD_DAY string = `
+DELETE FROM qotd_day
+WHERE userId=? AND date=?
+`
+
+const DELETE_QUESTION_QOTD_DAYS string = `
+DELETE FROM qotd_day
+WHERE questionId=?
+`
+
+const qotd_day_table string = `
+CREATE TABLE IF NOT EXISTS qotd_day (
+    userId INTEGER NOT NULL,
+    date TEXT NOT NULL,
+    questionId INTEGER NOT NULL,
+    PRIMARY KEY (userId, date),
+    FOREIGN KEY (userId)
+    REFERENCES user (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    FOREIGN KEY (questionId)
+    REFERENCES question (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    )`
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQotdDate(t *testing.T) {
+	config := StreamConfig{UserId: testChannelId, QotdTimezone: "America/New_York"}
+	beforeMidnight := time.Date(2024, 5, 2, 3, 59, 59, 0, time.UTC)
+	atMidnight := time.Date(2024, 5, 2, 4, 0, 0, 0, time.UTC)
+	assert.Equal(t, "2024-05-01", config.QotdDate(beforeMidnight))
+	assert.Equal(t, "2024-05-02", config.QotdDate(atMidnight), "the day rolls over at local midnight")
+
+	config.QotdTimezone = "Not/A_Zone"
+	assert.Equal(t, time.UTC, config.QotdLocation())
+	assert.Equal(t, "2024-05-02", config.QotdDate(beforeMidnight))
+}
+
+func TestDailyQuestion(t *testing.T) {
+	d := newTestDatabase(t)
+	assert.NoError(t, d.UpdateQotdDaily(testChannelId, true, "America/New_York"))
+	streamUser, _ := d.FindStreamUserByUserID(testChannelId)
+	if !assert.True(t, streamUser.QotdDaily) {
+		t.FailNow()
+	}
+	lateStream := streamUser.QotdDate(time.Date(2024, 5, 2, 3, 30, 0, 0, time.UTC))
+	nextStream := streamUser.QotdDate(time.Date(2024, 5, 2, 4, 30, 0, 0, time.UTC))
+
+	questionId := 1
+	assert.NoError(t, d.SetDailyQuestion(testChannelId, lateStream, &questionId))
+	question, ok := d.FindDailyQuestion(testChannelId, lateStream)
+	if assert.True(t, ok, "streams on the same local day share the question") {
+		assert.Equal(t, 1, question.ID)
+	}
+	_, ok = d.FindDailyQuestion(testChannelId, nextStream)
+	assert.False(t, ok, "a stream after local midnight gets a new question")
+
+	assert.NoError(t, d.SetDailyQuestion(testChannelId, lateStream, nil))
+	_, ok = d.FindDailyQuestion(testChannelId, lateStream)
+	assert.False(t, ok)
+}
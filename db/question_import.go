@@ -0,0 +1,146 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+)
+
+// Twitch chat messages are limited to 500 characters
+const MAX_QUESTION_LENGTH = 500
+
+const (
+	IMPORT_CREATED   = "created"
+	IMPORT_DUPLICATE = "duplicate"
+	IMPORT_INVALID   = "invalid"
+)
+
+type QuestionImport struct {
+	Row  int
+	Text string
+	Tags []string
+}
+
+type QuestionImportResult struct {
+	Row      int    `json:"row"`
+	Status   string `json:"status"`
+	ID       *int   `json:"id,omitempty"`
+	Question string `json:"question"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ImportQuestions
+// Creates all valid, non-duplicate questions in a single transaction.
+// Returns a result for every row, or an error if the transaction was rolled back.
+func (d *Database) ImportQuestions(imports []QuestionImport) ([]QuestionImportResult, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println("Error starting question import transaction: ", err)
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	results := make([]QuestionImportResult, 0, len(imports))
+	for _, imp := range imports {
+		result := QuestionImportResult{Row: imp.Row, Question: strings.TrimSpace(imp.Text)}
+		if result.Question == "" {
+			result.Status = IMPORT_INVALID
+			result.Reason = "question text is empty"
+			results = append(results, result)
+			continue
+		}
+		if len(result.Question) > MAX_QUESTION_LENGTH {
+			result.Status = IMPORT_INVALID
+			result.Reason = "question text is longer than 500 characters"
+			results = append(results, result)
+			continue
+		}
+
+		var existingID int
+		err := tx.QueryRow(FIND_QUESTION_BY_TEXT, result.Question).Scan(&existingID, new(string), new(bool), new(int))
+		if err == nil {
+			result.Status = IMPORT_DUPLICATE
+			result.ID = &existingID
+			result.Reason = "question already exists"
+			results = append(results, result)
+			continue
+		} else if err != sql.ErrNoRows {
+			log.Printf("Error checking for duplicate question on row %d: %v\n", imp.Row, err)
+			return nil, err
+		}
+
+		inserted, err := tx.Exec(INSERT_QUESTION, result.Question)
+		if err != nil {
+			log.Printf("Error inserting question on row %d: %v\n", imp.Row, err)
+			return nil, err
+		}
+		newID, err := inserted.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		id := int(newID)
+
+		for _, tag := range NormalizeTags(imp.Tags) {
+			if _, err := tx.Exec(INSERT_QUESTION_TAG, id, tag); err != nil {
+				log.Printf("Error inserting tag for question on row %d: %v\n", imp.Row, err)
+				return nil, err
+			}
+		}
+
+		result.Status = IMPORT_CREATED
+		result.ID = &id
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing question import: ", err)
+		return nil, err
+	}
+	return results, nil
+}
+
+// FindAllQuestions
+// Returns every question including disabled ones, with their tags
+func (d *Database) FindAllQuestions() ([]Question, error) {
+	rows, err := d.db.Query(FIND_ALL_QUESTIONS)
+	if err != nil {
+		log.Println("Error finding all questions: ", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	questions := []Question{}
+	for rows.Next() {
+		questions = append(questions, scanQuestionWithTags(rows))
+	}
+
+	return questions, nil
+}
+
+// NormalizeTags
+// Lowercases and trims tags, splitting any that contain a comma or semicolon, and drops duplicates
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	normalized := []string{}
+	for _, tag := range tags {
+		for _, t := range strings.FieldsFunc(tag, func(r rune) bool { return r == ',' || r == ';' }) {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if t != "" && !seen[t] {
+				seen[t] = true
+				normalized = append(normalized, t)
+			}
+		}
+	}
+	return normalized
+}
+
+const question_tag_table string = `
+CREATE TABLE IF NOT EXISTS question_tag (
+    questionId INTEGER NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (questionId, tag),
+    FOREIGN KEY (questionId)
+    REFERENCES question (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    )`
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportQuestions(t *testing.T) {
+	d := newTestDatabase(t)
+	existing, ok := d.FindQuestionByID(1)
+	if !assert.True(t, ok) {
+		t.FailNow()
+	}
+
+	results, err := d.ImportQuestions([]QuestionImport{
+		{Row: 1, Text: "  What's your favourite boss fight?  ", Tags: []string{"Games; Bosses", "games"}},
+		{Row: 2, Text: ""},
+		{Row: 3, Text: strings.Repeat("a", MAX_QUESTION_LENGTH+1)},
+		{Row: 4, Text: existing.Text},
+		{Row: 5, Text: "What's your favourite boss fight?"},
+	})
+	if !assert.NoError(t, err) || !assert.Len(t, results, 5) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, IMPORT_CREATED, results[0].Status)
+	assert.Equal(t, "What's your favourite boss fight?", results[0].Question, "the text is trimmed")
+	assert.Equal(t, IMPORT_INVALID, results[1].Status)
+	assert.Equal(t, IMPORT_INVALID, results[2].Status)
+	assert.Equal(t, IMPORT_DUPLICATE, results[3].Status)
+	assert.Equal(t, existing.ID, *results[3].ID)
+	assert.Equal(t, IMPORT_DUPLICATE, results[4].Status, "rows are checked against earlier rows in the import")
+	assert.Equal(t, *results[0].ID, *results[4].ID)
+	for i, result := range results {
+		assert.Equal(t, i+1, result.Row)
+	}
+
+	questions, err := d.FindAllQuestions()
+	assert.NoError(t, err)
+	var created *Question
+	for i := range questions {
+		if questions[i].ID == *results[0].ID {
+			created = &questions[i]
+		}
+	}
+	if assert.NotNil(t, created, "the created question is exported") {
+		assert.ElementsMatch(t, []string{"games", "bosses"}, created.Tags)
+	}
+}
+
+func TestExportQuestionsIncludesDisabled(t *testing.T) {
+	d := newTestDatabase(t)
+	assert.NoError(t, d.DisableQuestion(1))
+
+	questions, err := d.FindAllQuestions()
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, questions) {
+		assert.Equal(t, 1, questions[0].ID)
+		assert.True(t, questions[0].Disabled)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"games", "bosses", "music"}, NormalizeTags([]string{" Games ;bosses", "games,MUSIC", ""}))
+	assert.Empty(t, NormalizeTags(nil))
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Questions at least this similar after normalizing are likely duplicates
+const SIMILAR_QUESTION_THRESHOLD = 0.85
+
+type SimilarQuestion struct {
+	Question
+	Similarity float64 `json:"similarity"`
+}
+
+// Longer forms are listed first, so they are matched before their suffixes
+var contractions = strings.NewReplacer(
+	"won't", "will not",
+	"can't", "can not",
+	"cannot", "can not",
+	"shan't", "shall not",
+	"n't", " not",
+	"'re", " are",
+	"'ve", " have",
+	"'ll", " will",
+	"'m", " am",
+	"'d", " would",
+	"'s", " is",
+)
+
+// NormalizeText
+// Lowercases, expands contractions, strips punctuation and collapses whitespace
+func NormalizeText(text string) string {
+	text = strings.ToLower(text)
+	text = strings.NewReplacer("’", "'", "‘", "'", "`", "'").Replace(text)
+	text = contractions.Replace(text)
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// Similarity
+// Returns how alike two normalized strings are, from 0 to 1, based on their Levenshtein distance
+func Similarity(a string, b string) float64 {
+	longest := len([]rune(a))
+	if l := len([]rune(b)); l > longest {
+		longest = l
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(Levenshtein(a, b))/float64(longest)
+}
+
+// Levenshtein
+// Returns the number of single character edits needed to turn a into b
+func Levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// FindSimilarQuestions
+// Returns existing questions that are likely duplicates of the text, most similar first
+func (d *Database) FindSimilarQuestions(text string) ([]SimilarQuestion, error) {
+	questions, err := d.FindAllQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := NormalizeText(text)
+	similar := []SimilarQuestion{}
+	for _, question := range questions {
+		similarity := Similarity(normalized, NormalizeText(question.Text))
+		if similarity >= SIMILAR_QUESTION_THRESHOLD {
+			similar = append(similar, SimilarQuestion{Question: question, Similarity: similarity})
+		}
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool { return similar[i].Similarity > similar[j].Similarity })
+	return similar, nil
+}
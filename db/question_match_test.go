@@ -0,0 +1,30 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeText(t *testing.T) {
+	assert.Equal(t, "what is your favorite movie", NormalizeText("What's your   favorite movie?"))
+	assert.Equal(t, "i can not do not will not", NormalizeText("I can’t, DON'T... won't!"))
+	assert.Equal(t, "", NormalizeText(" ?! "))
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, Levenshtein("movie", "movie"))
+	assert.Equal(t, 3, Levenshtein("kitten", "sitting"))
+	assert.Equal(t, 5, Levenshtein("", "movie"))
+}
+
+func TestSimilarity(t *testing.T) {
+	a := NormalizeText("What's your favorite movie?")
+	b := NormalizeText("what is your favourite movie")
+	assert.GreaterOrEqual(t, Similarity(a, b), SIMILAR_QUESTION_THRESHOLD)
+
+	c := NormalizeText("What's your favorite board game?")
+	assert.Less(t, Similarity(a, c), SIMILAR_QUESTION_THRESHOLD)
+
+	assert.Equal(t, 1.0, Similarity("", ""))
+}
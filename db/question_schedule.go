@@ -0,0 +1,171 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Date format of scheduled questions, in the channel's local calendar
+const SCHEDULE_DATE_FORMAT = "2006-01-02"
+
+type ScheduledQuestion struct {
+	ID         int       `json:"id"`
+	UserId     int       `json:"userId"`
+	QuestionId int       `json:"questionId"`
+	Text       string    `json:"text"`
+	Date       *string   `json:"date"`
+	StreamId   *int      `json:"streamId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ScheduleQuestion
+// Queues a question for a channel on a date, or for the next stream when date is nil
+func (d *Database) ScheduleQuestion(userId int, questionId int, date *string) (*ScheduledQuestion, error) {
+	statement, err := d.db.Prepare(INSERT_QUESTION_SCHEDULE)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing insert question schedule statement: ", err)
+		return nil, err
+	}
+
+	createdAt := time.Now()
+	result, err := statement.Exec(userId, questionId, date, createdAt)
+	if err != nil {
+		log.Printf("Error scheduling question(%d) for userId(%d): %v\n", questionId, userId, err)
+		return nil, err
+	}
+
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScheduledQuestion{
+		ID:         int(newID),
+		UserId:     userId,
+		QuestionId: questionId,
+		Date:       date,
+		CreatedAt:  createdAt,
+	}, nil
+}
+
+// FindPendingScheduledQuestions
+// Returns questions queued for a channel that have not been asked yet, in the order they will be used
+func (d *Database) FindPendingScheduledQuestions(userId int) ([]ScheduledQuestion, error) {
+	rows, err := d.db.Query(FIND_PENDING_SCHEDULED_QUESTIONS, userId)
+	if err != nil {
+		log.Println("Error finding scheduled questions: ", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	scheduled := []ScheduledQuestion{}
+	for rows.Next() {
+		scheduled = append(scheduled, scanScheduledQuestion(rows))
+	}
+	return scheduled, nil
+}
+
+// UseScheduledQuestion
+// Finds the question scheduled for the date, or the next stream, and marks it as used by the stream.
+// Questions scheduled for a day without a stream are asked on the next stream, oldest first.
+// Only the first question for a stream comes from the schedule, so a skip falls back to a random question.
+func (d *Database) UseScheduledQuestion(stream *Stream, date string) (*Question, bool) {
+	rows, err := d.db.Query(FIND_SCHEDULED_QUESTION, stream.UserId, date, stream.ID)
+	if err != nil {
+		log.Println("Error finding scheduled question: ", err)
+		return nil, false
+	}
+	if !rows.Next() {
+		rows.Close()
+		return nil, false
+	}
+	scheduled := scanScheduledQuestion(rows)
+	// Have to close the rows, otherwise database is locked.
+	rows.Close()
+
+	statement, err := d.db.Prepare(UPDATE_QUESTION_SCHEDULE_STREAM)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing update question schedule statement: ", err)
+		return nil, false
+	}
+	if _, err := statement.Exec(stream.ID, scheduled.ID); err != nil {
+		log.Printf("Error marking scheduled question(%d) as used: %v\n", scheduled.ID, err)
+		return nil, false
+	}
+
+	return d.FindQuestionByID(scheduled.QuestionId)
+}
+
+func scanScheduledQuestion(rows *sql.Rows) ScheduledQuestion {
+	var scheduled ScheduledQuestion
+	rows.Scan(
+		&scheduled.ID,
+		&scheduled.UserId,
+		&scheduled.QuestionId,
+		&scheduled.Text,
+		&scheduled.Date,
+		&scheduled.StreamId,
+		&scheduled.CreatedAt,
+	)
+	return scheduled
+}
+
+const INSERT_QUESTION_SCHEDULE string = `
+INSERT INTO question_schedule (userId, questionId, scheduledDate, createdAt)
+VALUES (?,?,?,?)
+`
+
+const FIND_PENDING_SCHEDULED_QUESTIONS string = `
+SELECT qs.id, qs.userId, qs.questionId, q.text, qs.scheduledDate, qs.streamId, qs.createdAt
+FROM question_schedule qs
+JOIN question q ON q.id=qs.questionId
+WHERE qs.userId=? AND qs.streamId IS NULL
+ORDER BY qs.scheduledDate IS NULL, qs.scheduledDate, qs.id
+`
+
+// Questions for the date or a missed earlier date come before those queued for the next stream
+const FIND_SCHEDULED_QUESTION string = `
+SELECT qs.id, qs.userId, qs.questionId, q.text, qs.scheduledDate, qs.streamId, qs.createdAt
+FROM question_schedule qs
+JOIN question q ON q.id=qs.questionId
+WHERE qs.userId=? AND qs.streamId IS NULL
+    AND (qs.scheduledDate<=? OR qs.scheduledDate IS NULL)
+    AND NOT EXISTS (SELECT 1 FROM question_schedule WHERE streamId=?)
+ORDER BY qs.scheduledDate IS NULL, qs.scheduledDate, qs.id
+LIMIT 1
+`
+
+const UPDATE_QUESTION_SCHEDULE_STREAM string = `
+UPDATE question_schedule
+SET streamId=?
+WHERE id=?
+`
+
+const question_schedule_table string = `
+CREATE TABLE IF NOT EXISTS question_schedule (
+    id INTEGER PRIMARY KEY,
+    userId INTEGER NOT NULL,
+    questionId INTEGER NOT NULL,
+    scheduledDate TEXT,
+    streamId INTEGER,
+    createdAt DATETIME,
+    FOREIGN KEY (userId)
+    REFERENCES user (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    FOREIGN KEY (questionId)
+    REFERENCES question (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    FOREIGN KEY (streamId)
+    REFERENCES stream (id)
+        ON UPDATE SET NULL
+        ON DELETE SET NULL
+    )`
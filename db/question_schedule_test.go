@@ -0,0 +1,35 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseScheduledQuestion(t *testing.T) {
+	d := newTestDatabase(t)
+	missed, today := "2024-05-01", "2024-05-03"
+	_, err := d.ScheduleQuestion(testChannelId, 2, nil)
+	assert.NoError(t, err)
+	_, err = d.ScheduleQuestion(testChannelId, 3, &today)
+	assert.NoError(t, err)
+	_, err = d.ScheduleQuestion(testChannelId, 1, &missed)
+	assert.NoError(t, err)
+
+	// The question for a day without a stream is asked first, then the question for today
+	for _, questionId := range []int{1, 3, 2} {
+		stream, err := d.InsertStream(testChannelId, time.Now())
+		if !assert.NoError(t, err) {
+			return
+		}
+		question, ok := d.UseScheduledQuestion(stream, today)
+		if assert.True(t, ok) {
+			assert.Equal(t, questionId, question.ID)
+		}
+	}
+
+	pending, err := d.FindPendingScheduledQuestions(testChannelId)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type QuestionSearchResult struct {
+	Question
+	Rank float64 `json:"rank"`
+}
+
+// initQuestionSearch
+// Creates the full-text index of question text, kept in sync by triggers, and rebuilds it from the question table.
+// FTS5 requires building with `-tags sqlite_fts5`. Without it, search falls back to matching words in Go.
+func initQuestionSearch(db *sql.DB) bool {
+	if _, err := prepareAndExec(db, question_fts_table); err != nil {
+		log.Println("create question_fts_table failed, full-text search is disabled: ", err)
+		dropQuestionSearchTriggers(db)
+		return false
+	}
+
+	for _, trigger := range []string{question_fts_insert_trigger, question_fts_delete_trigger, question_fts_update_trigger} {
+		if _, err := prepareAndExec(db, trigger); err != nil {
+			log.Println("create question_fts trigger failed: ", err)
+			dropQuestionSearchTriggers(db)
+			return false
+		}
+	}
+
+	// Also detects an index left by a build with FTS5, as creating it is skipped when it exists
+	if _, err := prepareAndExec(db, REBUILD_QUESTION_FTS); err != nil {
+		log.Println("question_fts rebuild failed, full-text search is disabled: ", err)
+		dropQuestionSearchTriggers(db)
+		return false
+	}
+	return true
+}
+
+// Triggers left without FTS5 available would fail every write to the question table
+func dropQuestionSearchTriggers(db *sql.DB) {
+	for _, trigger := range []string{"question_fts_insert", "question_fts_delete", "question_fts_update"} {
+		if _, err := prepareAndExec(db, "DROP TRIGGER IF EXISTS "+trigger); err != nil {
+			log.Println("drop question_fts trigger failed: ", err)
+		}
+	}
+}
+
+// SearchQuestions
+// Finds questions containing any of the words in the query, best matches first
+func (d *Database) SearchQuestions(query string, limit int) ([]QuestionSearchResult, error) {
+	words := searchWords(query)
+	if len(words) == 0 {
+		return []QuestionSearchResult{}, nil
+	}
+	if !d.ftsEnabled {
+		return d.searchQuestionsWithoutIndex(words, limit)
+	}
+
+	// Quote each word so FTS5 syntax in chat input can't break the query, and match prefixes
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = `"` + word + `"*`
+	}
+
+	rows, err := d.db.Query(SEARCH_QUESTIONS, strings.Join(terms, " OR "), limit)
+	if err != nil {
+		log.Println("Error searching questions: ", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	results := []QuestionSearchResult{}
+	for rows.Next() {
+		var result QuestionSearchResult
+		rows.Scan(&result.ID, &result.Text, &result.Disabled, &result.SkipCount, &result.Rank)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// searchQuestionsWithoutIndex
+// Ranks every question by the number of query words it contains
+func (d *Database) searchQuestionsWithoutIndex(words []string, limit int) ([]QuestionSearchResult, error) {
+	questions, err := d.FindAllQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []QuestionSearchResult{}
+	for _, question := range questions {
+		text := strings.ToLower(question.Text)
+		matches := 0
+		for _, word := range words {
+			if strings.Contains(text, word) {
+				matches++
+			}
+		}
+		if matches > 0 {
+			// Negative to match FTS5, where a lower rank is a better match
+			results = append(results, QuestionSearchResult{Question: question, Rank: -float64(matches)})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank < results[j].Rank })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// searchWords
+// Splits a search query into lowercase words, dropping punctuation
+func searchWords(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+const SEARCH_QUESTIONS string = `
+SELECT q.id, q.text, q.disabled, q.skipCount, bm25(question_fts) AS rank
+FROM question_fts
+JOIN question q ON q.id=question_fts.rowid
+WHERE question_fts MATCH ?
+ORDER BY rank
+LIMIT ?
+`
+
+const REBUILD_QUESTION_FTS string = `
+INSERT INTO question_fts(question_fts) VALUES('rebuild')
+`
+
+const question_fts_table string = `
+CREATE VIRTUAL TABLE IF NOT EXISTS question_fts USING fts5(
+    text,
+    content='question',
+    content_rowid='id'
+    )`
+
+const question_fts_insert_trigger string = `
+CREATE TRIGGER IF NOT EXISTS question_fts_insert AFTER INSERT ON question BEGIN
+    INSERT INTO question_fts(rowid, text) VALUES (new.id, new.text);
+END`
+
+const question_fts_delete_trigger string = `
+CREATE TRIGGER IF NOT EXISTS question_fts_delete AFTER DELETE ON question BEGIN
+    INSERT INTO question_fts(question_fts, rowid, text) VALUES ('delete', old.id, old.text);
+END`
+
+const question_fts_update_trigger string = `
+CREATE TRIGGER IF NOT EXISTS question_fts_update AFTER UPDATE OF text ON question BEGIN
+    INSERT INTO question_fts(question_fts, rowid, text) VALUES ('delete', old.id, old.text);
+    INSERT INTO question_fts(rowid, text) VALUES (new.id, new.text);
+END`
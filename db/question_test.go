@@ -0,0 +1,80 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncrementQuestionSkip(t *testing.T) {
+	d := newTestDatabase(t)
+	stream, err := d.InsertStream(testChannelId, time.Now())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	skipCount, err := d.IncrementQuestionSkip(1, stream.ID, testViewerId)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, skipCount)
+	skipCount, err = d.IncrementQuestionSkip(1, stream.ID, testChannelId)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, skipCount)
+
+	_, err = d.IncrementQuestionSkip(999999, stream.ID, testViewerId)
+	assert.Error(t, err)
+
+	var skips int
+	assert.NoError(t, d.db.QueryRow(`SELECT COUNT(*) FROM question_skip`).Scan(&skips))
+	assert.Equal(t, 2, skips, "a skip isn't recorded without its count")
+}
+
+func TestEnableQuestionResetsSkipCount(t *testing.T) {
+	d := newTestDatabase(t)
+	stream, err := d.InsertStream(testChannelId, time.Now())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	d.IncrementQuestionSkip(1, stream.ID, testViewerId)
+	d.IncrementQuestionSkip(1, stream.ID, testChannelId)
+	assert.NoError(t, d.DisableQuestion(1))
+
+	question, _ := d.FindQuestionByID(1)
+	assert.True(t, question.Disabled)
+	assert.Equal(t, 2, question.SkipCount)
+
+	assert.NoError(t, d.EnableQuestion(1))
+	question, _ = d.FindQuestionByID(1)
+	assert.False(t, question.Disabled)
+	assert.Equal(t, 0, question.SkipCount)
+}
+
+func TestFindRandomQuestionRecyclesPool(t *testing.T) {
+	d := newTestDatabase(t)
+	_, err := d.db.Exec(`UPDATE question SET disabled=true WHERE id>2`)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ask := func() int {
+		stream, err := d.InsertStream(testChannelId, time.Now())
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		question, err := d.FindRandomQuestion(testChannelId)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.NoError(t, d.UpdateStreamQuestion(stream.ID, &question.ID))
+		return question.ID
+	}
+
+	first := ask()
+	second := ask()
+	assert.ElementsMatch(t, []int{1, 2}, []int{first, second}, "questions aren't repeated until the pool runs out")
+
+	before, _ := d.FindStreamUserByUserID(testChannelId)
+	assert.Equal(t, first, ask(), "the pool starts again with the least recently asked question")
+	after, _ := d.FindStreamUserByUserID(testChannelId)
+	assert.True(t, after.QotdEpoch.After(before.QotdEpoch), "the qotd epoch is reset")
+	assert.Equal(t, second, ask())
+}
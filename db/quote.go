@@ -0,0 +1,250 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+)
+
+// Quote
+// Something said on stream, numbered per channel, with the game and stream title when it was quoted
+type Quote struct {
+	ID          int       `json:"id"`
+	ChannelId   int       `json:"channelId"`
+	Number      int       `json:"number"`
+	Text        string    `json:"text"`
+	Quotee      string    `json:"quotee"`
+	Game        *string   `json:"game"`
+	StreamTitle *string   `json:"streamTitle"`
+	StreamId    *int      `json:"streamId"`
+	AddedBy     *int      `json:"addedBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// The most quotes a search returns
+const QUOTE_SEARCH_LIMIT = 10
+
+// InsertQuote
+// Adds the quote with the channel's next number
+func (d *Database) InsertQuote(quote Quote) (*Quote, error) {
+	statement, err := d.db.Prepare(INSERT_QUOTE)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing insert quote statement: ", err)
+		return nil, err
+	}
+
+	quote.CreatedAt = time.Now()
+	result, err := statement.Exec(
+		quote.ChannelId,
+		quote.ChannelId,
+		quote.Text,
+		quote.Quotee,
+		quote.Game,
+		quote.StreamTitle,
+		quote.StreamId,
+		quote.AddedBy,
+		quote.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("Error inserting quote for channel(%d): %v\n", quote.ChannelId, err)
+		return nil, err
+	}
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	quote.ID = int(newID)
+	if err := d.db.QueryRow(FIND_QUOTE_NUMBER, quote.ID).Scan(&quote.Number); err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// FindQuote
+// Returns the channel's quote with that number, or nil if there isn't one
+func (d *Database) FindQuote(channelId int, number int) (*Quote, error) {
+	return d.findQuote(FIND_QUOTE_BY_NUMBER, channelId, number)
+}
+
+// FindRandomQuote
+// Returns one of the channel's quotes at random, or nil if it has none
+func (d *Database) FindRandomQuote(channelId int) (*Quote, error) {
+	return d.findQuote(FIND_RANDOM_QUOTE, channelId)
+}
+
+func (d *Database) findQuote(query string, args ...any) (*Quote, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		log.Println("Error finding quote: ", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		return nil, nil
+	}
+	quote := scanQuote(rows)
+	return &quote, nil
+}
+
+// SearchQuotes
+// Returns the channel's quotes containing every word, in the text or who was quoted
+func (d *Database) SearchQuotes(channelId int, words []string) ([]Quote, error) {
+	query := FIND_QUOTES_BY_CHANNEL
+	args := []any{channelId}
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	for _, word := range words {
+		query += ` AND (text LIKE ? ESCAPE '\' OR quotee LIKE ? ESCAPE '\')`
+		pattern := "%" + escaper.Replace(word) + "%"
+		args = append(args, pattern, pattern)
+	}
+	query += ` ORDER BY number LIMIT ?`
+	args = append(args, QUOTE_SEARCH_LIMIT)
+
+	return d.findQuotes(query, args...)
+}
+
+// FindQuotes
+// Returns a page of the channel's quotes in order, and how many quotes it has. A limit of -1 returns every quote.
+func (d *Database) FindQuotes(channelId int, limit int, offset int) ([]Quote, int, error) {
+	var total int
+	if err := d.db.QueryRow(COUNT_QUOTES, channelId).Scan(&total); err != nil {
+		log.Printf("Error counting quotes for channel(%d): %v\n", channelId, err)
+		return nil, 0, err
+	}
+
+	quotes, err := d.findQuotes(FIND_QUOTES_BY_CHANNEL+` ORDER BY number LIMIT ? OFFSET ?`, channelId, limit, offset)
+	return quotes, total, err
+}
+
+func (d *Database) findQuotes(query string, args ...any) ([]Quote, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		log.Println("Error finding quotes: ", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	quotes := []Quote{}
+	for rows.Next() {
+		quotes = append(quotes, scanQuote(rows))
+	}
+	return quotes, nil
+}
+
+// DeleteQuote
+// Deletes the channel's quote, reporting false if the channel has no quote with that number.
+// Numbers aren't reused, so links to other quotes stay the same.
+func (d *Database) DeleteQuote(channelId int, number int) (bool, error) {
+	statement, err := d.db.Prepare(DELETE_QUOTE)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing delete quote statement: ", err)
+		return false, err
+	}
+
+	result, err := statement.Exec(time.Now(), channelId, number)
+	if err != nil {
+		log.Printf("Error deleting quote #%d in channel(%d): %v\n", number, channelId, err)
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+func scanQuote(rows *sql.Rows) Quote {
+	var quote Quote
+	err := rows.Scan(
+		&quote.ID,
+		&quote.ChannelId,
+		&quote.Number,
+		&quote.Text,
+		&quote.Quotee,
+		&quote.Game,
+		&quote.StreamTitle,
+		&quote.StreamId,
+		&quote.AddedBy,
+		&quote.CreatedAt,
+	)
+	if err != nil {
+		log.Println("Error scanning quote: ", err)
+	}
+	return quote
+}
+
+const INSERT_QUOTE string = `
+INSERT INTO quote (channelId, number, text, quotee, game, streamTitle, streamId, addedBy, createdAt)
+VALUES (?,(SELECT coalesce(max(number), 0) + 1 FROM quote WHERE channelId=?),?,?,?,?,?,?,?)
+`
+
+const FIND_QUOTE_NUMBER string = `
+SELECT number
+FROM quote
+WHERE id=?
+`
+
+const QUOTE_COLUMNS string = `id, channelId, number, text, quotee, game, streamTitle, streamId, addedBy, createdAt`
+
+const FIND_QUOTE_BY_NUMBER string = `
+SELECT ` + QUOTE_COLUMNS + `
+FROM quote
+WHERE channelId=? AND number=? AND deletedAt IS NULL
+`
+
+const FIND_RANDOM_QUOTE string = `
+SELECT ` + QUOTE_COLUMNS + `
+FROM quote
+WHERE channelId=? AND deletedAt IS NULL
+ORDER BY RANDOM()
+LIMIT 1
+`
+
+// Searches and pages add their own conditions and ordering
+const FIND_QUOTES_BY_CHANNEL string = `
+SELECT ` + QUOTE_COLUMNS + `
+FROM quote
+WHERE channelId=? AND deletedAt IS NULL`
+
+const COUNT_QUOTES string = `
+SELECT count(*)
+FROM quote
+WHERE channelId=? AND deletedAt IS NULL
+`
+
+const DELETE_QUOTE string = `
+UPDATE quote
+SET deletedAt=?
+WHERE channelId=? AND number=? AND deletedAt IS NULL
+`
+
+const quote_table string = `
+CREATE TABLE IF NOT EXISTS quote (
+    id INTEGER PRIMARY KEY,
+    channelId INTEGER NOT NULL,
+    number INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    quotee TEXT NOT NULL,
+    game TEXT,
+    streamTitle TEXT,
+    streamId INTEGER,
+    addedBy INTEGER,
+    createdAt DATETIME NOT NULL,
+    deletedAt DATETIME,
+    UNIQUE (channelId, number),
+    FOREIGN KEY (channelId)
+    REFERENCES user (id),
+    FOREIGN KEY (streamId)
+    REFERENCES stream (id),
+    FOREIGN KEY (addedBy)
+    REFERENCES user (id)
+    )`
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotes(t *testing.T) {
+	d := newTestDatabase(t)
+	game := "Elden Ring"
+	first, err := d.InsertQuote(Quote{ChannelId: testChannelId, Text: "I meant to do that", Quotee: "SouLxBurN", Game: &game})
+	if !assert.NoError(t, err) {
+		return
+	}
+	second, err := d.InsertQuote(Quote{ChannelId: testChannelId, Text: "100% on purpose", Quotee: "kinda_cringe_dev"})
+	assert.NoError(t, err)
+	other, err := d.InsertQuote(Quote{ChannelId: testViewerId, Text: "Hello", Quotee: "kinda_cringe_dev"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, first.Number)
+	assert.Equal(t, 2, second.Number)
+	assert.Equal(t, 1, other.Number, "quotes are numbered per channel")
+
+	quote, err := d.FindQuote(testChannelId, 1)
+	assert.NoError(t, err)
+	if assert.NotNil(t, quote) {
+		assert.Equal(t, "I meant to do that", quote.Text)
+		assert.Equal(t, "Elden Ring", *quote.Game)
+	}
+
+	found, err := d.SearchQuotes(testChannelId, []string{"MEANT", "do"})
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+	found, err = d.SearchQuotes(testChannelId, []string{"%"})
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1, "like wildcards are matched literally") {
+		assert.Equal(t, 2, found[0].Number)
+	}
+
+	deleted, err := d.DeleteQuote(testChannelId, 1)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	quote, err = d.FindQuote(testChannelId, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, quote)
+
+	third, err := d.InsertQuote(Quote{ChannelId: testChannelId, Text: "Chat, it's fine", Quotee: "SouLxBurN"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, third.Number, "numbers aren't reused")
+
+	quotes, total, err := d.FindQuotes(testChannelId, -1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, quotes, 2)
+}
@@ -0,0 +1,374 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Raffle statuses. Open raffles take entries, closed raffles are waiting to be drawn.
+const (
+	RAFFLE_OPEN      = "open"
+	RAFFLE_CLOSED    = "closed"
+	RAFFLE_DRAWN     = "drawn"
+	RAFFLE_CANCELLED = "cancelled"
+)
+
+// Raffle winner statuses. Winners must reply in chat to claim the prize.
+const (
+	RAFFLE_WINNER_PENDING  = "pending"
+	RAFFLE_WINNER_CLAIMED  = "claimed"
+	RAFFLE_WINNER_MISSED   = "missed"
+	RAFFLE_WINNER_REROLLED = "rerolled"
+)
+
+// Raffle
+// A giveaway in a channel. Viewers enter by typing the keyword, and can be limited to
+// subscribers, followers, or viewers who have watched the channel for a number of minutes.
+type Raffle struct {
+	ID              int        `json:"id"`
+	ChannelId       int        `json:"channelId"`
+	StreamId        *int       `json:"streamId"`
+	Keyword         string     `json:"keyword"`
+	SubOnly         bool       `json:"subOnly"`
+	FollowerOnly    bool       `json:"followerOnly"`
+	MinWatchMinutes int        `json:"minWatchMinutes"`
+	Status          string     `json:"status"`
+	OpenedBy        int        `json:"openedBy"`
+	OpenedAt        time.Time  `json:"openedAt"`
+	ClosesAt        *time.Time `json:"closesAt"`
+	ClosedAt        *time.Time `json:"closedAt"`
+}
+
+// RaffleEntry is a viewer's entry in a raffle, weighted by their subscriber tier
+type RaffleEntry struct {
+	RaffleId  int
+	User      User
+	Tier      int
+	Weight    int
+	EnteredAt time.Time
+}
+
+// RaffleWinner is a viewer drawn from a raffle, and whether they claimed the prize
+type RaffleWinner struct {
+	ID          int
+	RaffleId    int
+	UserId      int
+	Status      string
+	DrawnAt     time.Time
+	RespondedAt *time.Time
+}
+
+// InsertRaffle
+func (d *Database) InsertRaffle(raffle Raffle) (*Raffle, error) {
+	statement, err := d.db.Prepare(INSERT_RAFFLE)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing insert raffle statement: ", err)
+		return nil, err
+	}
+
+	raffle.Status = RAFFLE_OPEN
+	raffle.OpenedAt = time.Now()
+	result, err := statement.Exec(
+		raffle.ChannelId,
+		raffle.StreamId,
+		raffle.Keyword,
+		raffle.SubOnly,
+		raffle.FollowerOnly,
+		raffle.MinWatchMinutes,
+		raffle.Status,
+		raffle.OpenedBy,
+		raffle.OpenedAt,
+		raffle.ClosesAt,
+	)
+	if err != nil {
+		log.Printf("Error inserting raffle for channel(%d): %v\n", raffle.ChannelId, err)
+		return nil, err
+	}
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	raffle.ID = int(newID)
+	return &raffle, nil
+}
+
+// UpdateRaffleStatus
+// Sets the raffle's status, and when it stopped taking entries if it's no longer open
+func (d *Database) UpdateRaffleStatus(id int, status string) error {
+	statement, err := d.db.Prepare(UPDATE_RAFFLE_STATUS)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing update raffle status statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(status, status, RAFFLE_OPEN, time.Now(), id)
+	if err != nil {
+		log.Printf("Error updating raffle(%d) to %s: %v\n", id, status, err)
+		return err
+	}
+
+	return nil
+}
+
+// FindActiveRaffles
+// Returns every raffle that is open or waiting to be drawn
+func (d *Database) FindActiveRaffles() ([]Raffle, error) {
+	rows, err := d.db.Query(FIND_ACTIVE_RAFFLES, RAFFLE_OPEN, RAFFLE_CLOSED)
+	if err != nil {
+		log.Println("Error finding active raffles: ", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	raffles := []Raffle{}
+	for rows.Next() {
+		var raffle Raffle
+		err := rows.Scan(
+			&raffle.ID,
+			&raffle.ChannelId,
+			&raffle.StreamId,
+			&raffle.Keyword,
+			&raffle.SubOnly,
+			&raffle.FollowerOnly,
+			&raffle.MinWatchMinutes,
+			&raffle.Status,
+			&raffle.OpenedBy,
+			&raffle.OpenedAt,
+			&raffle.ClosesAt,
+			&raffle.ClosedAt,
+		)
+		if err != nil {
+			log.Println("Error scanning raffle: ", err)
+			continue
+		}
+		raffles = append(raffles, raffle)
+	}
+	return raffles, nil
+}
+
+// InsertRaffleEntry
+// Enters the viewer in the raffle, reporting false if they had already entered
+func (d *Database) InsertRaffleEntry(raffleId int, userId int, tier int, weight int) (bool, error) {
+	result, err := d.db.Exec(INSERT_RAFFLE_ENTRY, raffleId, userId, tier, weight, time.Now())
+	if err != nil {
+		log.Printf("Error entering userId(%d) in raffle(%d): %v\n", userId, raffleId, err)
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted > 0, nil
+}
+
+// HasRaffleEntry
+// Reports if the user has entered the raffle, or true if it can't be checked so they aren't entered twice
+func (d *Database) HasRaffleEntry(raffleId int, userId int) bool {
+	var count int
+	err := d.db.QueryRow(COUNT_RAFFLE_ENTRY, raffleId, userId).Scan(&count)
+	if err != nil {
+		log.Printf("Error checking if userId(%d) entered raffle(%d): %v\n", userId, raffleId, err)
+		return true
+	}
+	return count > 0
+}
+
+// FindRaffleEntries
+// Returns everyone who entered the raffle, in the order they entered
+func (d *Database) FindRaffleEntries(raffleId int) ([]RaffleEntry, error) {
+	rows, err := d.db.Query(FIND_RAFFLE_ENTRIES, raffleId)
+	if err != nil {
+		log.Printf("Error finding entries in raffle(%d): %v\n", raffleId, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	entries := []RaffleEntry{}
+	for rows.Next() {
+		var entry RaffleEntry
+		err := rows.Scan(
+			&entry.RaffleId,
+			&entry.User.ID,
+			&entry.User.Username,
+			&entry.User.DisplayName,
+			&entry.Tier,
+			&entry.Weight,
+			&entry.EnteredAt,
+		)
+		if err != nil {
+			log.Println("Error scanning raffle entry: ", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// InsertRaffleWinner
+// Records the viewer drawn from the raffle, waiting for them to claim the prize
+func (d *Database) InsertRaffleWinner(raffleId int, userId int) (*RaffleWinner, error) {
+	winner := RaffleWinner{
+		RaffleId: raffleId,
+		UserId:   userId,
+		Status:   RAFFLE_WINNER_PENDING,
+		DrawnAt:  time.Now(),
+	}
+	result, err := d.db.Exec(INSERT_RAFFLE_WINNER, winner.RaffleId, winner.UserId, winner.Status, winner.DrawnAt)
+	if err != nil {
+		log.Printf("Error inserting winner userId(%d) of raffle(%d): %v\n", userId, raffleId, err)
+		return nil, err
+	}
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	winner.ID = int(newID)
+	return &winner, nil
+}
+
+// UpdateRaffleWinnerStatus
+func (d *Database) UpdateRaffleWinnerStatus(id int, status string) error {
+	_, err := d.db.Exec(UPDATE_RAFFLE_WINNER_STATUS, status, time.Now(), id)
+	if err != nil {
+		log.Printf("Error updating raffle winner(%d) to %s: %v\n", id, status, err)
+	}
+	return err
+}
+
+// FindRaffleWinners
+// Returns everyone drawn from the raffle, in the order they were drawn
+func (d *Database) FindRaffleWinners(raffleId int) ([]RaffleWinner, error) {
+	rows, err := d.db.Query(FIND_RAFFLE_WINNERS, raffleId)
+	if err != nil {
+		log.Printf("Error finding winners of raffle(%d): %v\n", raffleId, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	winners := []RaffleWinner{}
+	for rows.Next() {
+		winners = append(winners, scanRaffleWinner(rows))
+	}
+	return winners, nil
+}
+
+func scanRaffleWinner(rows *sql.Rows) RaffleWinner {
+	var winner RaffleWinner
+	err := rows.Scan(&winner.ID, &winner.RaffleId, &winner.UserId, &winner.Status, &winner.DrawnAt, &winner.RespondedAt)
+	if err != nil {
+		log.Println("Error scanning raffle winner: ", err)
+	}
+	return winner
+}
+
+const INSERT_RAFFLE string = `
+INSERT INTO raffle (channelId, streamId, keyword, subOnly, followerOnly, minWatchMinutes, status, openedBy, openedAt, closesAt)
+VALUES (?,?,?,?,?,?,?,?,?,?)
+`
+
+const UPDATE_RAFFLE_STATUS string = `
+UPDATE raffle
+SET status=?, closedAt=CASE WHEN ?=? THEN NULL ELSE coalesce(closedAt, ?) END
+WHERE id=?
+`
+
+const FIND_ACTIVE_RAFFLES string = `
+SELECT id, channelId, streamId, keyword, subOnly, followerOnly, minWatchMinutes, status, openedBy, openedAt, closesAt, closedAt
+FROM raffle
+WHERE status IN (?,?)
+ORDER BY openedAt
+`
+
+const INSERT_RAFFLE_ENTRY string = `
+INSERT OR IGNORE INTO raffle_entry (raffleId, userId, tier, weight, enteredAt)
+VALUES (?,?,?,?,?)
+`
+
+const COUNT_RAFFLE_ENTRY string = `
+SELECT COUNT(*) FROM raffle_entry WHERE raffleId=? AND userId=?
+`
+
+const FIND_RAFFLE_ENTRIES string = `
+SELECT re.raffleId, u.id, u.username, u.displayName, re.tier, re.weight, re.enteredAt
+FROM raffle_entry re
+JOIN user u ON u.id = re.userId
+WHERE re.raffleId=?
+ORDER BY re.enteredAt, re.userId
+`
+
+const INSERT_RAFFLE_WINNER string = `
+INSERT INTO raffle_winner (raffleId, userId, status, drawnAt)
+VALUES (?,?,?,?)
+`
+
+const UPDATE_RAFFLE_WINNER_STATUS string = `
+UPDATE raffle_winner
+SET status=?, respondedAt=?
+WHERE id=?
+`
+
+const FIND_RAFFLE_WINNERS string = `
+SELECT id, raffleId, userId, status, drawnAt, respondedAt
+FROM raffle_winner
+WHERE raffleId=?
+ORDER BY drawnAt, id
+`
+
+const raffle_table string = `
+CREATE TABLE IF NOT EXISTS raffle (
+    id INTEGER PRIMARY KEY,
+    channelId INTEGER NOT NULL,
+    streamId INTEGER,
+    keyword TEXT NOT NULL,
+    subOnly BOOLEAN NOT NULL DEFAULT 0,
+    followerOnly BOOLEAN NOT NULL DEFAULT 0,
+    minWatchMinutes INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    openedBy INTEGER NOT NULL,
+    openedAt DATETIME NOT NULL,
+    closesAt DATETIME,
+    closedAt DATETIME,
+    FOREIGN KEY (channelId)
+    REFERENCES user (id),
+    FOREIGN KEY (streamId)
+    REFERENCES stream (id),
+    FOREIGN KEY (openedBy)
+    REFERENCES user (id)
+    )`
+
+const raffle_entry_table string = `
+CREATE TABLE IF NOT EXISTS raffle_entry (
+    raffleId INTEGER NOT NULL,
+    userId INTEGER NOT NULL,
+    tier INTEGER NOT NULL DEFAULT 0,
+    weight INTEGER NOT NULL DEFAULT 1,
+    enteredAt DATETIME NOT NULL,
+    PRIMARY KEY (raffleId, userId),
+    FOREIGN KEY (raffleId)
+    REFERENCES raffle (id),
+    FOREIGN KEY (userId)
+    REFERENCES user (id)
+    )`
+
+const raffle_winner_table string = `
+CREATE TABLE IF NOT EXISTS raffle_winner (
+    id INTEGER PRIMARY KEY,
+    raffleId INTEGER NOT NULL,
+    userId INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    drawnAt DATETIME NOT NULL,
+    respondedAt DATETIME,
+    FOREIGN KEY (raffleId)
+    REFERENCES raffle (id),
+    FOREIGN KEY (userId)
+    REFERENCES user (id)
+    )`
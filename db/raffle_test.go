@@ -0,0 +1,55 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRaffle(t *testing.T) {
+	d := newTestDatabase(t)
+	raffle, err := d.InsertRaffle(Raffle{ChannelId: testChannelId, Keyword: "pizza", SubOnly: true, OpenedBy: testChannelId})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, RAFFLE_OPEN, raffle.Status)
+
+	assert.False(t, d.HasRaffleEntry(raffle.ID, testViewerId))
+	entered, err := d.InsertRaffleEntry(raffle.ID, testViewerId, 2, 3)
+	assert.NoError(t, err)
+	assert.True(t, entered)
+	assert.True(t, d.HasRaffleEntry(raffle.ID, testViewerId))
+	entered, err = d.InsertRaffleEntry(raffle.ID, testViewerId, 2, 3)
+	assert.NoError(t, err)
+	assert.False(t, entered, "viewers can only enter once")
+
+	entries, err := d.FindRaffleEntries(raffle.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "kinda_cringe_dev", entries[0].User.Username)
+		assert.Equal(t, 3, entries[0].Weight)
+	}
+
+	assert.NoError(t, d.UpdateRaffleStatus(raffle.ID, RAFFLE_CLOSED))
+	active, err := d.FindActiveRaffles()
+	assert.NoError(t, err)
+	if assert.Len(t, active, 1) {
+		assert.Equal(t, RAFFLE_CLOSED, active[0].Status)
+		assert.True(t, active[0].SubOnly)
+		assert.NotNil(t, active[0].ClosedAt)
+	}
+
+	winner, err := d.InsertRaffleWinner(raffle.ID, testViewerId)
+	assert.NoError(t, err)
+	assert.NoError(t, d.UpdateRaffleWinnerStatus(winner.ID, RAFFLE_WINNER_CLAIMED))
+	assert.NoError(t, d.UpdateRaffleStatus(raffle.ID, RAFFLE_DRAWN))
+
+	winners, err := d.FindRaffleWinners(raffle.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, winners, 1) {
+		assert.Equal(t, RAFFLE_WINNER_CLAIMED, winners[0].Status)
+		assert.NotNil(t, winners[0].RespondedAt)
+	}
+	active, _ = d.FindActiveRaffles()
+	assert.Empty(t, active)
+}
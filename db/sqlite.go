@@ -8,7 +8,8 @@ import (
 const enable_foreign_keys string = `PRAGMA foreign_keys = ON`
 
 type Database struct {
-	db *sql.DB
+	db         *sql.DB
+	ftsEnabled bool
 }
 
 // InitDatabase
@@ -43,6 +44,86 @@ func InitDatabase() *Database {
 		log.Println("create exclusion_table failed: ", err)
 	}
 
+	if _, err := prepareAndExec(database, question_skip_table); err != nil {
+		log.Println("create question_skip_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, question_tag_table); err != nil {
+		log.Println("create question_tag_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, question_schedule_table); err != nil {
+		log.Println("create question_schedule_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, qotd_day_table); err != nil {
+		log.Println("create qotd_day_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, trivia_question_table); err != nil {
+		log.Println("create trivia_question_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, trivia_answer_table); err != nil {
+		log.Println("create trivia_answer_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, trivia_score_table); err != nil {
+		log.Println("create trivia_score_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, dice_config_table); err != nil {
+		log.Println("create dice_config_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, prediction_table); err != nil {
+		log.Println("create prediction_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, dice_game_table); err != nil {
+		log.Println("create dice_game_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, points_ledger_table); err != nil {
+		log.Println("create points_ledger_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, points_ledger_index); err != nil {
+		log.Println("create points_ledger index failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, watch_time_table); err != nil {
+		log.Println("create watch_time_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, bot_admin_table); err != nil {
+		log.Println("create bot_admin_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, raffle_table); err != nil {
+		log.Println("create raffle_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, raffle_entry_table); err != nil {
+		log.Println("create raffle_entry_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, raffle_winner_table); err != nil {
+		log.Println("create raffle_winner_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, poll_table); err != nil {
+		log.Println("create poll_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, timed_message_table); err != nil {
+		log.Println("create timed_message_table failed: ", err)
+	}
+
+	if _, err := prepareAndExec(database, quote_table); err != nil {
+		log.Println("create quote_table failed: ", err)
+	}
+
 	migrateExistingStreamUsers(database)
 
 	seedQuestionData(database)
@@ -51,6 +132,16 @@ func InitDatabase() *Database {
 	seedExclusionList(database)
 	addAuthToStreamConfig(database)
 	migrateUserApiKeys(database)
+	addQotdAutoPostColumns(database)
+	addQotdSkipVoteShareColumn(database)
+	addStreamQotdAskedAtColumn(database)
+	addQotdDailyColumns(database)
+	addPredictionSeedColumns(database)
+	addPointsLedgerAuditColumns(database)
+	addThanosColumns(database)
+	addRaidEnabledColumn(database)
+
+	db.ftsEnabled = initQuestionSearch(database)
 
 	return db
 }
@@ -246,6 +337,142 @@ func addAuthToStreamConfig(db *sql.DB) {
 	}
 }
 
+// Migration Script for adding qotd auto post settings to stream_config table
+func addQotdAutoPostColumns(db *sql.DB) {
+	if hasColumn(db, "stream_config", "qotdAutoDelay") {
+		return
+	}
+	addAutoDelayColumn := `ALTER TABLE stream_config ADD COLUMN qotdAutoDelay INTEGER DEFAULT 0`
+	addRepeatMinutesColumn := `ALTER TABLE stream_config ADD COLUMN qotdRepeatMinutes INTEGER DEFAULT 0`
+	addRepeatMessagesColumn := `ALTER TABLE stream_config ADD COLUMN qotdRepeatMessages INTEGER DEFAULT 0`
+
+	if _, err := prepareAndExec(db, addAutoDelayColumn); err != nil {
+		log.Println("stream_config.qotdAutoDelay column script failed: ", err)
+	}
+	if _, err := prepareAndExec(db, addRepeatMinutesColumn); err != nil {
+		log.Println("stream_config.qotdRepeatMinutes column script failed: ", err)
+	}
+	if _, err := prepareAndExec(db, addRepeatMessagesColumn); err != nil {
+		log.Println("stream_config.qotdRepeatMessages column script failed: ", err)
+	}
+}
+
+// Migration Script for adding the skip vote share to stream_config table
+func addQotdSkipVoteShareColumn(db *sql.DB) {
+	if hasColumn(db, "stream_config", "qotdSkipVoteShare") {
+		return
+	}
+	addSkipVoteShareColumn := `ALTER TABLE stream_config ADD COLUMN qotdSkipVoteShare INTEGER DEFAULT 50`
+	if _, err := prepareAndExec(db, addSkipVoteShareColumn); err != nil {
+		log.Println("stream_config.qotdSkipVoteShare column script failed: ", err)
+	}
+}
+
+// Migration Script for recording when the qotd was asked on a stream
+func addStreamQotdAskedAtColumn(db *sql.DB) {
+	if hasColumn(db, "stream", "qotdAskedAt") {
+		return
+	}
+	addAskedAtColumn := `ALTER TABLE stream ADD COLUMN qotdAskedAt DATETIME`
+	if _, err := prepareAndExec(db, addAskedAtColumn); err != nil {
+		log.Println("stream.qotdAskedAt column script failed: ", err)
+	}
+}
+
+// Migration Script for sharing the qotd across streams on the same day
+func addQotdDailyColumns(db *sql.DB) {
+	if hasColumn(db, "stream_config", "qotdDaily") {
+		return
+	}
+	addDailyColumn := `ALTER TABLE stream_config ADD COLUMN qotdDaily BOOLEAN DEFAULT 0`
+	addTimezoneColumn := `ALTER TABLE stream_config ADD COLUMN qotdTimezone TEXT DEFAULT 'UTC'`
+	if _, err := prepareAndExec(db, addDailyColumn); err != nil {
+		log.Println("stream_config.qotdDaily column script failed: ", err)
+	}
+	if _, err := prepareAndExec(db, addTimezoneColumn); err != nil {
+		log.Println("stream_config.qotdTimezone column script failed: ", err)
+	}
+}
+
+// Migration Script for provably fair predictions, keeping the seed and dice each game was played with
+func addPredictionSeedColumns(db *sql.DB) {
+	if hasColumn(db, "prediction", "seed") {
+		return
+	}
+	columns := []string{
+		"seed TEXT",
+		"seedHash TEXT",
+		"diceCount INTEGER DEFAULT 0",
+		"sides INTEGER DEFAULT 0",
+		"evenTitle TEXT DEFAULT ''",
+		"oddTitle TEXT DEFAULT ''",
+		"rolls TEXT",
+	}
+	for _, column := range columns {
+		if _, err := prepareAndExec(db, "ALTER TABLE prediction ADD COLUMN "+column); err != nil {
+			log.Printf("prediction.%s column script failed: %v", column, err)
+		}
+	}
+}
+
+// Migration Script for recording the stream points were earned in, and who gave them
+func addPointsLedgerAuditColumns(db *sql.DB) {
+	if hasColumn(db, "points_ledger", "streamId") {
+		return
+	}
+	addStreamColumn := `ALTER TABLE points_ledger ADD COLUMN streamId INTEGER REFERENCES stream (id)`
+	addActorColumn := `ALTER TABLE points_ledger ADD COLUMN actorId INTEGER REFERENCES user (id)`
+	if _, err := prepareAndExec(db, addStreamColumn); err != nil {
+		log.Println("points_ledger.streamId column script failed: ", err)
+	}
+	if _, err := prepareAndExec(db, addActorColumn); err != nil {
+		log.Println("points_ledger.actorId column script failed: ", err)
+	}
+}
+
+// Migration Script for turning !thanos on per channel, with how long it times chatters out
+func addThanosColumns(db *sql.DB) {
+	if hasColumn(db, "stream_config", "thanosEnabled") {
+		return
+	}
+	addEnabledColumn := `ALTER TABLE stream_config ADD COLUMN thanosEnabled BOOLEAN DEFAULT 0`
+	addDurationColumn := `ALTER TABLE stream_config ADD COLUMN thanosDuration INTEGER DEFAULT 60`
+	if _, err := prepareAndExec(db, addEnabledColumn); err != nil {
+		log.Println("stream_config.thanosEnabled column script failed: ", err)
+	}
+	if _, err := prepareAndExec(db, addDurationColumn); err != nil {
+		log.Println("stream_config.thanosDuration column script failed: ", err)
+	}
+}
+
+// Migration Script for adding the !raid setting to stream_config table.
+// !raid used to only work in soulxburn's channel, so it stays on there.
+func addRaidEnabledColumn(db *sql.DB) {
+	if hasColumn(db, "stream_config", "raidEnabled") {
+		return
+	}
+	addColumn := `ALTER TABLE stream_config ADD COLUMN raidEnabled BOOLEAN DEFAULT 0`
+	if _, err := prepareAndExec(db, addColumn); err != nil {
+		log.Println("stream_config.raidEnabled column script failed: ", err)
+		return
+	}
+	enableSoulxburn := `UPDATE stream_config SET raidEnabled=1 WHERE userId IN (SELECT id FROM user WHERE username='soulxburn')`
+	if _, err := prepareAndExec(db, enableSoulxburn); err != nil {
+		log.Println("stream_config.raidEnabled migrate failed: ", err)
+	}
+}
+
+// Helper function to check if a column is present on a table
+func hasColumn(db *sql.DB, table string, column string) bool {
+	var count int
+	err := db.QueryRow(`SELECT count(*) FROM pragma_table_info(?) WHERE name=?`, table, column).Scan(&count)
+	if err != nil {
+		log.Printf("%s.%s column check failed: %v", table, column, err)
+		return false
+	}
+	return count > 0
+}
+
 // Helper function to prepare, exec and close a query
 func prepareAndExec(db *sql.DB, query string) (sql.Result, error) {
 	statement, err := db.Prepare(query)
@@ -14,6 +14,7 @@ type Stream struct {
 	StartedAt   time.Time
 	EndedAt     *time.Time
 	QOTDId      *int
+	QOTDAskedAt *time.Time
 	FirstUserId *int
 }
 
@@ -68,6 +69,7 @@ func scanRowIntoStream(stream *Stream, rows *sql.Rows) {
 		&stream.UserId,
 		&stream.FirstUserId,
 		&stream.QOTDId,
+		&stream.QOTDAskedAt,
 	)
 }
 
@@ -193,7 +195,13 @@ func (d *Database) UpdateStreamQuestion(streamId int, questionId *int) error {
 		return err
 	}
 
-	_, err = statement.Exec(questionId, streamId)
+	var askedAt *time.Time
+	if questionId != nil {
+		now := time.Now()
+		askedAt = &now
+	}
+
+	_, err = statement.Exec(questionId, askedAt, streamId)
 	if err != nil {
 		log.Printf("Error updating stream question for streamId(%d): %x\n", streamId, err)
 		return err
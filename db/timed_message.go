@@ -0,0 +1,177 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// When a timed message can be posted, based on whether the channel is live
+const (
+	TIMED_MESSAGE_ALWAYS  = "always"
+	TIMED_MESSAGE_ONLINE  = "online"
+	TIMED_MESSAGE_OFFLINE = "offline"
+)
+
+// Limits on timed messages, so the bot doesn't spam chat
+const (
+	TIMED_MESSAGE_MIN_INTERVAL = 5
+	TIMED_MESSAGE_MAX_INTERVAL = 24 * 60
+	TIMED_MESSAGE_MAX_LENGTH   = 500
+)
+
+// ValidateTimedMessage
+// Returns why the message can't be used as a timed message, or an empty string if it can
+func ValidateTimedMessage(message TimedMessage) string {
+	switch {
+	case strings.TrimSpace(message.Message) == "":
+		return "message is required"
+	case len(message.Message) > TIMED_MESSAGE_MAX_LENGTH:
+		return fmt.Sprintf("message must be at most %d characters", TIMED_MESSAGE_MAX_LENGTH)
+	case message.IntervalMinutes < TIMED_MESSAGE_MIN_INTERVAL || message.IntervalMinutes > TIMED_MESSAGE_MAX_INTERVAL:
+		return fmt.Sprintf("interval must be between %d and %d minutes", TIMED_MESSAGE_MIN_INTERVAL, TIMED_MESSAGE_MAX_INTERVAL)
+	case message.MinLines < 0:
+		return "minLines can't be negative"
+	case message.Mode != TIMED_MESSAGE_ALWAYS && message.Mode != TIMED_MESSAGE_ONLINE && message.Mode != TIMED_MESSAGE_OFFLINE:
+		return fmt.Sprintf("mode must be %s, %s or %s", TIMED_MESSAGE_ALWAYS, TIMED_MESSAGE_ONLINE, TIMED_MESSAGE_OFFLINE)
+	}
+	return ""
+}
+
+// TimedMessage
+// A message the bot posts in a channel every IntervalMinutes, once at least MinLines
+// have been said in chat since it was last posted
+type TimedMessage struct {
+	ID              int       `json:"id"`
+	ChannelId       int       `json:"channelId"`
+	Message         string    `json:"message"`
+	IntervalMinutes int       `json:"intervalMinutes"`
+	MinLines        int       `json:"minLines"`
+	Mode            string    `json:"mode"`
+	CreatedBy       *int      `json:"createdBy"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// InsertTimedMessage
+func (d *Database) InsertTimedMessage(message TimedMessage) (*TimedMessage, error) {
+	statement, err := d.db.Prepare(INSERT_TIMED_MESSAGE)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing insert timed message statement: ", err)
+		return nil, err
+	}
+
+	message.CreatedAt = time.Now()
+	result, err := statement.Exec(
+		message.ChannelId,
+		message.Message,
+		message.IntervalMinutes,
+		message.MinLines,
+		message.Mode,
+		message.CreatedBy,
+		message.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("Error inserting timed message for channel(%d): %v\n", message.ChannelId, err)
+		return nil, err
+	}
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	message.ID = int(newID)
+	return &message, nil
+}
+
+// DeleteTimedMessage
+// Deletes the channel's timed message, reporting false if the channel has no message with that id
+func (d *Database) DeleteTimedMessage(channelId int, id int) (bool, error) {
+	statement, err := d.db.Prepare(DELETE_TIMED_MESSAGE)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing delete timed message statement: ", err)
+		return false, err
+	}
+
+	result, err := statement.Exec(id, channelId)
+	if err != nil {
+		log.Printf("Error deleting timed message(%d) in channel(%d): %v\n", id, channelId, err)
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+// FindTimedMessages
+// Returns the channel's timed messages, in the order they were added
+func (d *Database) FindTimedMessages(channelId int) ([]TimedMessage, error) {
+	rows, err := d.db.Query(FIND_TIMED_MESSAGES, channelId)
+	if err != nil {
+		log.Printf("Error finding timed messages for channel(%d): %v\n", channelId, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	messages := []TimedMessage{}
+	for rows.Next() {
+		var message TimedMessage
+		err := rows.Scan(
+			&message.ID,
+			&message.ChannelId,
+			&message.Message,
+			&message.IntervalMinutes,
+			&message.MinLines,
+			&message.Mode,
+			&message.CreatedBy,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			log.Println("Error scanning timed message: ", err)
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+const INSERT_TIMED_MESSAGE string = `
+INSERT INTO timed_message (channelId, message, intervalMinutes, minLines, mode, createdBy, createdAt)
+VALUES (?,?,?,?,?,?,?)
+`
+
+const DELETE_TIMED_MESSAGE string = `
+DELETE FROM timed_message
+WHERE id=? AND channelId=?
+`
+
+const FIND_TIMED_MESSAGES string = `
+SELECT id, channelId, message, intervalMinutes, minLines, mode, createdBy, createdAt
+FROM timed_message
+WHERE channelId=?
+ORDER BY id
+`
+
+const timed_message_table string = `
+CREATE TABLE IF NOT EXISTS timed_message (
+    id INTEGER PRIMARY KEY,
+    channelId INTEGER NOT NULL,
+    message TEXT NOT NULL,
+    intervalMinutes INTEGER NOT NULL,
+    minLines INTEGER NOT NULL DEFAULT 0,
+    mode TEXT NOT NULL DEFAULT 'always',
+    createdBy INTEGER,
+    createdAt DATETIME NOT NULL,
+    FOREIGN KEY (channelId)
+    REFERENCES user (id),
+    FOREIGN KEY (createdBy)
+    REFERENCES user (id)
+    )`
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimedMessages(t *testing.T) {
+	d := newTestDatabase(t)
+	createdBy := testChannelId
+	message, err := d.InsertTimedMessage(TimedMessage{
+		ChannelId:       testChannelId,
+		Message:         "Follow the channel!",
+		IntervalMinutes: 15,
+		MinLines:        5,
+		Mode:            TIMED_MESSAGE_ONLINE,
+		CreatedBy:       &createdBy,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	messages, err := d.FindTimedMessages(testChannelId)
+	assert.NoError(t, err)
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, message.ID, messages[0].ID)
+		assert.Equal(t, "Follow the channel!", messages[0].Message)
+		assert.Equal(t, 15, messages[0].IntervalMinutes)
+		assert.Equal(t, TIMED_MESSAGE_ONLINE, messages[0].Mode)
+	}
+
+	deleted, err := d.DeleteTimedMessage(testViewerId, message.ID)
+	assert.NoError(t, err)
+	assert.False(t, deleted, "only the channel's own messages can be deleted")
+
+	deleted, err = d.DeleteTimedMessage(testChannelId, message.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	messages, err = d.FindTimedMessages(testChannelId)
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+}
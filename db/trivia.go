@@ -0,0 +1,341 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+)
+
+// Guesses at least this similar to an answer after normalizing are accepted
+const TRIVIA_ANSWER_THRESHOLD = 0.8
+
+// Answers this short, or made of numbers, have to match exactly
+const TRIVIA_EXACT_ANSWER_LENGTH = 4
+
+const (
+	TRIVIA_EASY   = "easy"
+	TRIVIA_MEDIUM = "medium"
+	TRIVIA_HARD   = "hard"
+)
+
+// Points awarded for a correct answer by difficulty
+var TriviaPoints = map[string]int{
+	TRIVIA_EASY:   1,
+	TRIVIA_MEDIUM: 2,
+	TRIVIA_HARD:   3,
+}
+
+type TriviaQuestion struct {
+	ID         int      `json:"id"`
+	Question   string   `json:"question"`
+	Answers    []string `json:"answers"`
+	Category   string   `json:"category"`
+	Difficulty string   `json:"difficulty"`
+}
+
+type TriviaImport struct {
+	Row        int
+	Question   string
+	Answers    []string
+	Category   string
+	Difficulty string
+}
+
+type TriviaImportResult struct {
+	Row      int    `json:"row"`
+	Status   string `json:"status"`
+	ID       *int   `json:"id,omitempty"`
+	Question string `json:"question"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type TriviaLeader struct {
+	User    User
+	Points  int
+	Correct int
+}
+
+// IsCorrect
+// Reports if the guess matches one of the accepted answers, allowing for small typos
+func (t TriviaQuestion) IsCorrect(guess string) bool {
+	guess = NormalizeText(guess)
+	if guess == "" {
+		return false
+	}
+	for _, answer := range t.Answers {
+		answer = NormalizeText(answer)
+		if guess == answer {
+			return true
+		}
+		if len([]rune(answer)) <= TRIVIA_EXACT_ANSWER_LENGTH || strings.IndexFunc(answer, isDigit) >= 0 {
+			continue
+		}
+		if Similarity(guess, answer) >= TRIVIA_ANSWER_THRESHOLD {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// ImportTrivia
+// Creates all valid, non-duplicate trivia questions in a single transaction.
+// Returns a result for every row, or an error if the transaction was rolled back.
+func (d *Database) ImportTrivia(imports []TriviaImport) ([]TriviaImportResult, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println("Error starting trivia import transaction: ", err)
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	results := make([]TriviaImportResult, 0, len(imports))
+	for _, imp := range imports {
+		result := TriviaImportResult{Row: imp.Row, Question: strings.TrimSpace(imp.Question)}
+		answers := normalizeTriviaAnswers(imp.Answers)
+		difficulty := strings.ToLower(strings.TrimSpace(imp.Difficulty))
+		if difficulty == "" {
+			difficulty = TRIVIA_MEDIUM
+		}
+
+		if reason := validateTrivia(result.Question, answers, difficulty); reason != "" {
+			result.Status = IMPORT_INVALID
+			result.Reason = reason
+			results = append(results, result)
+			continue
+		}
+
+		var existingID int
+		err := tx.QueryRow(FIND_TRIVIA_ID_BY_QUESTION, result.Question).Scan(&existingID)
+		if err == nil {
+			result.Status = IMPORT_DUPLICATE
+			result.ID = &existingID
+			result.Reason = "question already exists"
+			results = append(results, result)
+			continue
+		} else if err != sql.ErrNoRows {
+			log.Printf("Error checking for duplicate trivia on row %d: %v\n", imp.Row, err)
+			return nil, err
+		}
+
+		category := strings.ToLower(strings.TrimSpace(imp.Category))
+		inserted, err := tx.Exec(INSERT_TRIVIA_QUESTION, result.Question, category, difficulty)
+		if err != nil {
+			log.Printf("Error inserting trivia on row %d: %v\n", imp.Row, err)
+			return nil, err
+		}
+		newID, err := inserted.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		id := int(newID)
+
+		for _, answer := range answers {
+			if _, err := tx.Exec(INSERT_TRIVIA_ANSWER, id, answer); err != nil {
+				log.Printf("Error inserting trivia answer on row %d: %v\n", imp.Row, err)
+				return nil, err
+			}
+		}
+
+		result.Status = IMPORT_CREATED
+		result.ID = &id
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing trivia import: ", err)
+		return nil, err
+	}
+	return results, nil
+}
+
+func validateTrivia(question string, answers []string, difficulty string) string {
+	if question == "" {
+		return "question text is empty"
+	}
+	if len(question) > MAX_QUESTION_LENGTH {
+		return "question text is longer than 500 characters"
+	}
+	if len(answers) == 0 {
+		return "at least one answer is required"
+	}
+	if _, ok := TriviaPoints[difficulty]; !ok {
+		return "difficulty must be easy, medium or hard"
+	}
+	return ""
+}
+
+// normalizeTriviaAnswers
+// Trims answers and drops empty ones, or ones that are the same after normalizing
+func normalizeTriviaAnswers(answers []string) []string {
+	seen := make(map[string]bool)
+	normalized := []string{}
+	for _, answer := range answers {
+		answer = strings.TrimSpace(answer)
+		key := NormalizeText(answer)
+		if key != "" && !seen[key] {
+			seen[key] = true
+			normalized = append(normalized, answer)
+		}
+	}
+	return normalized
+}
+
+// FindRandomTrivia
+// Finds a random trivia question, optionally in a category, that isn't one of the excluded ids
+func (d *Database) FindRandomTrivia(category string, exclude []int) (*TriviaQuestion, bool) {
+	query := FIND_RANDOM_TRIVIA
+	args := []interface{}{category, category}
+	if len(exclude) > 0 {
+		query += " AND id NOT IN (?" + strings.Repeat(",?", len(exclude)-1) + ")"
+		for _, id := range exclude {
+			args = append(args, id)
+		}
+	}
+	query += " ORDER BY random() LIMIT 1"
+
+	var trivia TriviaQuestion
+	err := d.db.QueryRow(query, args...).Scan(&trivia.ID, &trivia.Question, &trivia.Category, &trivia.Difficulty)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Println("Error finding random trivia: ", err)
+		}
+		return nil, false
+	}
+
+	rows, err := d.db.Query(FIND_TRIVIA_ANSWERS, trivia.ID)
+	if err != nil {
+		log.Println("Error finding trivia answers: ", err)
+		return nil, false
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var answer string
+		rows.Scan(&answer)
+		trivia.Answers = append(trivia.Answers, answer)
+	}
+
+	return &trivia, true
+}
+
+// AddTriviaScore
+// Adds points and a correct answer to the user's trivia score on the channel
+func (d *Database) AddTriviaScore(streamUserId int, userId int, points int) error {
+	statement, err := d.db.Prepare(UPSERT_TRIVIA_SCORE)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing trivia score statement: ", err)
+		return err
+	}
+
+	if _, err := statement.Exec(streamUserId, userId, points); err != nil {
+		log.Printf("Error adding trivia score for userId(%d): %v\n", userId, err)
+		return err
+	}
+	return nil
+}
+
+// FindTriviaLeaders
+// Returns the users with the most trivia points on the channel
+func (d *Database) FindTriviaLeaders(streamUserId int, count int) ([]TriviaLeader, error) {
+	rows, err := d.db.Query(FIND_TRIVIA_LEADERS, streamUserId, count)
+	if err != nil {
+		log.Println("Error finding trivia leaders: ", err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []TriviaLeader
+	for rows.Next() {
+		var leader TriviaLeader
+		rows.Scan(&leader.User.ID, &leader.User.Username, &leader.User.DisplayName, &leader.Points, &leader.Correct)
+		results = append(results, leader)
+	}
+
+	return results, nil
+}
+
+const FIND_TRIVIA_ID_BY_QUESTION string = `
+SELECT id
+FROM trivia_question
+WHERE question=?
+`
+
+const INSERT_TRIVIA_QUESTION string = `
+INSERT INTO trivia_question (question, category, difficulty)
+VALUES (?,?,?)
+`
+
+const INSERT_TRIVIA_ANSWER string = `
+INSERT INTO trivia_answer (triviaId, answer)
+VALUES (?,?)
+`
+
+// An empty category matches every question
+const FIND_RANDOM_TRIVIA string = `
+SELECT id, question, category, difficulty
+FROM trivia_question
+WHERE (?='' OR category=?)`
+
+const FIND_TRIVIA_ANSWERS string = `
+SELECT answer
+FROM trivia_answer
+WHERE triviaId=?
+ORDER BY rowid
+`
+
+const UPSERT_TRIVIA_SCORE string = `
+INSERT INTO trivia_score (streamUserId, userId, points, correct)
+VALUES (?,?,?,1)
+ON CONFLICT (streamUserId, userId) DO UPDATE SET points=points+excluded.points, correct=correct+1
+`
+
+const FIND_TRIVIA_LEADERS string = `
+SELECT u.id, u.username, u.displayName, ts.points, ts.correct
+FROM trivia_score ts
+JOIN user u ON u.id=ts.userId
+WHERE ts.streamUserId=?
+ORDER BY ts.points DESC, ts.correct DESC
+LIMIT ?
+`
+
+const trivia_question_table string = `
+CREATE TABLE IF NOT EXISTS trivia_question (
+    id INTEGER PRIMARY KEY,
+    question TEXT UNIQUE NOT NULL,
+    category TEXT NOT NULL DEFAULT '',
+    difficulty TEXT NOT NULL DEFAULT 'medium'
+    )`
+
+const trivia_answer_table string = `
+CREATE TABLE IF NOT EXISTS trivia_answer (
+    triviaId INTEGER NOT NULL,
+    answer TEXT NOT NULL,
+    PRIMARY KEY (triviaId, answer),
+    FOREIGN KEY (triviaId)
+    REFERENCES trivia_question (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    )`
+
+const trivia_score_table string = `
+CREATE TABLE IF NOT EXISTS trivia_score (
+    streamUserId INTEGER NOT NULL,
+    userId INTEGER NOT NULL,
+    points INTEGER NOT NULL DEFAULT 0,
+    correct INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (streamUserId, userId),
+    FOREIGN KEY (streamUserId)
+    REFERENCES user (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    FOREIGN KEY (userId)
+    REFERENCES user (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+    )`
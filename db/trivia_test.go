@@ -0,0 +1,22 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriviaIsCorrect(t *testing.T) {
+	trivia := TriviaQuestion{Answers: []string{"Leonardo da Vinci", "da Vinci", "1969", "Ra"}}
+
+	assert.True(t, trivia.IsCorrect("leonardo da vinci!"))
+	assert.True(t, trivia.IsCorrect("Leonardo Da Vinchi"))
+	assert.True(t, trivia.IsCorrect("davinci"))
+	assert.True(t, trivia.IsCorrect("1969"))
+	assert.True(t, trivia.IsCorrect("ra"))
+
+	assert.False(t, trivia.IsCorrect("1968"))
+	assert.False(t, trivia.IsCorrect("re"))
+	assert.False(t, trivia.IsCorrect("michelangelo"))
+	assert.False(t, trivia.IsCorrect(""))
+}
@@ -25,6 +25,18 @@ type StreamConfig struct {
 	APIKey             string
 	TwitchAuthToken    *EncryptedToken
 	TwitchRefreshToken *EncryptedToken
+	QotdAutoDelay      int
+	QotdRepeatMinutes  int
+	QotdRepeatMessages int
+	QotdSkipVoteShare  int
+	// Every stream on the same local day shares one qotd
+	QotdDaily    bool
+	QotdTimezone string
+	// !thanos times out half of chat for ThanosDuration seconds, once the broadcaster turns it on
+	ThanosEnabled  bool
+	ThanosDuration int
+	// !raid spams the channel's raid emotes, only in channels that turn it on
+	RaidEnabled bool
 }
 
 type StreamUser struct {
@@ -200,6 +212,8 @@ func (d *Database) CreateStreamConfig(userId int, apiKey string, authToken Encry
 		APIKey:             apiKey,
 		TwitchAuthToken:    &authToken,
 		TwitchRefreshToken: &refreshToken,
+		QotdSkipVoteShare:  50,
+		QotdTimezone:       "UTC",
 	}
 
 	result, err := statement.Exec(
@@ -293,6 +307,15 @@ func scanStreamUser(rows *sql.Rows) StreamUser {
 		&config.APIKey,
 		&config.TwitchAuthToken,
 		&config.TwitchRefreshToken,
+		&config.QotdAutoDelay,
+		&config.QotdRepeatMinutes,
+		&config.QotdRepeatMessages,
+		&config.QotdSkipVoteShare,
+		&config.QotdDaily,
+		&config.QotdTimezone,
+		&config.ThanosEnabled,
+		&config.ThanosDuration,
+		&config.RaidEnabled,
 	)
 	return StreamUser{user, config}
 }
@@ -315,6 +338,91 @@ func (d *Database) ResetFirstEpoch(userId int) error {
 	return nil
 }
 
+// UpdateQotdAutoPost
+// Sets the minutes after going live to post the qotd, and how often to repeat it.
+// A value of zero disables that option.
+func (d *Database) UpdateQotdAutoPost(userId int, delay int, repeatMinutes int, repeatMessages int) error {
+	statement, err := d.db.Prepare(UPDATE_QOTD_AUTO_POST)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing update qotd auto post statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(delay, repeatMinutes, repeatMessages, userId)
+	if err != nil {
+		log.Printf("Error updating qotd auto post for userId(%d): %v\n", userId, err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateQotdSkipVoteShare
+// Sets the percent of active chatters needed to skip the qotd with !skipvote
+func (d *Database) UpdateQotdSkipVoteShare(userId int, share int) error {
+	statement, err := d.db.Prepare(UPDATE_QOTD_SKIP_VOTE_SHARE)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing update qotd skip vote share statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(share, userId)
+	if err != nil {
+		log.Printf("Error updating qotd skip vote share for userId(%d): %v\n", userId, err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateThanos
+// Turns !thanos on or off for the channel, and sets how many seconds it times chatters out for
+func (d *Database) UpdateThanos(userId int, enabled bool, duration int) error {
+	statement, err := d.db.Prepare(UPDATE_THANOS)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing update thanos statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(enabled, duration, userId)
+	if err != nil {
+		log.Printf("Error updating thanos for userId(%d): %v\n", userId, err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateRaidEnabled
+// Turns !raid on or off for the channel
+func (d *Database) UpdateRaidEnabled(userId int, enabled bool) error {
+	statement, err := d.db.Prepare(UPDATE_RAID_ENABLED)
+	if statement != nil {
+		defer func() { _ = statement.Close() }()
+	}
+	if err != nil {
+		log.Println("Error preparing update raid enabled statement: ", err)
+		return err
+	}
+
+	_, err = statement.Exec(enabled, userId)
+	if err != nil {
+		log.Printf("Error updating raid enabled for userId(%d): %v\n", userId, err)
+		return err
+	}
+
+	return nil
+}
+
 const user_table string = `
 CREATE TABLE IF NOT EXISTS user (
     id INTEGER PRIMARY KEY,
@@ -0,0 +1,198 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// StreamWatchTime is how long a viewer watched one stream
+type StreamWatchTime struct {
+	StreamId    int       `json:"streamId"`
+	ChannelId   int       `json:"channelId"`
+	UserId      int       `json:"userId"`
+	Seconds     int       `json:"seconds"`
+	StartedAt   time.Time `json:"startedAt"`
+	FirstSeenAt time.Time `json:"firstSeenAt"`
+	LastSeenAt  time.Time `json:"lastSeenAt"`
+}
+
+// WatchTimeLeader is a viewer and how long they've watched a channel
+type WatchTimeLeader struct {
+	User    User
+	Seconds int
+}
+
+// AddWatchTime
+// Records that the viewers were in chat during the stream at seenAt. Each viewer is credited with the
+// time since they were last seen, unless that was longer than maxGap ago, so time the viewer was away
+// or the bot wasn't watching chat is never counted.
+func (d *Database) AddWatchTime(streamId int, userIds []int, seenAt time.Time, maxGap time.Duration) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println("Error starting watch time transaction: ", err)
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, userId := range userIds {
+		var lastSeenAt time.Time
+		err := tx.QueryRow(FIND_WATCH_TIME_LAST_SEEN, streamId, userId).Scan(&lastSeenAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			if _, err := tx.Exec(INSERT_WATCH_TIME, streamId, userId, seenAt, seenAt); err != nil {
+				log.Printf("Error inserting watch time for userId(%d) in stream(%d): %v\n", userId, streamId, err)
+				return err
+			}
+			continue
+		} else if err != nil {
+			log.Printf("Error finding watch time for userId(%d) in stream(%d): %v\n", userId, streamId, err)
+			return err
+		}
+
+		var seconds int
+		if gap := seenAt.Sub(lastSeenAt); gap > 0 && gap <= maxGap {
+			seconds = int(gap.Seconds())
+		}
+		if _, err := tx.Exec(UPDATE_WATCH_TIME, seconds, seenAt, streamId, userId); err != nil {
+			log.Printf("Error updating watch time for userId(%d) in stream(%d): %v\n", userId, streamId, err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// FindUserWatchTime
+// Returns how long the viewer watched each stream, newest first. A nil channelId includes every channel.
+func (d *Database) FindUserWatchTime(userId int, channelId *int) ([]StreamWatchTime, error) {
+	rows, err := d.db.Query(FIND_USER_WATCH_TIME, userId, channelId, channelId)
+	if err != nil {
+		log.Printf("Error finding watch time for userId(%d): %v\n", userId, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	streams := []StreamWatchTime{}
+	for rows.Next() {
+		var stream StreamWatchTime
+		err := rows.Scan(
+			&stream.StreamId,
+			&stream.ChannelId,
+			&stream.UserId,
+			&stream.Seconds,
+			&stream.StartedAt,
+			&stream.FirstSeenAt,
+			&stream.LastSeenAt,
+		)
+		if err != nil {
+			log.Println("Error scanning watch time: ", err)
+			continue
+		}
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
+
+// FindWatchTimeLeaders
+// Returns the viewers who have watched the channel the longest
+func (d *Database) FindWatchTimeLeaders(channelId int, limit int) ([]WatchTimeLeader, error) {
+	rows, err := d.db.Query(FIND_WATCH_TIME_LEADERS, channelId, limit)
+	if err != nil {
+		log.Printf("Error finding watch time leaders in channel(%d): %v\n", channelId, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	leaders := []WatchTimeLeader{}
+	for rows.Next() {
+		var leader WatchTimeLeader
+		if err := rows.Scan(&leader.User.ID, &leader.User.Username, &leader.User.DisplayName, &leader.Seconds); err != nil {
+			log.Println("Error scanning watch time leader: ", err)
+			continue
+		}
+		leaders = append(leaders, leader)
+	}
+	return leaders, nil
+}
+
+// FindStreamViewerIDs
+// Returns the viewers seen in chat during the stream since the time
+func (d *Database) FindStreamViewerIDs(streamId int, since time.Time) ([]int, error) {
+	rows, err := d.db.Query(FIND_STREAM_VIEWER_IDS, streamId, since)
+	if err != nil {
+		log.Printf("Error finding viewers of stream(%d): %v\n", streamId, err)
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			log.Println("Error scanning stream viewer: ", err)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+const FIND_WATCH_TIME_LAST_SEEN string = `
+SELECT lastSeenAt
+FROM watch_time
+WHERE streamId=? AND userId=?
+`
+
+const FIND_STREAM_VIEWER_IDS string = `
+SELECT userId
+FROM watch_time
+WHERE streamId=? AND lastSeenAt>=?
+ORDER BY userId
+`
+
+const INSERT_WATCH_TIME string = `
+INSERT INTO watch_time (streamId, userId, seconds, firstSeenAt, lastSeenAt)
+VALUES (?,?,0,?,?)
+`
+
+const UPDATE_WATCH_TIME string = `
+UPDATE watch_time
+SET seconds=seconds+?, lastSeenAt=?
+WHERE streamId=? AND userId=?
+`
+
+const FIND_USER_WATCH_TIME string = `
+SELECT wt.streamId, s.userId, wt.userId, wt.seconds, s.startedAt, wt.firstSeenAt, wt.lastSeenAt
+FROM watch_time wt
+JOIN stream s ON s.id = wt.streamId
+WHERE wt.userId=?
+AND (? IS NULL OR s.userId=?)
+ORDER BY s.startedAt DESC, wt.streamId DESC
+`
+
+const FIND_WATCH_TIME_LEADERS string = `
+SELECT u.id, u.username, u.displayName, sum(wt.seconds) AS seconds
+FROM watch_time wt
+JOIN stream s ON s.id = wt.streamId
+JOIN user u ON u.id = wt.userId
+WHERE s.userId=?
+GROUP BY u.id
+HAVING seconds > 0
+ORDER BY seconds DESC, u.username
+LIMIT ?
+`
+
+const watch_time_table string = `
+CREATE TABLE IF NOT EXISTS watch_time (
+    id INTEGER PRIMARY KEY,
+    streamId INTEGER NOT NULL,
+    userId INTEGER NOT NULL,
+    seconds INTEGER NOT NULL DEFAULT 0,
+    firstSeenAt DATETIME NOT NULL,
+    lastSeenAt DATETIME NOT NULL,
+    UNIQUE (streamId, userId),
+    FOREIGN KEY (streamId)
+    REFERENCES stream (id),
+    FOREIGN KEY (userId)
+    REFERENCES user (id)
+    )`
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddWatchTime(t *testing.T) {
+	d := newTestDatabase(t)
+	start := time.Now().Add(-time.Hour)
+	stream, err := d.InsertStream(testChannelId, start)
+	if !assert.NoError(t, err) {
+		return
+	}
+	maxGap := 4 * time.Minute
+	viewers := []int{testViewerId}
+
+	// The first sample only marks the viewer as seen
+	assert.NoError(t, d.AddWatchTime(stream.ID, viewers, start, maxGap))
+	assert.NoError(t, d.AddWatchTime(stream.ID, viewers, start.Add(2*time.Minute), maxGap))
+	assert.NoError(t, d.AddWatchTime(stream.ID, viewers, start.Add(4*time.Minute), maxGap))
+	// The bot was down for 30 minutes, so the gap isn't counted
+	assert.NoError(t, d.AddWatchTime(stream.ID, viewers, start.Add(34*time.Minute), maxGap))
+	assert.NoError(t, d.AddWatchTime(stream.ID, viewers, start.Add(36*time.Minute), maxGap))
+
+	streams, err := d.FindUserWatchTime(testViewerId, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, streams, 1) {
+		assert.Equal(t, stream.ID, streams[0].StreamId)
+		assert.Equal(t, testChannelId, streams[0].ChannelId)
+		assert.Equal(t, 6*60, streams[0].Seconds)
+	}
+
+	otherChannel := testViewerId
+	streams, err = d.FindUserWatchTime(testViewerId, &otherChannel)
+	assert.NoError(t, err)
+	assert.Empty(t, streams)
+
+	leaders, err := d.FindWatchTimeLeaders(testChannelId, 5)
+	assert.NoError(t, err)
+	if assert.Len(t, leaders, 1) {
+		assert.Equal(t, testViewerId, leaders[0].User.ID)
+		assert.Equal(t, 6*60, leaders[0].Seconds)
+	}
+
+	seen, err := d.FindStreamViewerIDs(stream.ID, start.Add(35*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{testViewerId}, seen)
+	seen, err = d.FindStreamViewerIDs(stream.ID, start.Add(37*time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, seen)
+}
@@ -0,0 +1,287 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/soulxburn/soulxbot/twitch"
+)
+
+// Chat predictions are kept with the Twitch predictions, with IDs starting with the prefix
+const CHAT_PREDICTION_PREFIX = "chat-"
+
+// Number of winners announced when chat bets are paid out
+const CHAT_BET_TOP_WINNERS = 3
+
+var ErrBetsClosed = errors.New("No bets are open")
+var ErrUnknownOutcome = errors.New("Unknown outcome")
+var ErrBetOtherOutcome = errors.New("Bets can only be added to the same outcome")
+var ErrInvalidBet = errors.New("Bets must be a number of points, or all")
+
+// ChatBet is a viewer's bet on an outcome of a chat prediction
+type ChatBet struct {
+	UserId  int
+	Name    string
+	Outcome string
+	Amount  int
+	Payout  int
+}
+
+// chatBets
+// Bets on a prediction run in chat with bot points, for channels that can't create Twitch predictions
+type chatBets struct {
+	record   *db.Prediction
+	outcomes []string
+	closesAt time.Time
+	bets     map[int]*ChatBet
+}
+
+// IsChatPrediction
+// Reports if the prediction was bet on in chat rather than on Twitch
+func IsChatPrediction(record db.Prediction) bool {
+	return strings.HasPrefix(record.TwitchID, CHAT_PREDICTION_PREFIX)
+}
+
+// startChatBets
+// Opens chat betting on the game, and plays it once betting closes
+func (dg *DiceGame) startChatBets(user db.StreamUser, config db.DiceConfig, mode string, game PredictionGame, seed string) error {
+	id, err := NewServerSeed()
+	if err != nil {
+		return err
+	}
+
+	closesAt := time.Now().Add(time.Duration(config.PredictionWindow) * time.Second)
+	rollAt := closesAt.Add(ROLL_AFTER_WINDOW_SECONDS * time.Second)
+	record, err := dg.insertRecord(user, CHAT_PREDICTION_PREFIX+id[:16], twitch.PREDICTION_ACTIVE, config, mode, seed, rollAt)
+	if err != nil {
+		return err
+	}
+
+	bets := &chatBets{
+		record:   record,
+		outcomes: game.Outcomes(),
+		closesAt: closesAt,
+		bets:     make(map[int]*ChatBet),
+	}
+	dg.mu.Lock()
+	dg.bets = bets
+	dg.mu.Unlock()
+
+	dg.ircClient.Say(dg.channel, fmt.Sprintf("%s Bet your points with !bet <outcome> <points> in the next %d seconds. Outcomes: %s",
+		game.Title(), config.PredictionWindow, strings.Join(game.Outcomes(), ", ")))
+	dg.announceSeedHash(record)
+
+	go func() {
+		time.Sleep(time.Until(rollAt))
+		dg.resolveChatBets(user, bets, mode, game)
+	}()
+	return nil
+}
+
+// PlaceBet
+// Bets the viewer's points on an outcome of the open chat prediction. Amount is a number of points,
+// or all of them. Viewers can add to their bet, but only on the outcome they picked first.
+func (dg *DiceGame) PlaceBet(user db.StreamUser, bettor db.User, outcome string, amount string) (*ChatBet, error) {
+	dg.mu.Lock()
+	defer dg.mu.Unlock()
+
+	bets := dg.bets
+	if bets == nil || !time.Now().Before(bets.closesAt) {
+		return nil, ErrBetsClosed
+	}
+	title, ok := matchOutcome(bets.outcomes, outcome)
+	if !ok {
+		return nil, ErrUnknownOutcome
+	}
+	bet, ok := bets.bets[bettor.ID]
+	if ok && bet.Outcome != title {
+		return nil, ErrBetOtherOutcome
+	}
+
+	var points int
+	if strings.EqualFold(amount, "all") {
+		balance, err := dg.dataStore.FindPointsBalance(user.UserId, bettor.ID)
+		if err != nil {
+			return nil, err
+		}
+		if balance < 1 {
+			return nil, db.ErrInsufficientPoints
+		}
+		points = balance
+	} else {
+		parsed, err := strconv.Atoi(amount)
+		if err != nil || parsed < 1 {
+			return nil, ErrInvalidBet
+		}
+		points = parsed
+	}
+
+	if err := dg.dataStore.SpendPoints(db.PointsEntry{
+		ChannelId:    user.UserId,
+		UserId:       bettor.ID,
+		Amount:       points,
+		Reason:       db.POINTS_REASON_BET,
+		PredictionId: &bets.record.ID,
+	}); err != nil {
+		return nil, err
+	}
+	if !ok {
+		bet = &ChatBet{UserId: bettor.ID, Name: bettor.DisplayName, Outcome: title}
+		bets.bets[bettor.ID] = bet
+	}
+	bet.Amount += points
+	return bet, nil
+}
+
+// matchOutcome
+// Finds the outcome by its title, the start of its title, or its number
+func matchOutcome(outcomes []string, input string) (string, bool) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "" {
+		return "", false
+	}
+	if number, err := strconv.Atoi(input); err == nil && number >= 1 && number <= len(outcomes) {
+		return outcomes[number-1], true
+	}
+
+	var matches []string
+	for _, outcome := range outcomes {
+		title := strings.ToLower(outcome)
+		if title == input {
+			return outcome, true
+		}
+		if strings.HasPrefix(title, input) {
+			matches = append(matches, outcome)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], true
+	}
+	return "", false
+}
+
+// resolveChatBets
+// Closes betting, plays the game and pays the winners. Like Twitch predictions, winners share
+// the whole pot in proportion to their bets, and nobody is paid if nobody picked the winner.
+func (dg *DiceGame) resolveChatBets(user db.StreamUser, bets *chatBets, mode string, game PredictionGame) {
+	dg.mu.Lock()
+	if dg.bets == bets {
+		dg.bets = nil
+	}
+	dg.mu.Unlock()
+	record := bets.record
+
+	var rolls []int
+	roll := recordRolls(NewSeededRoller(*record.Seed, record.TwitchID).Roll, &rolls)
+	winner, err := playGame(game, roll, func(message string) {
+		dg.ircClient.Say(dg.channel, message)
+	})
+	if err != nil {
+		dg.refundBets(user, record, err)
+		return
+	}
+
+	winners := payChatBets(bets.bets, winner)
+	for _, bet := range winners {
+		dg.dataStore.AddPoints(db.PointsEntry{
+			ChannelId:    user.UserId,
+			UserId:       bet.UserId,
+			Amount:       bet.Payout,
+			Reason:       db.POINTS_REASON_BET_PAYOUT,
+			PredictionId: &record.ID,
+		})
+	}
+	dg.ircClient.Say(dg.channel, winnersMessage(winner, winners, len(bets.bets)))
+
+	formattedRolls := FormatRolls(rolls)
+	dg.updateRecord(record, twitch.PREDICTION_RESOLVED, &winner, &formattedRolls)
+	dg.saveGame(user, record, bets.outcomeTotals(), mode, rolls, winner, twitch.PREDICTION_RESOLVED)
+}
+
+// payChatBets
+// Works out the payout of each winning bet, and returns the winning bets with the biggest payouts first
+func payChatBets(bets map[int]*ChatBet, winner string) []*ChatBet {
+	pot, winningPot := 0, 0
+	winners := []*ChatBet{}
+	for _, bet := range bets {
+		pot += bet.Amount
+		if bet.Outcome == winner {
+			winningPot += bet.Amount
+			winners = append(winners, bet)
+		}
+	}
+
+	for _, bet := range winners {
+		bet.Payout = int(int64(bet.Amount) * int64(pot) / int64(winningPot))
+	}
+	sort.Slice(winners, func(i, j int) bool {
+		if winners[i].Payout == winners[j].Payout {
+			return winners[i].Name < winners[j].Name
+		}
+		return winners[i].Payout > winners[j].Payout
+	})
+	return winners
+}
+
+func winnersMessage(winner string, winners []*ChatBet, betCount int) string {
+	switch {
+	case betCount == 0:
+		return fmt.Sprintf("%s wins! Nobody placed a bet", winner)
+	case len(winners) == 0:
+		return fmt.Sprintf("%s wins! Nobody bet on it, so all bets are lost", winner)
+	}
+
+	top := make([]string, 0, CHAT_BET_TOP_WINNERS)
+	for i, bet := range winners {
+		if i == CHAT_BET_TOP_WINNERS {
+			break
+		}
+		top = append(top, fmt.Sprintf("%s won %d", bet.Name, bet.Payout))
+	}
+	return fmt.Sprintf("%s wins! %d of %d bets paid out. Top winners: %s", winner, len(winners), betCount, strings.Join(top, ", "))
+}
+
+// outcomeTotals
+// Returns how many viewers bet on each outcome and how many points they bet, like a Twitch prediction's outcomes
+func (b *chatBets) outcomeTotals() []twitch.Outcome {
+	outcomes := make([]twitch.Outcome, len(b.outcomes))
+	for i, title := range b.outcomes {
+		outcomes[i].Title = title
+		for _, bet := range b.bets {
+			if bet.Outcome == title {
+				outcomes[i].Users++
+				outcomes[i].ChannelPoints += bet.Amount
+			}
+		}
+	}
+	return outcomes
+}
+
+// refundBets
+// Cancels the chat prediction, giving back everything bet on it that hasn't been paid out
+func (dg *DiceGame) refundBets(user db.StreamUser, record *db.Prediction, reason error) {
+	log.Printf("Refunding bets on prediction %s: %v", record.TwitchID, reason)
+	bets, err := dg.dataStore.FindPredictionBets(record.ID)
+	if err != nil {
+		return
+	}
+	for _, bet := range bets {
+		dg.dataStore.AddPoints(db.PointsEntry{
+			ChannelId:    user.UserId,
+			UserId:       bet.UserId,
+			Amount:       bet.Amount,
+			Reason:       db.POINTS_REASON_BET_REFUND,
+			PredictionId: &record.ID,
+		})
+	}
+	if len(bets) > 0 {
+		dg.ircClient.Say(dg.channel, fmt.Sprintf("The roll was cancelled, %d bets were refunded", len(bets)))
+	}
+	dg.updateRecord(record, twitch.PREDICTION_CANCELED, nil, nil)
+}
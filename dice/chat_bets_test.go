@@ -0,0 +1,147 @@
+package dice
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/soulxburn/soulxbot/twitch"
+	"github.com/stretchr/testify/assert"
+)
+
+// noPredictionsAPI fails to create predictions, like a channel that isn't an affiliate
+type noPredictionsAPI struct {
+	fakeTwitchAPI
+	createErr error
+}
+
+func (f *noPredictionsAPI) CreatePrediction(user db.StreamUser, title string, window int, outcomes []string) (*twitch.TwitchPrediction, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	return nil, &twitch.HelixError{Endpoint: "/predictions", Status: "403 Forbidden", StatusCode: http.StatusForbidden}
+}
+
+func TestMatchOutcome(t *testing.T) {
+	outcomes := []string{"Under 7", "Exactly 7", "Over 7"}
+	for input, expected := range map[string]string{
+		"under 7": "Under 7",
+		"OVER":    "Over 7",
+		"ex":      "Exactly 7",
+		"2":       "Exactly 7",
+	} {
+		outcome, ok := matchOutcome(outcomes, input)
+		assert.True(t, ok, input)
+		assert.Equal(t, expected, outcome, input)
+	}
+	for _, input := range []string{"", "4", "sideways"} {
+		_, ok := matchOutcome(outcomes, input)
+		assert.False(t, ok, input)
+	}
+}
+
+func TestPayChatBets(t *testing.T) {
+	bets := map[int]*ChatBet{
+		1: {UserId: 1, Name: "a", Outcome: "Even", Amount: 100},
+		2: {UserId: 2, Name: "b", Outcome: "Even", Amount: 300},
+		3: {UserId: 3, Name: "c", Outcome: "Odd", Amount: 600},
+	}
+	winners := payChatBets(bets, "Even")
+	if assert.Len(t, winners, 2) {
+		assert.Equal(t, 750, winners[0].Payout)
+		assert.Equal(t, 250, winners[1].Payout)
+	}
+	assert.Empty(t, payChatBets(bets, "Sideways"))
+}
+
+func TestChatBets(t *testing.T) {
+	game, _, _ := newTestDiceGame(t, twitch.PREDICTION_ACTIVE)
+	game.twitchAPI = &noPredictionsAPI{}
+	config := db.DefaultDiceConfig(testStreamUser.UserId)
+	coinFlip, _ := NewPredictionGame("coinflip", config)
+	streamer := db.User{ID: 31568083, DisplayName: "SouLxBurN"}
+	viewer := db.User{ID: 236797464, DisplayName: "kinda_cringe_dev"}
+
+	_, err := game.PlaceBet(testStreamUser, viewer, "heads", "100")
+	assert.ErrorIs(t, err, ErrBetsClosed)
+
+	assert.NoError(t, game.StartRoll(testStreamUser, config, "coinflip", coinFlip))
+	assert.ErrorIs(t, game.StartRoll(testStreamUser, config, "coinflip", coinFlip), ErrRollCooldown)
+
+	_, err = game.PlaceBet(testStreamUser, viewer, "sideways", "100")
+	assert.ErrorIs(t, err, ErrUnknownOutcome)
+	_, err = game.PlaceBet(testStreamUser, viewer, "heads", "5000")
+	assert.ErrorIs(t, err, db.ErrInsufficientPoints)
+	bet, err := game.PlaceBet(testStreamUser, viewer, "heads", "100")
+	assert.NoError(t, err)
+	bet, err = game.PlaceBet(testStreamUser, viewer, "h", "200")
+	assert.NoError(t, err)
+	assert.Equal(t, 300, bet.Amount)
+	_, err = game.PlaceBet(testStreamUser, viewer, "tails", "100")
+	assert.ErrorIs(t, err, ErrBetOtherOutcome)
+	_, err = game.PlaceBet(testStreamUser, streamer, "tails", "all")
+	assert.NoError(t, err)
+
+	balance, _ := game.dataStore.FindPointsBalance(testStreamUser.UserId, viewer.ID)
+	assert.Equal(t, db.DEFAULT_STARTING_POINTS-300, balance)
+
+	game.resolveChatBets(testStreamUser, game.bets, "coinflip", coinFlip)
+	assert.Nil(t, game.bets)
+
+	games, _, _ := game.dataStore.FindDiceGames(db.DiceGameFilter{UserId: testStreamUser.UserId, Limit: -1})
+	if !assert.Len(t, games, 1) {
+		t.FailNow()
+	}
+	assert.Equal(t, 2, games[0].Users)
+	assert.Equal(t, 1300, games[0].ChannelPoints)
+
+	// The winner takes the whole pot of 1300
+	viewerBalance, _ := game.dataStore.FindPointsBalance(testStreamUser.UserId, viewer.ID)
+	streamerBalance, _ := game.dataStore.FindPointsBalance(testStreamUser.UserId, streamer.ID)
+	if games[0].WinningOutcome == "Heads" {
+		assert.Equal(t, 700+1300, viewerBalance)
+		assert.Equal(t, 0, streamerBalance)
+	} else {
+		assert.Equal(t, 700, viewerBalance)
+		assert.Equal(t, 1300, streamerBalance)
+	}
+}
+
+func TestRefundChatBets(t *testing.T) {
+	game, _, _ := newTestDiceGame(t, twitch.PREDICTION_ACTIVE)
+	game.twitchAPI = &noPredictionsAPI{}
+	config := db.DefaultDiceConfig(testStreamUser.UserId)
+	coinFlip, _ := NewPredictionGame("coinflip", config)
+	viewer := db.User{ID: 236797464, DisplayName: "kinda_cringe_dev"}
+
+	assert.NoError(t, game.StartRoll(testStreamUser, config, "coinflip", coinFlip))
+	_, err := game.PlaceBet(testStreamUser, viewer, "tails", "250")
+	assert.NoError(t, err)
+
+	game.resolveChatBets(testStreamUser, game.bets, "coinflip", &panicGame{})
+	balance, _ := game.dataStore.FindPointsBalance(testStreamUser.UserId, viewer.ID)
+	assert.Equal(t, db.DEFAULT_STARTING_POINTS, balance)
+}
+
+func TestStartRollFailsWithoutChatBets(t *testing.T) {
+	game, _, _ := newTestDiceGame(t, twitch.PREDICTION_ACTIVE)
+	config := db.DefaultDiceConfig(testStreamUser.UserId)
+	coinFlip, _ := NewPredictionGame("coinflip", config)
+
+	for _, err := range []error{
+		&twitch.RateLimitError{},
+		&twitch.HelixError{Endpoint: "/predictions", Status: "500 Internal Server Error", StatusCode: http.StatusInternalServerError},
+		&twitch.HelixError{Endpoint: "/predictions", Status: "400 Bad Request", StatusCode: http.StatusBadRequest, Message: "prediction already active"},
+		errors.New("Critical: Failed to refresh Token"),
+	} {
+		game.twitchAPI = &noPredictionsAPI{createErr: err}
+		assert.ErrorIs(t, game.StartRoll(testStreamUser, config, "coinflip", coinFlip), err)
+		assert.Nil(t, game.bets, "%v doesn't start chat bets", err)
+		assert.True(t, game.CanRoll(), "%v resets the cooldown", err)
+	}
+
+	game.twitchAPI = &noPredictionsAPI{createErr: twitch.ErrMissingAuthToken}
+	assert.NoError(t, game.StartRoll(testStreamUser, config, "coinflip", coinFlip))
+	assert.NotNil(t, game.bets)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"sync"
 	"time"
 
 	twitchirc "github.com/gempir/go-twitch-irc/v2"
@@ -12,22 +13,48 @@ import (
 	"github.com/soulxburn/soulxbot/twitch"
 )
 
+// Limits on dice settings, the prediction limits are set by Twitch
 const (
-	ROLL_COOLDOWN_RESET = 180 * time.Second
-	ROLL_COOLDOWN_START = 10 * time.Second
-	COUNTDOWN_TIMER     = 121 * time.Second
+	MAX_DICE_COUNT            = 10
+	MAX_DICE_SIDES            = 100
+	MIN_PREDICTION_WINDOW     = 30
+	MAX_PREDICTION_WINDOW     = 1800
+	MAX_PREDICTION_TITLE      = 45
+	MAX_PREDICTION_OUTCOME    = 25
+	ROLL_AFTER_WINDOW_SECONDS = 1
 )
 
+// Stored status of predictions the bot couldn't end on Twitch
+const PREDICTION_FAILED = "FAILED"
+
+// How long to wait before trying to cancel a prediction again
+const CANCEL_RETRY_DELAY = time.Second
+
+var ErrRollCooldown = errors.New("Roll is on cooldown")
+
 type Dice struct {
 	sides int
 }
 
+// DiceGame runs the dice roll prediction for a single channel
 type DiceGame struct {
-	Dice         []*Dice
-	CanRoll      bool
-	rollCooldown *time.Timer
-	ircClient    *twitchirc.Client
-	twitchAPI    twitch.ITwitchAPI
+	channel       string
+	cooldownUntil time.Time
+	bets          *chatBets
+	mu            sync.Mutex
+	dataStore     *db.Database
+	ircClient     *twitchirc.Client
+	twitchAPI     twitch.ITwitchAPI
+}
+
+// DiceGameManager keeps a DiceGame for each channel, so a roll in one channel
+// doesn't put the others on cooldown
+type DiceGameManager struct {
+	dataStore *db.Database
+	ircClient *twitchirc.Client
+	twitchAPI twitch.ITwitchAPI
+	games     map[string]*DiceGame
+	mu        sync.Mutex
 }
 
 // NewDice
@@ -49,83 +76,392 @@ func NewDiceSlice(count int, sides int) []*Dice {
 	return dice
 }
 
-func (dg *DiceGame) RollAll(channel string) int {
-	var result int
-	for i, dice := range dg.Dice {
-		roll := dice.Roll()
-		dg.ircClient.Say(channel, fmt.Sprintf("Dice #%d: %d", i+1, roll))
-		result += roll
-	}
-	return result
+// Rolls a single die, replaced in tests to make rolls predictable
+var rollDie = func(sides int) int {
+	return rand.Intn(sides) + 1
 }
 
 // Roll
 func (d *Dice) Roll() int {
-	roll := (rand.Int() % d.sides) + 1
-	return roll
+	return rollDie(d.sides)
 }
 
-// NewDiceGame
-func NewDiceGame(ircClient *twitchirc.Client, twitchAPI twitch.ITwitchAPI) *DiceGame {
-	diceGame := &DiceGame{
-		Dice:         NewDiceSlice(2, 6),
-		CanRoll:      true,
-		rollCooldown: time.NewTimer(ROLL_COOLDOWN_START),
-		ircClient:    ircClient,
-		twitchAPI:    twitchAPI,
+// NewDiceGameManager
+func NewDiceGameManager(dataStore *db.Database, ircClient *twitchirc.Client, twitchAPI twitch.ITwitchAPI) *DiceGameManager {
+	return &DiceGameManager{
+		dataStore: dataStore,
+		ircClient: ircClient,
+		twitchAPI: twitchAPI,
+		games:     make(map[string]*DiceGame),
 	}
-	go diceGame.startCooldownTimer()
-	return diceGame
 }
 
-func (dg *DiceGame) startCooldownTimer() {
-	for {
-		<-dg.rollCooldown.C
-		dg.CanRoll = true
+// Game
+// Returns the channel's dice game, creating it on first use
+func (m *DiceGameManager) Game(channel string) *DiceGame {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	game, ok := m.games[channel]
+	if !ok {
+		game = &DiceGame{
+			channel:   channel,
+			dataStore: m.dataStore,
+			ircClient: m.ircClient,
+			twitchAPI: m.twitchAPI,
+		}
+		m.games[channel] = game
 	}
+	return game
 }
 
 // StartRoll
-func (dg *DiceGame) StartRoll(user db.StreamUser, channel string) error {
-	if !dg.CanRoll {
-		return errors.New("Roll is already in progress")
+// Starts a prediction game in the channel for the mode, using the channel's dice settings
+func (m *DiceGameManager) StartRoll(user db.StreamUser, channel string, mode string) error {
+	config, err := m.dataStore.FindDiceConfig(user.UserId)
+	if err != nil {
+		return err
+	}
+	mode = modeName(mode)
+	game, err := NewPredictionGame(mode, config)
+	if err != nil {
+		return err
+	}
+	return m.Game(channel).StartRoll(user, config, mode, game)
+}
+
+// ResumePredictions
+// Picks up predictions left open when the bot stopped. Each game is played once its
+// prediction window has closed, or the prediction is cancelled if the game can't be played.
+// Bets on chat predictions are refunded.
+func (m *DiceGameManager) ResumePredictions() {
+	records, err := m.dataStore.FindOpenPredictions()
+	if err != nil {
+		return
 	}
 
-	dg.rollCooldown.Reset(ROLL_COOLDOWN_RESET)
-	dg.CanRoll = false
+	for i := range records {
+		record := &records[i]
+		streamUser, err := m.dataStore.FindStreamUserByUserID(record.UserId)
+		if err != nil || streamUser == nil {
+			log.Printf("Unable to resume prediction %s, no stream user %d", record.TwitchID, record.UserId)
+			continue
+		}
+		// Chat bets are only kept in memory, so everything bet on them is refunded
+		if IsChatPrediction(*record) {
+			m.Game(streamUser.Username).refundBets(*streamUser, record, errors.New("the bot restarted"))
+			continue
+		}
+		predictions, err := m.twitchAPI.GetPredictions(*streamUser, []string{record.TwitchID})
+		if err != nil {
+			log.Printf("Unable to resume prediction %s: %v", record.TwitchID, err)
+			continue
+		}
+		prediction := predictions[0]
+		diceGame := m.Game(streamUser.Username)
+
+		if prediction.Status == twitch.PREDICTION_RESOLVED || prediction.Status == twitch.PREDICTION_CANCELED {
+			diceGame.updateRecord(record, prediction.Status, outcomeTitle(prediction, prediction.WinningOutcomeID), nil)
+			continue
+		}
+
+		config, err := m.dataStore.FindDiceConfig(streamUser.UserId)
+		if err != nil {
+			continue
+		}
+		// Seeded games are replayed with the dice they were started with, so they can be verified
+		gameConfig := config
+		if record.Seed != nil {
+			gameConfig = recordConfig(*record)
+		}
+		game, err := NewPredictionGame(record.Mode, gameConfig)
+		if err != nil {
+			diceGame.cancel(*streamUser, record, prediction, err)
+			continue
+		}
+
+		diceGame.mu.Lock()
+		diceGame.cooldownUntil = record.CreatedAt.Add(time.Duration(config.Cooldown) * time.Second)
+		diceGame.mu.Unlock()
+
+		log.Printf("Resuming prediction %s in %s", record.TwitchID, streamUser.Username)
+		go diceGame.playAt(*streamUser, record, prediction, record.Mode, game, record.RollAt)
+	}
+}
+
+// ValidateConfig
+// Checks the dice settings are within the limits of the game and Twitch predictions
+func ValidateConfig(config db.DiceConfig) error {
+	switch {
+	case config.DiceCount < 1 || config.DiceCount > MAX_DICE_COUNT:
+		return fmt.Errorf("dice must be 1 to %d", MAX_DICE_COUNT)
+	case config.Sides < 2 || config.Sides > MAX_DICE_SIDES:
+		return fmt.Errorf("sides must be 2 to %d", MAX_DICE_SIDES)
+	case config.PredictionWindow < MIN_PREDICTION_WINDOW || config.PredictionWindow > MAX_PREDICTION_WINDOW:
+		return fmt.Errorf("window must be %d to %d seconds", MIN_PREDICTION_WINDOW, MAX_PREDICTION_WINDOW)
+	case config.Cooldown < config.PredictionWindow+ROLL_AFTER_WINDOW_SECONDS:
+		// The cooldown starts with the roll, so the next roll can't start while the prediction is open
+		return fmt.Errorf("cooldown must be at least the window plus %d second", ROLL_AFTER_WINDOW_SECONDS)
+	case config.Title == "" || len([]rune(config.Title)) > MAX_PREDICTION_TITLE:
+		return fmt.Errorf("title must be 1 to %d characters", MAX_PREDICTION_TITLE)
+	case config.EvenTitle == "" || len([]rune(config.EvenTitle)) > MAX_PREDICTION_OUTCOME,
+		config.OddTitle == "" || len([]rune(config.OddTitle)) > MAX_PREDICTION_OUTCOME:
+		return fmt.Errorf("outcomes must be 1 to %d characters", MAX_PREDICTION_OUTCOME)
+	case config.EvenTitle == config.OddTitle:
+		return errors.New("outcomes must be different")
+	}
+	return nil
+}
+
+// CanRoll
+// Reports if the channel's roll cooldown has passed
+func (dg *DiceGame) CanRoll() bool {
+	dg.mu.Lock()
+	defer dg.mu.Unlock()
+	return !time.Now().Before(dg.cooldownUntil)
+}
+
+// StartRoll
+// Creates the game's prediction, and plays the game once the prediction window closes
+func (dg *DiceGame) StartRoll(user db.StreamUser, config db.DiceConfig, mode string, game PredictionGame) error {
+	dg.mu.Lock()
+	if time.Now().Before(dg.cooldownUntil) || dg.bets != nil {
+		dg.mu.Unlock()
+		return ErrRollCooldown
+	}
+	// The cooldown starts with the roll, and is at least as long as the prediction window
+	dg.cooldownUntil = time.Now().Add(time.Duration(config.Cooldown) * time.Second)
+	dg.mu.Unlock()
+
 	log.Println("Executing startroll")
-	// Check if prediction is in-flight.
-	// Start prediction
-	prediction, err := dg.twitchAPI.CreatePrediction(user, "Dice Roll Prediction!", 120, []string{"Even", "Odd"})
+	// The rolls come from a secret seed, its hash is posted before anyone bets and the seed revealed after
+	seed, err := NewServerSeed()
 	if err != nil {
+		dg.resetCooldown()
+		return err
+	}
+
+	// Start prediction, falling back to betting bot points in chat when the channel can't use predictions.
+	// Other errors may still leave a prediction open on Twitch, so they don't start chat bets.
+	prediction, err := dg.twitchAPI.CreatePrediction(user, game.Title(), config.PredictionWindow, game.Outcomes())
+	if err != nil && !twitch.PredictionsUnavailable(err) {
+		dg.resetCooldown()
 		return err
 	}
-	// Start a countdown for rolling dice
-	go func() {
-		// Wait for timer
-		time.Sleep(COUNTDOWN_TIMER)
-		// roll dice
-		total := dg.RollAll(channel)
-		dg.ircClient.Say(channel, fmt.Sprintf("Total: %d", total))
-
-		// determine winner
-		winner := total % 2
-		if winner == 0 {
-			// Even wins
-			dg.endPrediction(user, prediction, "Even")
-		} else {
-			// Odd wins
-			dg.endPrediction(user, prediction, "Odd")
+	if err != nil {
+		log.Printf("Unable to create a prediction in %s, starting chat bets: %v", dg.channel, err)
+		if err := dg.startChatBets(user, config, mode, game, seed); err != nil {
+			dg.resetCooldown()
+			return err
 		}
-	}()
+		return nil
+	}
+
+	// Wait for the prediction window to close before rolling
+	rollAt := time.Now().Add(time.Duration(config.PredictionWindow+ROLL_AFTER_WINDOW_SECONDS) * time.Second)
+	record, err := dg.insertRecord(user, prediction.ID, prediction.Status, config, mode, seed, rollAt)
+	if err != nil {
+		log.Printf("Prediction %s won't be resumed or verifiable: %v", prediction.ID, err)
+	} else {
+		dg.announceSeedHash(record)
+	}
 
+	go dg.playAt(user, record, prediction, mode, game, rollAt)
 	return nil
 }
 
-func (dg *DiceGame) endPrediction(user db.StreamUser, prediction *twitch.TwitchPrediction, title string) {
-	for _, v := range prediction.Outcomes {
-		if v.Title == title {
-			dg.twitchAPI.EndPrediction(user, prediction, v.ID)
+// insertRecord
+// Saves the prediction with the seed and dice settings its game is played with
+func (dg *DiceGame) insertRecord(user db.StreamUser, twitchID string, status string, config db.DiceConfig, mode string, seed string, rollAt time.Time) (*db.Prediction, error) {
+	seedHash := HashSeed(seed)
+	return dg.dataStore.InsertPrediction(db.Prediction{
+		TwitchID:  twitchID,
+		UserId:    user.UserId,
+		Mode:      mode,
+		Status:    status,
+		RollAt:    rollAt,
+		Seed:      &seed,
+		SeedHash:  &seedHash,
+		DiceCount: config.DiceCount,
+		Sides:     config.Sides,
+		EvenTitle: config.EvenTitle,
+		OddTitle:  config.OddTitle,
+	})
+}
+
+func (dg *DiceGame) announceSeedHash(record *db.Prediction) {
+	dg.ircClient.Say(dg.channel, fmt.Sprintf("Roll #%d seed hash: %s", record.ID, *record.SeedHash))
+}
+
+func (dg *DiceGame) resetCooldown() {
+	dg.mu.Lock()
+	dg.cooldownUntil = time.Time{}
+	dg.mu.Unlock()
+}
+
+// playAt
+// Waits until the time to roll, then plays the game and resolves the prediction
+func (dg *DiceGame) playAt(user db.StreamUser, record *db.Prediction, prediction *twitch.TwitchPrediction, mode string, game PredictionGame, rollAt time.Time) {
+	time.Sleep(time.Until(rollAt))
+	dg.resolve(user, record, prediction, mode, game)
+}
+
+// resolve
+// Plays the game and resolves the prediction with the winning outcome.
+// If the game can't be played or resolved, the prediction is cancelled so channel points are refunded.
+func (dg *DiceGame) resolve(user db.StreamUser, record *db.Prediction, prediction *twitch.TwitchPrediction, mode string, game PredictionGame) {
+	// The broadcaster may have locked, resolved or cancelled the prediction themselves
+	if current, err := dg.twitchAPI.GetPredictions(user, []string{prediction.ID}); err == nil {
+		prediction = current[0]
+	} else {
+		log.Printf("Unable to check the status of prediction %s: %v", prediction.ID, err)
+	}
+
+	switch prediction.Status {
+	case twitch.PREDICTION_RESOLVED, twitch.PREDICTION_CANCELED:
+		dg.updateRecord(record, prediction.Status, outcomeTitle(prediction, prediction.WinningOutcomeID), nil)
+		return
+	case twitch.PREDICTION_ACTIVE:
+		// Rolling while the prediction is still open would let viewers bet on the result
+		if err := dg.twitchAPI.LockPrediction(user, prediction); err != nil {
+			dg.cancel(user, record, prediction, fmt.Errorf("unable to lock the prediction: %w", err))
+			return
+		}
+	}
+
+	var roll RollFunc = rollDie
+	if record != nil && record.Seed != nil {
+		roll = NewSeededRoller(*record.Seed, record.TwitchID).Roll
+	}
+	var rolls []int
+	winner, err := playGame(game, recordRolls(roll, &rolls), func(message string) {
+		dg.ircClient.Say(dg.channel, message)
+	})
+	if err != nil {
+		dg.cancel(user, record, prediction, err)
+		return
+	}
+
+	var winningID string
+	for _, outcome := range prediction.Outcomes {
+		if outcome.Title == winner {
+			winningID = outcome.ID
+		}
+	}
+	if winningID == "" {
+		err = fmt.Errorf("no outcome matches the winner %q", winner)
+	} else {
+		err = dg.twitchAPI.EndPrediction(user, prediction, winningID)
+	}
+	if err != nil {
+		status := dg.cancel(user, record, prediction, err)
+		dg.saveGame(user, record, prediction.Outcomes, mode, rolls, winner, status)
+		return
+	}
+	formattedRolls := FormatRolls(rolls)
+	dg.updateRecord(record, twitch.PREDICTION_RESOLVED, &winner, &formattedRolls)
+	dg.saveGame(user, record, prediction.Outcomes, mode, rolls, winner, twitch.PREDICTION_RESOLVED)
+}
+
+// saveGame
+// Adds the played game to the channel's history, with how many chatters and points were bet on it
+func (dg *DiceGame) saveGame(user db.StreamUser, record *db.Prediction, outcomes []twitch.Outcome, mode string, rolls []int, winner string, status string) {
+	game := db.DiceGame{
+		UserId:         user.UserId,
+		Mode:           mode,
+		Rolls:          rolls,
+		WinningOutcome: winner,
+		Status:         status,
+	}
+	if stream := dg.dataStore.FindCurrentStream(user.UserId); stream != nil {
+		game.StreamId = &stream.ID
+	}
+	if record != nil {
+		game.PredictionId = &record.ID
+	}
+	for _, roll := range rolls {
+		game.Total += roll
+	}
+	for _, outcome := range outcomes {
+		game.Users += outcome.Users
+		game.ChannelPoints += outcome.ChannelPoints
+	}
+	dg.dataStore.InsertDiceGame(game)
+}
+
+// cancel
+// Cancels the prediction, refunding the channel points. If Twitch won't cancel it, the record is
+// still ended with the prediction's status on Twitch so its seed is revealed.
+// Returns the status the prediction was ended with.
+func (dg *DiceGame) cancel(user db.StreamUser, record *db.Prediction, prediction *twitch.TwitchPrediction, reason error) string {
+	log.Printf("Cancelling prediction %s: %v", prediction.ID, reason)
+	err := dg.twitchAPI.CancelPrediction(user, prediction)
+	if err != nil {
+		log.Printf("Unable to cancel prediction %s, trying again: %v", prediction.ID, err)
+		time.Sleep(CANCEL_RETRY_DELAY)
+		err = dg.twitchAPI.CancelPrediction(user, prediction)
+	}
+	if err != nil {
+		log.Printf("Unable to cancel prediction %s: %v", prediction.ID, err)
+		dg.ircClient.Say(dg.channel, "Something went wrong with the roll, and the prediction couldn't be cancelled. The broadcaster can cancel it to refund channel points")
+		status, winner := dg.twitchStatus(user, prediction)
+		dg.updateRecord(record, status, winner, nil)
+		return status
+	}
+	dg.ircClient.Say(dg.channel, "Something went wrong with the roll, the prediction was cancelled and channel points refunded")
+	dg.updateRecord(record, twitch.PREDICTION_CANCELED, nil, nil)
+	return twitch.PREDICTION_CANCELED
+}
+
+// twitchStatus
+// Returns how the prediction ended on Twitch, or the failed status if it hasn't ended
+func (dg *DiceGame) twitchStatus(user db.StreamUser, prediction *twitch.TwitchPrediction) (string, *string) {
+	predictions, err := dg.twitchAPI.GetPredictions(user, []string{prediction.ID})
+	if err != nil || len(predictions) == 0 {
+		return PREDICTION_FAILED, nil
+	}
+	current := predictions[0]
+	if current.Status != twitch.PREDICTION_RESOLVED && current.Status != twitch.PREDICTION_CANCELED {
+		return PREDICTION_FAILED, nil
+	}
+	return current.Status, outcomeTitle(current, current.WinningOutcomeID)
+}
+
+// updateRecord
+// Ends the stored prediction, revealing its seed now no more bets can be made
+func (dg *DiceGame) updateRecord(record *db.Prediction, status string, winner *string, rolls *string) {
+	if record == nil {
+		return
+	}
+	if err := dg.dataStore.UpdatePredictionStatus(record.ID, status, true, winner, rolls); err != nil {
+		return
+	}
+	if record.Seed != nil {
+		dg.ircClient.Say(dg.channel, fmt.Sprintf("Roll #%d seed: %s, check the roll with !verifyroll %d", record.ID, *record.Seed, record.ID))
+	}
+}
+
+// playGame
+// Plays the game, returning an error instead of panicking if the game fails
+func playGame(game PredictionGame, roll RollFunc, say func(string)) (winner string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("game failed: %v", r)
 		}
+	}()
+	return game.Play(roll, say), nil
+}
+
+// outcomeTitle
+// Returns the title of the outcome with the id
+func outcomeTitle(prediction *twitch.TwitchPrediction, id *string) *string {
+	if id == nil {
+		return nil
 	}
+	for _, outcome := range prediction.Outcomes {
+		if outcome.ID == *id {
+			return &outcome.Title
+		}
+	}
+	return nil
 }
@@ -0,0 +1,188 @@
+package dice
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/soulxburn/soulxbot/twitch"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTwitchAPI records the prediction calls made by the dice game
+type fakeTwitchAPI struct {
+	twitch.ITwitchAPI
+	prediction *twitch.TwitchPrediction
+	resolvedID string
+	locked     bool
+	canceled   bool
+	lockErr    error
+	endErr     error
+	cancelErr  error
+	cancels    int
+}
+
+func (f *fakeTwitchAPI) GetPredictions(user db.StreamUser, ids []string) ([]*twitch.TwitchPrediction, error) {
+	return []*twitch.TwitchPrediction{f.prediction}, nil
+}
+
+func (f *fakeTwitchAPI) LockPrediction(user db.StreamUser, prediction *twitch.TwitchPrediction) error {
+	if f.lockErr != nil {
+		return f.lockErr
+	}
+	f.locked = true
+	return nil
+}
+
+func (f *fakeTwitchAPI) EndPrediction(user db.StreamUser, prediction *twitch.TwitchPrediction, winningID string) error {
+	if f.endErr != nil {
+		return f.endErr
+	}
+	f.resolvedID = winningID
+	return nil
+}
+
+func (f *fakeTwitchAPI) CancelPrediction(user db.StreamUser, prediction *twitch.TwitchPrediction) error {
+	f.cancels++
+	if f.cancelErr != nil {
+		return f.cancelErr
+	}
+	f.canceled = true
+	return nil
+}
+
+var testStreamUser = db.StreamUser{StreamConfig: db.StreamConfig{UserId: 31568083}}
+
+type panicGame struct {
+	coinFlipGame
+}
+
+func (g *panicGame) Play(roll RollFunc, say func(string)) string {
+	panic("dice fell off the table")
+}
+
+func newTestDiceGame(t *testing.T, status string) (*DiceGame, *fakeTwitchAPI, *db.Prediction) {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	t.Cleanup(func() { os.Chdir(wd) })
+	dataStore := db.InitDatabase()
+
+	api := &fakeTwitchAPI{prediction: &twitch.TwitchPrediction{
+		ID:     "prediction",
+		Status: status,
+		Outcomes: []twitch.Outcome{
+			{ID: "heads", Title: "Heads"},
+			{ID: "tails", Title: "Tails"},
+		},
+	}}
+	manager := NewDiceGameManager(dataStore, twitchirc.NewClient("bot", "oauth"), api)
+	record, err := dataStore.InsertPrediction(db.Prediction{
+		TwitchID: "prediction",
+		UserId:   31568083,
+		Mode:     "coinflip",
+		Status:   status,
+		RollAt:   time.Now(),
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return manager.Game("soulxburn"), api, record
+}
+
+func openPredictions(t *testing.T, game *DiceGame) int {
+	open, err := game.dataStore.FindOpenPredictions()
+	assert.NoError(t, err)
+	return len(open)
+}
+
+func TestResolvePrediction(t *testing.T) {
+	game, api, record := newTestDiceGame(t, twitch.PREDICTION_ACTIVE)
+	fixedRolls(t, 2)
+
+	game.resolve(testStreamUser, record, api.prediction, "coinflip", &coinFlipGame{})
+	assert.True(t, api.locked)
+	assert.Equal(t, "tails", api.resolvedID)
+	assert.False(t, api.canceled)
+	assert.Equal(t, 0, openPredictions(t, game))
+
+	games, _, err := game.dataStore.FindDiceGames(db.DiceGameFilter{UserId: 31568083, Limit: -1})
+	assert.NoError(t, err)
+	if assert.Len(t, games, 1) {
+		assert.Equal(t, []int{2}, games[0].Rolls)
+		assert.Equal(t, "Tails", games[0].WinningOutcome)
+		assert.Equal(t, twitch.PREDICTION_RESOLVED, games[0].Status)
+		assert.Equal(t, record.ID, *games[0].PredictionId)
+	}
+}
+
+func TestResolvePredictionCancelsFailedGame(t *testing.T) {
+	game, api, record := newTestDiceGame(t, twitch.PREDICTION_LOCKED)
+
+	game.resolve(testStreamUser, record, api.prediction, "coinflip", &panicGame{})
+	assert.False(t, api.locked)
+	assert.Empty(t, api.resolvedID)
+	assert.True(t, api.canceled)
+	assert.Equal(t, 0, openPredictions(t, game))
+}
+
+func TestResolvePredictionCancelFails(t *testing.T) {
+	game, api, record := newTestDiceGame(t, twitch.PREDICTION_LOCKED)
+	api.cancelErr = errors.New("twitch is down")
+
+	game.resolve(testStreamUser, record, api.prediction, "coinflip", &panicGame{})
+	assert.Equal(t, 2, api.cancels, "the cancel is retried once")
+	assert.Equal(t, 0, openPredictions(t, game), "the record is ended so its seed is revealed")
+
+	ended, ok := game.dataStore.FindPredictionByID(record.ID)
+	if assert.True(t, ok) {
+		assert.Equal(t, PREDICTION_FAILED, ended.Status)
+	}
+}
+
+func TestResolvePredictionLockFails(t *testing.T) {
+	game, api, record := newTestDiceGame(t, twitch.PREDICTION_ACTIVE)
+	api.lockErr = errors.New("twitch is down")
+
+	game.resolve(testStreamUser, record, api.prediction, "coinflip", &panicGame{})
+	assert.Empty(t, api.resolvedID)
+	assert.True(t, api.canceled, "the game isn't played while bets can still be made")
+	assert.Equal(t, 0, openPredictions(t, game))
+}
+
+func TestResolvePredictionEndAndCancelFail(t *testing.T) {
+	game, api, record := newTestDiceGame(t, twitch.PREDICTION_LOCKED)
+	api.endErr = errors.New("twitch is down")
+	api.cancelErr = errors.New("twitch is down")
+
+	game.resolve(testStreamUser, record, api.prediction, "coinflip", &coinFlipGame{})
+	games, _, err := game.dataStore.FindDiceGames(db.DiceGameFilter{UserId: 31568083, Limit: -1})
+	assert.NoError(t, err)
+	if assert.Len(t, games, 1) {
+		assert.Equal(t, PREDICTION_FAILED, games[0].Status, "the game isn't saved as cancelled when it couldn't be")
+	}
+}
+
+func TestResolvePredictionEndedByBroadcaster(t *testing.T) {
+	game, api, record := newTestDiceGame(t, twitch.PREDICTION_CANCELED)
+
+	game.resolve(testStreamUser, record, api.prediction, "coinflip", &coinFlipGame{})
+	assert.Empty(t, api.resolvedID)
+	assert.False(t, api.canceled)
+	assert.Equal(t, 0, openPredictions(t, game))
+}
+
+func TestValidateConfig(t *testing.T) {
+	config := db.DefaultDiceConfig(testStreamUser.UserId)
+	assert.NoError(t, ValidateConfig(config))
+
+	config.Cooldown = 0
+	assert.Error(t, ValidateConfig(config), "the next roll could start while the prediction is open")
+	config.Cooldown = config.PredictionWindow + ROLL_AFTER_WINDOW_SECONDS
+	assert.NoError(t, ValidateConfig(config))
+	config.PredictionWindow++
+	assert.Error(t, ValidateConfig(config))
+}
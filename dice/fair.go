@@ -0,0 +1,181 @@
+package dice
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/soulxburn/soulxbot/db"
+)
+
+// Size of a server seed in bytes, it's hex encoded when stored and revealed
+const SERVER_SEED_BYTES = 32
+
+var ErrSeedNotRevealed = errors.New("The seed is revealed once the prediction ends")
+var ErrNoSeed = errors.New("The prediction wasn't rolled with a seed")
+
+// RollFunc rolls a single die with the number of sides
+type RollFunc func(sides int) int
+
+// SeededRoller
+// Rolls dice from HMAC-SHA256(seed, "<prediction id>:<nonce>"), so anyone given the seed
+// after the prediction ends can replay the rolls
+type SeededRoller struct {
+	key   []byte
+	id    string
+	nonce uint64
+}
+
+// Verification is the result of replaying a prediction's rolls from its revealed seed
+type Verification struct {
+	ID             int      `json:"id"`
+	TwitchID       string   `json:"twitchId"`
+	Mode           string   `json:"mode"`
+	Status         string   `json:"status"`
+	Seed           string   `json:"seed"`
+	SeedHash       string   `json:"seedHash"`
+	HashMatches    bool     `json:"hashMatches"`
+	Rolls          []int    `json:"rolls"`
+	RecordedRolls  []int    `json:"recordedRolls"`
+	Winner         string   `json:"winner"`
+	RecordedWinner *string  `json:"recordedWinner"`
+	Messages       []string `json:"messages"`
+	Verified       bool     `json:"verified"`
+}
+
+// NewServerSeed
+// Generates a secret seed for a prediction's rolls
+func NewServerSeed() (string, error) {
+	seed := make([]byte, SERVER_SEED_BYTES)
+	if _, err := rand.Read(seed); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(seed), nil
+}
+
+// HashSeed
+// Returns the SHA-256 hash of the seed, posted in chat before anyone bets
+func HashSeed(seed string) string {
+	hash := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(hash[:])
+}
+
+// NewSeededRoller
+func NewSeededRoller(seed string, predictionID string) *SeededRoller {
+	return &SeededRoller{
+		key: []byte(seed),
+		id:  predictionID,
+	}
+}
+
+// Roll
+// Rolls the next die, skipping values that would make some sides more likely than others
+func (r *SeededRoller) Roll(sides int) int {
+	n := uint64(sides)
+	limit := math.MaxUint64 - math.MaxUint64%n
+	for {
+		value := r.next()
+		if value < limit {
+			return int(value%n) + 1
+		}
+	}
+}
+
+func (r *SeededRoller) next() uint64 {
+	mac := hmac.New(sha256.New, r.key)
+	fmt.Fprintf(mac, "%s:%d", r.id, r.nonce)
+	r.nonce++
+	return binary.BigEndian.Uint64(mac.Sum(nil)[:8])
+}
+
+// recordRolls
+// Wraps roll, adding each roll to rolls
+func recordRolls(roll RollFunc, rolls *[]int) RollFunc {
+	return func(sides int) int {
+		value := roll(sides)
+		*rolls = append(*rolls, value)
+		return value
+	}
+}
+
+// FormatRolls
+// Joins the rolls for storing with the prediction
+func FormatRolls(rolls []int) string {
+	values := make([]string, len(rolls))
+	for i, roll := range rolls {
+		values[i] = strconv.Itoa(roll)
+	}
+	return strings.Join(values, ",")
+}
+
+func parseRolls(text string) []int {
+	rolls := []int{}
+	for _, value := range strings.Split(text, ",") {
+		if roll, err := strconv.Atoi(value); err == nil {
+			rolls = append(rolls, roll)
+		}
+	}
+	return rolls
+}
+
+// recordConfig
+// Returns the dice settings the prediction was started with
+func recordConfig(record db.Prediction) db.DiceConfig {
+	config := db.DefaultDiceConfig(record.UserId)
+	config.DiceCount = record.DiceCount
+	config.Sides = record.Sides
+	config.EvenTitle = record.EvenTitle
+	config.OddTitle = record.OddTitle
+	return config
+}
+
+// VerifyPrediction
+// Replays an ended prediction's game from its revealed seed, checking the seed matches the hash
+// posted in chat and the game gives the same rolls and winner as were recorded
+func VerifyPrediction(record db.Prediction) (*Verification, error) {
+	if record.Seed == nil || record.SeedHash == nil {
+		return nil, ErrNoSeed
+	}
+	if record.EndedAt == nil {
+		return nil, ErrSeedNotRevealed
+	}
+
+	verification := &Verification{
+		ID:             record.ID,
+		TwitchID:       record.TwitchID,
+		Mode:           record.Mode,
+		Status:         record.Status,
+		Seed:           *record.Seed,
+		SeedHash:       *record.SeedHash,
+		HashMatches:    HashSeed(*record.Seed) == *record.SeedHash,
+		RecordedWinner: record.WinningOutcome,
+		Messages:       []string{},
+	}
+	if record.Rolls != nil {
+		verification.RecordedRolls = parseRolls(*record.Rolls)
+	}
+
+	game, err := NewPredictionGame(record.Mode, recordConfig(record))
+	if err != nil {
+		return nil, err
+	}
+	roll := recordRolls(NewSeededRoller(*record.Seed, record.TwitchID).Roll, &verification.Rolls)
+	verification.Winner, err = playGame(game, roll, func(message string) {
+		verification.Messages = append(verification.Messages, message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	verification.Verified = verification.HashMatches &&
+		record.WinningOutcome != nil && *record.WinningOutcome == verification.Winner &&
+		FormatRolls(verification.Rolls) == FormatRolls(verification.RecordedRolls)
+	return verification, nil
+}
@@ -0,0 +1,76 @@
+package dice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/soulxburn/soulxbot/twitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeededRoller(t *testing.T) {
+	first := NewSeededRoller("seed", "prediction")
+	second := NewSeededRoller("seed", "prediction")
+	other := NewSeededRoller("seed", "other prediction")
+
+	var firstRolls, secondRolls, otherRolls []int
+	for i := 0; i < 20; i++ {
+		roll := first.Roll(6)
+		assert.True(t, roll >= 1 && roll <= 6)
+		firstRolls = append(firstRolls, roll)
+		secondRolls = append(secondRolls, second.Roll(6))
+		otherRolls = append(otherRolls, other.Roll(6))
+	}
+	assert.Equal(t, firstRolls, secondRolls)
+	assert.NotEqual(t, firstRolls, otherRolls)
+}
+
+func TestVerifyPrediction(t *testing.T) {
+	game, api, _ := newTestDiceGame(t, twitch.PREDICTION_ACTIVE)
+	seed, err := NewServerSeed()
+	assert.NoError(t, err)
+	seedHash := HashSeed(seed)
+	config := db.DefaultDiceConfig(31568083)
+
+	record, err := game.dataStore.InsertPrediction(db.Prediction{
+		TwitchID:  "seeded",
+		UserId:    31568083,
+		Mode:      "evenodd",
+		Status:    twitch.PREDICTION_ACTIVE,
+		RollAt:    time.Now(),
+		Seed:      &seed,
+		SeedHash:  &seedHash,
+		DiceCount: config.DiceCount,
+		Sides:     config.Sides,
+		EvenTitle: config.EvenTitle,
+		OddTitle:  config.OddTitle,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	api.prediction.ID = "seeded"
+	api.prediction.Outcomes = []twitch.Outcome{{ID: "even", Title: config.EvenTitle}, {ID: "odd", Title: config.OddTitle}}
+
+	evenOdd, _ := NewPredictionGame("evenodd", config)
+	game.resolve(testStreamUser, record, api.prediction, "evenodd", evenOdd)
+
+	record, _ = game.dataStore.FindPredictionByID(record.ID)
+	verification, err := VerifyPrediction(*record)
+	if assert.NoError(t, err) {
+		assert.True(t, verification.HashMatches)
+		assert.True(t, verification.Verified)
+		assert.Len(t, verification.Rolls, config.DiceCount)
+		assert.Equal(t, *record.WinningOutcome, verification.Winner)
+	}
+
+	tampered := "not the seed"
+	record.Seed = &tampered
+	verification, _ = VerifyPrediction(*record)
+	assert.False(t, verification.HashMatches)
+	assert.False(t, verification.Verified)
+
+	record.EndedAt = nil
+	_, err = VerifyPrediction(*record)
+	assert.ErrorIs(t, err, ErrSeedNotRevealed)
+}
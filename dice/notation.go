@@ -0,0 +1,359 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits on a single roll, so one command can't flood chat or hang the bot
+const (
+	MAX_ROLL_DICE       = 100
+	MAX_ROLL_SIDES      = 1000
+	MAX_ROLL_TERMS      = 10
+	MAX_ROLL_CONSTANT   = 100000
+	MAX_ROLL_EXPLOSIONS = 100
+)
+
+var ErrEmptyNotation = errors.New("nothing to roll")
+
+// DiceTerm is a single term of a roll, either dice like 4d6kh3 or a constant like 5
+type DiceTerm struct {
+	// 1 to add the term to the total, -1 to subtract it
+	Sign     int
+	Count    int
+	Sides    int
+	Constant int
+	// Number of dice to keep, zero keeps them all
+	KeepHighest int
+	KeepLowest  int
+	// Dice that roll their highest side are rolled again
+	Explode bool
+}
+
+// DiceExpression is a parsed roll like 3d20+5, made of one or more terms
+type DiceExpression struct {
+	Notation string
+	Terms    []DiceTerm
+}
+
+type DieResult struct {
+	Value    int
+	Kept     bool
+	Exploded bool
+}
+
+type TermResult struct {
+	Term  DiceTerm
+	Dice  []DieResult
+	Total int
+}
+
+type RollResult struct {
+	Notation string
+	Terms    []TermResult
+	Total    int
+}
+
+// IsDice
+// Reports if the term is rolled, rather than a constant
+func (t DiceTerm) IsDice() bool {
+	return t.Sides > 0
+}
+
+// String
+// Formats the term in standard notation, without its sign
+func (t DiceTerm) String() string {
+	if !t.IsDice() {
+		return strconv.Itoa(t.Constant)
+	}
+
+	var notation strings.Builder
+	fmt.Fprintf(&notation, "%dd%d", t.Count, t.Sides)
+	if t.Explode {
+		notation.WriteString("!")
+	}
+	if t.KeepHighest > 0 {
+		fmt.Fprintf(&notation, "kh%d", t.KeepHighest)
+	}
+	if t.KeepLowest > 0 {
+		fmt.Fprintf(&notation, "kl%d", t.KeepLowest)
+	}
+	return notation.String()
+}
+
+// ParseNotation
+// Parses standard dice notation, such as 3d20+5, 4d6kh3, d%, 2d6! or 1d8+1d6-2.
+// Supported modifiers are kh (keep highest), kl (keep lowest), k (same as kh) and ! (exploding).
+func ParseNotation(notation string) (*DiceExpression, error) {
+	text := strings.ToLower(strings.Join(strings.Fields(notation), ""))
+	if text == "" {
+		return nil, ErrEmptyNotation
+	}
+
+	expression := &DiceExpression{}
+	totalDice := 0
+	sign := 1
+	if text[0] == '-' || text[0] == '+' {
+		if text[0] == '-' {
+			sign = -1
+		}
+		text = text[1:]
+	}
+
+	for i := 0; ; {
+		term, next, err := parseTerm(text, i)
+		if err != nil {
+			return nil, err
+		}
+		term.Sign = sign
+		expression.Terms = append(expression.Terms, term)
+		if len(expression.Terms) > MAX_ROLL_TERMS {
+			return nil, fmt.Errorf("a roll can have at most %d terms", MAX_ROLL_TERMS)
+		}
+		totalDice += term.Count
+		if totalDice > MAX_ROLL_DICE {
+			return nil, fmt.Errorf("a roll can have at most %d dice", MAX_ROLL_DICE)
+		}
+
+		i = next
+		if i == len(text) {
+			break
+		}
+		switch text[i] {
+		case '+':
+			sign = 1
+		case '-':
+			sign = -1
+		default:
+			return nil, fmt.Errorf("unexpected %q in %s", text[i], text)
+		}
+		i++
+		if i == len(text) {
+			return nil, fmt.Errorf("expected dice or a number after %q", text[i-1])
+		}
+	}
+
+	expression.Notation = expression.String()
+	return expression, nil
+}
+
+// parseTerm
+// Parses the term starting at i, returning it and the index after it
+func parseTerm(text string, i int) (DiceTerm, int, error) {
+	var term DiceTerm
+	count, i, hasCount := readNumber(text, i)
+
+	if i >= len(text) || text[i] != 'd' {
+		if !hasCount {
+			return term, i, fmt.Errorf("expected dice or a number in %s", text)
+		}
+		if count > MAX_ROLL_CONSTANT {
+			return term, i, fmt.Errorf("numbers can be at most %d", MAX_ROLL_CONSTANT)
+		}
+		term.Constant = count
+		return term, i, nil
+	}
+
+	i++
+	if !hasCount {
+		count = 1
+	}
+	if i < len(text) && text[i] == '%' {
+		term.Sides = 100
+		i++
+	} else {
+		var hasSides bool
+		term.Sides, i, hasSides = readNumber(text, i)
+		if !hasSides {
+			return term, i, fmt.Errorf("expected the number of sides after d in %s", text)
+		}
+	}
+	term.Count = count
+
+	for i < len(text) && text[i] != '+' && text[i] != '-' {
+		switch {
+		case text[i] == '!':
+			term.Explode = true
+			i++
+		case strings.HasPrefix(text[i:], "kh"), strings.HasPrefix(text[i:], "kl"), text[i] == 'k':
+			lowest := strings.HasPrefix(text[i:], "kl")
+			if strings.HasPrefix(text[i:], "kh") || lowest {
+				i++
+			}
+			var keep int
+			var hasKeep bool
+			keep, i, hasKeep = readNumber(text, i+1)
+			if !hasKeep {
+				return term, i, fmt.Errorf("expected the number of dice to keep in %s", text)
+			}
+			if lowest {
+				term.KeepLowest = keep
+			} else {
+				term.KeepHighest = keep
+			}
+		default:
+			return term, i, fmt.Errorf("unexpected %q in %s", text[i], text)
+		}
+	}
+
+	return term, i, validateTerm(term)
+}
+
+func validateTerm(term DiceTerm) error {
+	switch {
+	case term.Count < 1 || term.Count > MAX_ROLL_DICE:
+		return fmt.Errorf("dice count must be 1 to %d", MAX_ROLL_DICE)
+	case term.Sides < 1 || term.Sides > MAX_ROLL_SIDES:
+		return fmt.Errorf("dice must have 1 to %d sides", MAX_ROLL_SIDES)
+	case term.Explode && term.Sides < 2:
+		return errors.New("exploding dice need at least 2 sides")
+	case term.KeepHighest > 0 && term.KeepLowest > 0:
+		return errors.New("dice can keep the highest or the lowest, not both")
+	case term.KeepHighest > term.Count || term.KeepLowest > term.Count:
+		return errors.New("can't keep more dice than are rolled")
+	}
+	return nil
+}
+
+// readNumber
+// Reads the digits starting at i, reporting if there were any
+func readNumber(text string, i int) (int, int, bool) {
+	start := i
+	for i < len(text) && text[i] >= '0' && text[i] <= '9' {
+		i++
+	}
+	if i == start {
+		return 0, i, false
+	}
+	// Too many digits to fit in an int is over every limit anyway
+	number, err := strconv.Atoi(text[start:i])
+	if err != nil {
+		number = MAX_ROLL_CONSTANT + 1
+	}
+	return number, i, true
+}
+
+// String
+// Formats the expression in standard notation
+func (e *DiceExpression) String() string {
+	var notation strings.Builder
+	for i, term := range e.Terms {
+		if term.Sign < 0 {
+			notation.WriteString("-")
+		} else if i > 0 {
+			notation.WriteString("+")
+		}
+		notation.WriteString(term.String())
+	}
+	return notation.String()
+}
+
+// Roll
+// Rolls every term of the expression
+func (e *DiceExpression) Roll() RollResult {
+	result := RollResult{Notation: e.Notation}
+	for _, term := range e.Terms {
+		termResult := term.roll()
+		result.Terms = append(result.Terms, termResult)
+		result.Total += term.Sign * termResult.Total
+	}
+	return result
+}
+
+func (t DiceTerm) roll() TermResult {
+	result := TermResult{Term: t}
+	if !t.IsDice() {
+		result.Total = t.Constant
+		return result
+	}
+
+	die := NewDice(t.Sides)
+	explosions := 0
+	for i := 0; i < t.Count; i++ {
+		value := die.Roll()
+		result.Dice = append(result.Dice, DieResult{Value: value, Kept: true})
+		for t.Explode && value == t.Sides && explosions < MAX_ROLL_EXPLOSIONS {
+			result.Dice[len(result.Dice)-1].Exploded = true
+			explosions++
+			value = die.Roll()
+			result.Dice = append(result.Dice, DieResult{Value: value, Kept: true})
+		}
+	}
+
+	if keep := t.KeepHighest + t.KeepLowest; keep > 0 {
+		order := make([]int, len(result.Dice))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			if t.KeepLowest > 0 {
+				return result.Dice[order[a]].Value < result.Dice[order[b]].Value
+			}
+			return result.Dice[order[a]].Value > result.Dice[order[b]].Value
+		})
+		for _, i := range order[keep:] {
+			result.Dice[i].Kept = false
+		}
+	}
+
+	for _, die := range result.Dice {
+		if die.Kept {
+			result.Total += die.Value
+		}
+	}
+	return result
+}
+
+// Format
+// Formats the result with every die, dropped dice in parentheses and exploded dice marked with !.
+// If that's longer than maxLength it falls back to the total of each term, then to the total alone.
+func (r RollResult) Format(maxLength int) string {
+	detailed := r.format(true)
+	if len(detailed) <= maxLength {
+		return detailed
+	}
+	summary := r.format(false)
+	if len(summary) <= maxLength {
+		return summary
+	}
+	return fmt.Sprintf("%s = %d", r.Notation, r.Total)
+}
+
+func (r RollResult) format(showDice bool) string {
+	var text strings.Builder
+	text.WriteString(r.Notation + ": ")
+	for i, term := range r.Terms {
+		if term.Term.Sign < 0 {
+			text.WriteString(" - ")
+		} else if i > 0 {
+			text.WriteString(" + ")
+		}
+
+		if !term.Term.IsDice() {
+			text.WriteString(strconv.Itoa(term.Total))
+			continue
+		}
+		if !showDice {
+			fmt.Fprintf(&text, "%s (%d)", term.Term.String(), term.Total)
+			continue
+		}
+
+		dice := make([]string, len(term.Dice))
+		for j, die := range term.Dice {
+			value := strconv.Itoa(die.Value)
+			if die.Exploded {
+				value += "!"
+			}
+			if !die.Kept {
+				value = "(" + value + ")"
+			}
+			dice[j] = value
+		}
+		text.WriteString("[" + strings.Join(dice, ", ") + "]")
+	}
+	fmt.Fprintf(&text, " = %d", r.Total)
+	return text.String()
+}
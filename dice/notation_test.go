@@ -0,0 +1,104 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedRolls makes the dice roll the values in order, then restores random rolls
+func fixedRolls(t *testing.T, values ...int) {
+	original := rollDie
+	t.Cleanup(func() { rollDie = original })
+
+	next := 0
+	rollDie = func(sides int) int {
+		value := values[next%len(values)]
+		next++
+		return value
+	}
+}
+
+func TestParseNotation(t *testing.T) {
+	cases := map[string]string{
+		"3d20+5":      "3d20+5",
+		"4d6kh3":      "4d6kh3",
+		"4d6k3":       "4d6kh3",
+		"2d20kl1":     "2d20kl1",
+		"d%":          "1d100",
+		"2d6!":        "2d6!",
+		" 1d8 + d6-2": "1d8+1d6-2",
+		"-1+d4":       "-1+1d4",
+	}
+	for notation, expected := range cases {
+		expression, err := ParseNotation(notation)
+		if assert.NoError(t, err, notation) {
+			assert.Equal(t, expected, expression.Notation, notation)
+		}
+	}
+}
+
+func TestParseNotationErrors(t *testing.T) {
+	for _, notation := range []string{
+		"", "d", "2d", "3d20+", "abc", "2d6x", "101d6", "1d1001", "1d1!",
+		"2d6kh3", "4d6kh2kl1", "60d6+60d6", "1+1+1+1+1+1+1+1+1+1+1",
+		"99999999999999999999d6",
+	} {
+		_, err := ParseNotation(notation)
+		assert.Error(t, err, notation)
+	}
+}
+
+func TestRoll(t *testing.T) {
+	fixedRolls(t, 12, 4, 19)
+	expression, _ := ParseNotation("3d20+5")
+	result := expression.Roll()
+	assert.Equal(t, 40, result.Total)
+	assert.Equal(t, "3d20+5: [12, 4, 19] + 5 = 40", result.Format(100))
+}
+
+func TestRollKeepHighest(t *testing.T) {
+	fixedRolls(t, 3, 6, 1, 5)
+	expression, _ := ParseNotation("4d6kh3")
+	result := expression.Roll()
+	assert.Equal(t, 14, result.Total)
+	assert.Equal(t, "4d6kh3: [3, 6, (1), 5] = 14", result.Format(100))
+}
+
+func TestRollKeepLowest(t *testing.T) {
+	fixedRolls(t, 15, 8)
+	expression, _ := ParseNotation("2d20kl1-1")
+	result := expression.Roll()
+	assert.Equal(t, 7, result.Total)
+}
+
+func TestRollExploding(t *testing.T) {
+	fixedRolls(t, 6, 6, 2, 4)
+	expression, _ := ParseNotation("2d6!")
+	result := expression.Roll()
+	assert.Equal(t, 18, result.Total)
+	assert.Equal(t, "2d6!: [6!, 6!, 2, 4] = 18", result.Format(100))
+}
+
+func TestRollExplodingLimit(t *testing.T) {
+	fixedRolls(t, 2)
+	expression, _ := ParseNotation("1d2!")
+	result := expression.Roll()
+	assert.Len(t, result.Terms[0].Dice, MAX_ROLL_EXPLOSIONS+1)
+}
+
+func TestFormatTrimsToFit(t *testing.T) {
+	fixedRolls(t, 3)
+	expression, _ := ParseNotation("100d6+2")
+	result := expression.Roll()
+	assert.Equal(t, "100d6+2: 100d6 (300) + 2 = 302", result.Format(100))
+	assert.Equal(t, "100d6+2 = 302", result.Format(20))
+}
+
+func TestRollRange(t *testing.T) {
+	expression, _ := ParseNotation("d%")
+	for i := 0; i < 1000; i++ {
+		total := expression.Roll().Total
+		assert.True(t, total >= 1 && total <= 100)
+	}
+}
@@ -0,0 +1,245 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/soulxburn/soulxbot/db"
+)
+
+// Twitch predictions can have 2 to 10 outcomes
+const (
+	MIN_PREDICTION_OUTCOMES = 2
+	MAX_PREDICTION_OUTCOMES = 10
+)
+
+const DEFAULT_PREDICTION_MODE = "evenodd"
+
+var ErrUnknownMode = errors.New("Unknown prediction mode")
+
+// PredictionGame is a game of chance chat predicts the outcome of with a Twitch prediction
+type PredictionGame interface {
+	// Title of the prediction
+	Title() string
+	// Outcomes chat can predict, one of which is returned by Play
+	Outcomes() []string
+	// Play runs the game with dice rolled by roll, announcing each step with say, and returns the winning outcome
+	Play(roll RollFunc, say func(string)) string
+}
+
+// Creates the game for a mode from the channel's dice settings
+type predictionGameFactory func(config db.DiceConfig) PredictionGame
+
+var predictionModes = map[string]predictionGameFactory{
+	"evenodd":   newEvenOddGame,
+	"overunder": newOverUnderGame,
+	"doubles":   newDoublesGame,
+	"highlow":   newHighLowGame,
+	"coinflip":  newCoinFlipGame,
+}
+
+// PredictionModes
+// Returns the names of every prediction game mode
+func PredictionModes() []string {
+	modes := make([]string, 0, len(predictionModes))
+	for mode := range predictionModes {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	return modes
+}
+
+// NewPredictionGame
+// Creates the game for the mode, the default mode when it is empty
+func NewPredictionGame(mode string, config db.DiceConfig) (PredictionGame, error) {
+	factory, ok := predictionModes[modeName(mode)]
+	if !ok {
+		return nil, ErrUnknownMode
+	}
+
+	game := factory(config)
+	if err := validateGame(game); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+func modeName(mode string) string {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" {
+		return DEFAULT_PREDICTION_MODE
+	}
+	return mode
+}
+
+func validateGame(game PredictionGame) error {
+	outcomes := game.Outcomes()
+	if len(outcomes) < MIN_PREDICTION_OUTCOMES || len(outcomes) > MAX_PREDICTION_OUTCOMES {
+		return fmt.Errorf("predictions need %d to %d outcomes", MIN_PREDICTION_OUTCOMES, MAX_PREDICTION_OUTCOMES)
+	}
+	seen := make(map[string]bool)
+	for _, outcome := range outcomes {
+		if outcome == "" || len([]rune(outcome)) > MAX_PREDICTION_OUTCOME || seen[outcome] {
+			return fmt.Errorf("outcomes must be different, and 1 to %d characters", MAX_PREDICTION_OUTCOME)
+		}
+		seen[outcome] = true
+	}
+	return nil
+}
+
+// rollAll
+// Rolls the dice, announcing each one, and returns the rolls and their total
+func rollAll(roll RollFunc, count int, sides int, say func(string)) ([]int, int) {
+	rolls := make([]int, count)
+	total := 0
+	for i := range rolls {
+		rolls[i] = roll(sides)
+		say(fmt.Sprintf("Dice #%d: %d", i+1, rolls[i]))
+		total += rolls[i]
+	}
+	return rolls, total
+}
+
+// evenOddGame
+// The original dice game, predicting if the total of the channel's dice is even or odd
+type evenOddGame struct {
+	config db.DiceConfig
+}
+
+func newEvenOddGame(config db.DiceConfig) PredictionGame {
+	return &evenOddGame{config: config}
+}
+
+func (g *evenOddGame) Title() string {
+	return g.config.Title
+}
+
+func (g *evenOddGame) Outcomes() []string {
+	return []string{g.config.EvenTitle, g.config.OddTitle}
+}
+
+func (g *evenOddGame) Play(roll RollFunc, say func(string)) string {
+	_, total := rollAll(roll, g.config.DiceCount, g.config.Sides, say)
+	say(fmt.Sprintf("Total: %d", total))
+	if total%2 == 0 {
+		return g.config.EvenTitle
+	}
+	return g.config.OddTitle
+}
+
+// overUnderGame
+// Predicts if two six-sided dice total under, exactly or over 7
+type overUnderGame struct{}
+
+func newOverUnderGame(config db.DiceConfig) PredictionGame {
+	return &overUnderGame{}
+}
+
+func (g *overUnderGame) Title() string {
+	return "Over or under 7?"
+}
+
+func (g *overUnderGame) Outcomes() []string {
+	return []string{"Under 7", "Exactly 7", "Over 7"}
+}
+
+func (g *overUnderGame) Play(roll RollFunc, say func(string)) string {
+	_, total := rollAll(roll, 2, 6, say)
+	say(fmt.Sprintf("Total: %d", total))
+	switch {
+	case total < 7:
+		return "Under 7"
+	case total == 7:
+		return "Exactly 7"
+	default:
+		return "Over 7"
+	}
+}
+
+// doublesGame
+// Predicts if two of the channel's dice roll the same number
+type doublesGame struct {
+	sides int
+}
+
+func newDoublesGame(config db.DiceConfig) PredictionGame {
+	return &doublesGame{sides: config.Sides}
+}
+
+func (g *doublesGame) Title() string {
+	return "Will it be doubles?"
+}
+
+func (g *doublesGame) Outcomes() []string {
+	return []string{"Doubles", "No doubles"}
+}
+
+func (g *doublesGame) Play(roll RollFunc, say func(string)) string {
+	rolls, _ := rollAll(roll, 2, g.sides, say)
+	if rolls[0] == rolls[1] {
+		say("Doubles!")
+		return "Doubles"
+	}
+	return "No doubles"
+}
+
+// highLowGame
+// Predicts if a card drawn from the deck is low, an eight, or high. Aces are high.
+type highLowGame struct{}
+
+var cardRanks = []string{"2", "3", "4", "5", "6", "7", "8", "9", "10", "Jack", "Queen", "King", "Ace"}
+var cardSuits = []string{"Clubs", "Diamonds", "Hearts", "Spades"}
+
+func newHighLowGame(config db.DiceConfig) PredictionGame {
+	return &highLowGame{}
+}
+
+func (g *highLowGame) Title() string {
+	return "High or low card?"
+}
+
+func (g *highLowGame) Outcomes() []string {
+	return []string{"Low (2-7)", "Eight", "High (9-Ace)"}
+}
+
+func (g *highLowGame) Play(roll RollFunc, say func(string)) string {
+	rank := roll(len(cardRanks)) - 1
+	suit := roll(len(cardSuits)) - 1
+	say(fmt.Sprintf("The card is the %s of %s", cardRanks[rank], cardSuits[suit]))
+
+	switch value := rank + 2; {
+	case value < 8:
+		return "Low (2-7)"
+	case value == 8:
+		return "Eight"
+	default:
+		return "High (9-Ace)"
+	}
+}
+
+// coinFlipGame
+// Predicts heads or tails
+type coinFlipGame struct{}
+
+func newCoinFlipGame(config db.DiceConfig) PredictionGame {
+	return &coinFlipGame{}
+}
+
+func (g *coinFlipGame) Title() string {
+	return "Heads or tails?"
+}
+
+func (g *coinFlipGame) Outcomes() []string {
+	return []string{"Heads", "Tails"}
+}
+
+func (g *coinFlipGame) Play(roll RollFunc, say func(string)) string {
+	if roll(2) == 1 {
+		say("The coin landed on heads")
+		return "Heads"
+	}
+	say("The coin landed on tails")
+	return "Tails"
+}
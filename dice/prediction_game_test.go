@@ -0,0 +1,80 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func playMode(t *testing.T, mode string, config db.DiceConfig) (string, []string) {
+	game, err := NewPredictionGame(mode, config)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var messages []string
+	winner := game.Play(rollDie, func(message string) { messages = append(messages, message) })
+	assert.Contains(t, game.Outcomes(), winner)
+	return winner, messages
+}
+
+func TestEvenOddGame(t *testing.T) {
+	config := db.DefaultDiceConfig(1)
+	fixedRolls(t, 3, 5)
+	winner, messages := playMode(t, "", config)
+	assert.Equal(t, config.EvenTitle, winner)
+	assert.Equal(t, []string{"Dice #1: 3", "Dice #2: 5", "Total: 8"}, messages)
+
+	config.DiceCount = 3
+	winner, _ = playMode(t, "evenodd", config)
+	assert.Equal(t, config.OddTitle, winner)
+}
+
+func TestOverUnderGame(t *testing.T) {
+	config := db.DefaultDiceConfig(1)
+	fixedRolls(t, 1, 2, 3, 4, 6, 6)
+	for _, expected := range []string{"Under 7", "Exactly 7", "Over 7"} {
+		winner, _ := playMode(t, "overunder", config)
+		assert.Equal(t, expected, winner)
+	}
+}
+
+func TestDoublesGame(t *testing.T) {
+	config := db.DefaultDiceConfig(1)
+	fixedRolls(t, 4, 4, 4, 5)
+	winner, _ := playMode(t, "doubles", config)
+	assert.Equal(t, "Doubles", winner)
+	winner, _ = playMode(t, "doubles", config)
+	assert.Equal(t, "No doubles", winner)
+}
+
+func TestHighLowGame(t *testing.T) {
+	config := db.DefaultDiceConfig(1)
+	fixedRolls(t, 13, 4, 7, 1, 1, 2)
+	winner, messages := playMode(t, "highlow", config)
+	assert.Equal(t, "High (9-Ace)", winner)
+	assert.Equal(t, []string{"The card is the Ace of Spades"}, messages)
+	winner, _ = playMode(t, "highlow", config)
+	assert.Equal(t, "Eight", winner)
+	winner, _ = playMode(t, "HighLow", config)
+	assert.Equal(t, "Low (2-7)", winner)
+}
+
+func TestCoinFlipGame(t *testing.T) {
+	config := db.DefaultDiceConfig(1)
+	fixedRolls(t, 1, 2)
+	winner, _ := playMode(t, "coinflip", config)
+	assert.Equal(t, "Heads", winner)
+	winner, _ = playMode(t, "coinflip", config)
+	assert.Equal(t, "Tails", winner)
+}
+
+func TestNewPredictionGameErrors(t *testing.T) {
+	config := db.DefaultDiceConfig(1)
+	_, err := NewPredictionGame("roulette", config)
+	assert.ErrorIs(t, err, ErrUnknownMode)
+
+	config.OddTitle = config.EvenTitle
+	_, err = NewPredictionGame("evenodd", config)
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package dice
+
+import (
+	"sort"
+
+	"github.com/soulxburn/soulxbot/db"
+)
+
+// DiceStats summarises a channel's dice game history
+type DiceStats struct {
+	Games int
+	// How often each outcome won, most common first
+	Outcomes []OutcomeCount
+	// Longest streaks of even and odd totals in a row, from evenodd games
+	LongestEvenRun int
+	LongestOddRun  int
+	// The game with the most channel points bet on it, nil if nothing was bet
+	BiggestPot *db.DiceGame
+}
+
+// OutcomeCount is how many games an outcome won
+type OutcomeCount struct {
+	Outcome string
+	Wins    int
+}
+
+// NewDiceStats
+// Summarises games, which are ordered newest first
+func NewDiceStats(games []db.DiceGame) DiceStats {
+	stats := DiceStats{Games: len(games)}
+
+	wins := make(map[string]int)
+	evenRun, oddRun := 0, 0
+	for i := len(games) - 1; i >= 0; i-- {
+		game := games[i]
+		wins[game.WinningOutcome]++
+
+		if game.ChannelPoints > 0 && (stats.BiggestPot == nil || game.ChannelPoints > stats.BiggestPot.ChannelPoints) {
+			stats.BiggestPot = &games[i]
+		}
+
+		if game.Mode != DEFAULT_PREDICTION_MODE {
+			continue
+		}
+		if game.Total%2 == 0 {
+			evenRun, oddRun = evenRun+1, 0
+		} else {
+			evenRun, oddRun = 0, oddRun+1
+		}
+		stats.LongestEvenRun = max(stats.LongestEvenRun, evenRun)
+		stats.LongestOddRun = max(stats.LongestOddRun, oddRun)
+	}
+
+	for outcome, count := range wins {
+		stats.Outcomes = append(stats.Outcomes, OutcomeCount{Outcome: outcome, Wins: count})
+	}
+	sort.Slice(stats.Outcomes, func(i, j int) bool {
+		if stats.Outcomes[i].Wins == stats.Outcomes[j].Wins {
+			return stats.Outcomes[i].Outcome < stats.Outcomes[j].Outcome
+		}
+		return stats.Outcomes[i].Wins > stats.Outcomes[j].Wins
+	})
+	return stats
+}
@@ -0,0 +1,28 @@
+package dice
+
+import (
+	"testing"
+
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDiceStats(t *testing.T) {
+	// Newest first, as they are returned by FindDiceGames
+	games := []db.DiceGame{
+		{ID: 6, Mode: "evenodd", Total: 7, WinningOutcome: "Odd"},
+		{ID: 5, Mode: "coinflip", Total: 2, WinningOutcome: "Tails", ChannelPoints: 5000},
+		{ID: 4, Mode: "evenodd", Total: 3, WinningOutcome: "Odd"},
+		{ID: 3, Mode: "evenodd", Total: 4, WinningOutcome: "Even", ChannelPoints: 300},
+		{ID: 2, Mode: "evenodd", Total: 8, WinningOutcome: "Even"},
+		{ID: 1, Mode: "evenodd", Total: 12, WinningOutcome: "Even"},
+	}
+
+	stats := NewDiceStats(games)
+	assert.Equal(t, 6, stats.Games)
+	assert.Equal(t, []OutcomeCount{{"Even", 3}, {"Odd", 2}, {"Tails", 1}}, stats.Outcomes)
+	assert.Equal(t, 3, stats.LongestEvenRun)
+	// The coinflip doesn't break the run of odd totals
+	assert.Equal(t, 2, stats.LongestOddRun)
+	assert.Equal(t, 5, stats.BiggestPot.ID)
+}
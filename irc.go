@@ -1,12 +1,16 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	// Embeds the timezone database for channels using the qotd daily mode
+	_ "time/tzdata"
 
 	twitchirc "github.com/gempir/go-twitch-irc/v2"
 	dotenv "github.com/joho/godotenv"
@@ -29,7 +33,7 @@ type AppContext struct {
 	TwitchAPI twitch.ITwitchAPI
 	ClientIRC *twitchirc.Client
 	DataStore *db.Database
-	DiceGame  *dice.DiceGame
+	DiceGames *dice.DiceGameManager
 }
 
 var AppCtx AppContext
@@ -53,39 +57,84 @@ func main() {
 	keyPhrase := os.Getenv("SOULXBOT_KEYPHRASE")
 	// keyPhrase := "SOULXBOT_KEYPHRASE"
 
+	qotdSkipLimit, err := strconv.Atoi(os.Getenv("SOULXBOT_QOTDSKIPLIMIT"))
+	if err != nil {
+		qotdSkipLimit = irc.DEFAULT_SKIP_DISABLE_THRESHOLD
+	}
+
 	AppCtx.DataStore = db.InitDatabase()
 	AppCtx.TwitchAPI = twitch.NewTwitchAPI(clientID, clientSecret, AppCtx.DataStore, oauthRedirectUri, keyPhrase)
 	AppCtx.ClientIRC = twitchirc.NewClient(user, oauth)
-	AppCtx.DiceGame = dice.NewDiceGame(AppCtx.ClientIRC, AppCtx.TwitchAPI)
+	AppCtx.DiceGames = dice.NewDiceGameManager(AppCtx.DataStore, AppCtx.ClientIRC, AppCtx.TwitchAPI)
+	var resumePredictions sync.Once
+	AppCtx.ClientIRC.OnConnect(func() {
+		resumePredictions.Do(AppCtx.DiceGames.ResumePredictions)
+	})
+
+	questionAutoPoster := irc.NewQuestionAutoPoster(AppCtx.DataStore, AppCtx.ClientIRC)
+	dailyQuestionRollover := irc.NewDailyQuestionRollover(AppCtx.DataStore, AppCtx.ClientIRC)
+	pointsCommands := irc.NewPointsCommands(AppCtx.DataStore, AppCtx.ClientIRC)
+	watchTimeCommands := &irc.WatchTimeCommands{
+		DataStore: AppCtx.DataStore,
+		ClientIRC: AppCtx.ClientIRC,
+		TwitchAPI: AppCtx.TwitchAPI,
+	}
+	streamScheduler := irc.NewStreamScheduler(questionAutoPoster, dailyQuestionRollover, pointsCommands, watchTimeCommands)
 
 	apiConfig := api.Config{BasicAuth: basicAuth, ClientID: clientID, RedirectURI: oauthRedirectUri, KeyPhrase: keyPhrase}
 	httpApi := api.New(apiConfig, AppCtx.DataStore, AppCtx.TwitchAPI, AppCtx.ClientIRC)
+	httpApi.AddStreamListener(streamScheduler)
 	go httpApi.InitAPIAndListen()
 
 	AppCtx.ClientIRC.OnUserNoticeMessage(func(message twitchirc.UserNoticeMessage) {
 		fmt.Printf("Notice: %s\n", message.Message)
 	})
 
+	activeChatters := irc.NewActiveChatters()
 	questionCommands := irc.QuestionCommands{
+		DataStore:            AppCtx.DataStore,
+		ClientIRC:            AppCtx.ClientIRC,
+		Chatters:             activeChatters,
+		SkipDisableThreshold: qotdSkipLimit,
+	}
+	firstCommands := irc.FirstCommands{
 		DataStore: AppCtx.DataStore,
 		ClientIRC: AppCtx.ClientIRC,
 	}
-	firstCommands := irc.FirstCommands{
+	triviaGame := irc.NewTriviaGame(AppCtx.DataStore, AppCtx.ClientIRC)
+	diceCommands := irc.DiceCommands{
 		DataStore: AppCtx.DataStore,
 		ClientIRC: AppCtx.ClientIRC,
+		DiceGames: AppCtx.DiceGames,
 	}
-	thanosCommand := irc.ThanosCommand{
+	adminCommands := irc.NewAdminCommands(AppCtx.DataStore, AppCtx.ClientIRC, os.Getenv("SOULXBOT_ADMINS"))
+	raffleCommands := irc.NewRaffleCommands(AppCtx.DataStore, AppCtx.ClientIRC, AppCtx.TwitchAPI)
+	pollCommands := irc.NewPollCommands(AppCtx.DataStore, AppCtx.ClientIRC, AppCtx.TwitchAPI)
+	AppCtx.Timers = make(map[string]*time.Timer)
+	timerCommands := irc.NewTimerCommands(AppCtx.DataStore, AppCtx.ClientIRC, AppCtx.Timers)
+	adminCommands.AddChannelListener(timerCommands)
+	quoteCommands := irc.QuoteCommands{
 		DataStore: AppCtx.DataStore,
 		ClientIRC: AppCtx.ClientIRC,
 		TwitchAPI: AppCtx.TwitchAPI,
 	}
+	thanosCommand := irc.NewThanosCommand(AppCtx.DataStore, AppCtx.ClientIRC, AppCtx.TwitchAPI, user)
 
 	var cmds []irc.Command
 	commands := make(map[string]irc.CommandHandler)
 	cmds = append(cmds, questionCommands.GetCommands()...)
 	cmds = append(cmds, firstCommands.GetCommands()...)
 	cmds = append(cmds, thanosCommand.GetCommands()...)
-	listeners := []irc.MessageListener{&firstCommands}
+	cmds = append(cmds, triviaGame.GetCommands()...)
+	cmds = append(cmds, diceCommands.GetCommands()...)
+	cmds = append(cmds, pointsCommands.GetCommands()...)
+	cmds = append(cmds, watchTimeCommands.GetCommands()...)
+	cmds = append(cmds, adminCommands.GetCommands()...)
+	cmds = append(cmds, raffleCommands.GetCommands()...)
+	cmds = append(cmds, pollCommands.GetCommands()...)
+	cmds = append(cmds, timerCommands.GetCommands()...)
+	cmds = append(cmds, quoteCommands.GetCommands()...)
+	listeners := []irc.MessageListener{activeChatters, &firstCommands, questionAutoPoster, triviaGame, pointsCommands, raffleCommands, timerCommands}
 
 	if env != "prod" {
 		dev := "-dev"
@@ -107,13 +156,16 @@ func main() {
 			MessageUser: messageUser,
 			StreamUser:  streamUser,
 			Stream:      stream,
+			Badges:      message.User.Badges,
+			Message:     message.Message,
+			Admin:       messageUser != nil && adminCommands.IsAdmin(messageUser.ID),
 		}
 
 		for _, listener := range listeners {
 			listener.OnMessage(msgCtx)
 		}
 
-		if !streamUser.BotDisabled && isCommand(message.Message) {
+		if (!streamUser.BotDisabled || msgCtx.Admin) && isCommand(message.Message) {
 			command, input := parseCommand(message.Message)
 			cmd, ok := commands[command]
 			if ok {
@@ -122,15 +174,20 @@ func main() {
 				// This is all deprecated
 				switch command {
 				case "startroll":
-					if AppCtx.DiceGame.CanRoll {
-						if err := AppCtx.DiceGame.StartRoll(*streamUser, message.Channel); err != nil {
-							log.Println("Failed to start roll: ", err)
-						}
-					} else {
+					err := AppCtx.DiceGames.StartRoll(*streamUser, message.Channel, input)
+					if errors.Is(err, dice.ErrRollCooldown) {
 						AppCtx.ClientIRC.Say(message.Channel, fmt.Sprintf("%s, That command is on cooldown", message.User.DisplayName))
+					} else if errors.Is(err, dice.ErrUnknownMode) {
+						AppCtx.ClientIRC.Say(message.Channel, fmt.Sprintf("%s, pick a mode: %s", message.User.DisplayName, strings.Join(dice.PredictionModes(), ", ")))
+					} else if err != nil {
+						log.Println("Failed to start roll: ", err)
 					}
 				case "raid":
-					if irc.IsSouLxBurN(streamUser.Username) {
+					if (input == "on" || input == "off") && msgCtx.IsBroadcaster() {
+						if err := AppCtx.DataStore.UpdateRaidEnabled(streamUser.UserId, input == "on"); err == nil {
+							AppCtx.ClientIRC.Say(message.Channel, fmt.Sprintf("!raid is %s", input))
+						}
+					} else if streamUser.RaidEnabled && msgCtx.IsBroadcaster() {
 						var buff strings.Builder
 						for i := 0; i < 9; i++ {
 							buff.WriteString("%[1]s %[2]s %[3]s ")
@@ -155,6 +212,7 @@ func main() {
 		if !user.BotDisabled {
 			usernames = append(usernames, user.Username)
 			AppCtx.ClientIRC.Join(user.Username)
+			timerCommands.Start(user.Username)
 		}
 	}
 	log.Println("Joined channels: ", usernames)
@@ -0,0 +1,59 @@
+package irc
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long after their last message a user still counts as an active chatter
+const ACTIVE_CHATTER_WINDOW = 10 * time.Minute
+
+// ActiveChatters keeps track of who has recently chatted in each channel
+type ActiveChatters struct {
+	lastSeen map[string]map[int]time.Time
+	mu       sync.Mutex
+}
+
+// NewActiveChatters
+func NewActiveChatters() *ActiveChatters {
+	return &ActiveChatters{
+		lastSeen: make(map[string]map[int]time.Time),
+	}
+}
+
+// OnMessage
+func (a *ActiveChatters) OnMessage(msgCtx MessageContext) {
+	if msgCtx.MessageUser == nil {
+		return
+	}
+	channel := strings.ToLower(msgCtx.Channel)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	users, ok := a.lastSeen[channel]
+	if !ok {
+		users = make(map[int]time.Time)
+		a.lastSeen[channel] = users
+	}
+	users[msgCtx.MessageUser.ID] = time.Now()
+}
+
+// Count
+// Returns the number of users that have chatted in the channel within the active window
+func (a *ActiveChatters) Count(channel string) int {
+	channel = strings.ToLower(channel)
+	cutoff := time.Now().Add(-ACTIVE_CHATTER_WINDOW)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	count := 0
+	for userId, seen := range a.lastSeen[channel] {
+		if seen.Before(cutoff) {
+			delete(a.lastSeen[channel], userId)
+			continue
+		}
+		count++
+	}
+	return count
+}
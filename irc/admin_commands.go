@@ -0,0 +1,252 @@
+package irc
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	"github.com/soulxburn/soulxbot/db"
+)
+
+// ChannelListener is told when an admin makes the bot join or leave a channel
+type ChannelListener interface {
+	OnJoin(channel string)
+	OnPart(channel string)
+}
+
+// AdminCommands are for the bot's admins, who can run any command in any channel.
+// Admins are set by twitch user id in the SOULXBOT_ADMINS environment variable, or added in chat.
+type AdminCommands struct {
+	DataStore *db.Database
+	ClientIRC *twitchirc.Client
+	// Admins from the environment can't be removed in chat
+	envAdmins map[int]bool
+	dbAdmins  map[int]bool
+	mu        sync.RWMutex
+	listeners []ChannelListener
+}
+
+// NewAdminCommands
+// Loads the admins from a comma separated list of twitch user ids, and the database
+func NewAdminCommands(dataStore *db.Database, clientIRC *twitchirc.Client, adminIDs string) *AdminCommands {
+	a := &AdminCommands{
+		DataStore: dataStore,
+		ClientIRC: clientIRC,
+		envAdmins: make(map[int]bool),
+		dbAdmins:  make(map[int]bool),
+	}
+	for _, field := range strings.FieldsFunc(adminIDs, func(r rune) bool { return r == ',' || r == ' ' }) {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			log.Printf("Ignoring bot admin %q, admins are twitch user ids", field)
+			continue
+		}
+		a.envAdmins[id] = true
+	}
+
+	ids, err := dataStore.FindBotAdminIDs()
+	if err == nil {
+		for _, id := range ids {
+			a.dbAdmins[id] = true
+		}
+	}
+	return a
+}
+
+// AddChannelListener
+// Registers a listener for the channels admins join and leave, must be called before the bot connects
+func (a *AdminCommands) AddChannelListener(listener ChannelListener) {
+	a.listeners = append(a.listeners, listener)
+}
+
+// IsAdmin
+func (a *AdminCommands) IsAdmin(userId int) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.envAdmins[userId] || a.dbAdmins[userId]
+}
+
+func (a *AdminCommands) GetCommands() []Command {
+	commands := []Command{
+		{"botjoin", a.botjoin},
+		{"botpart", a.botpart},
+		{"botdisable", a.botdisable},
+		{"broadcast", a.broadcast},
+		{"botadmin", a.botadmin},
+	}
+	return commands
+}
+
+// botjoin
+// !botjoin <channel>
+// Turns the bot back on in a registered channel and joins it
+func (a *AdminCommands) botjoin(msgCtx MessageContext, command string, input string) {
+	streamUser, ok := a.findChannel(msgCtx, input)
+	if !ok {
+		return
+	}
+	if err := a.DataStore.UpdateBotDisabled(streamUser.UserId, false); err != nil {
+		return
+	}
+	a.ClientIRC.Join(streamUser.Username)
+	for _, listener := range a.listeners {
+		listener.OnJoin(streamUser.Username)
+	}
+	a.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Joined %s", streamUser.DisplayName))
+}
+
+// botpart
+// !botpart <channel>
+// Leaves a channel until the bot restarts
+func (a *AdminCommands) botpart(msgCtx MessageContext, command string, input string) {
+	streamUser, ok := a.findChannel(msgCtx, input)
+	if !ok {
+		return
+	}
+	a.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Leaving %s", streamUser.DisplayName))
+	a.part(streamUser.Username)
+}
+
+// botdisable
+// !botdisable <channel>
+// Turns the bot off in a channel and leaves it, until an admin uses !botjoin
+func (a *AdminCommands) botdisable(msgCtx MessageContext, command string, input string) {
+	streamUser, ok := a.findChannel(msgCtx, input)
+	if !ok {
+		return
+	}
+	if err := a.DataStore.UpdateBotDisabled(streamUser.UserId, true); err != nil {
+		return
+	}
+	a.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Disabled the bot in %s", streamUser.DisplayName))
+	a.part(streamUser.Username)
+}
+
+// broadcast
+// !broadcast <message>
+// Says the message in every channel the bot is enabled in
+func (a *AdminCommands) broadcast(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if !msgCtx.Admin {
+		return
+	}
+	if len(input) == 0 {
+		a.ClientIRC.Say(msgCtx.Channel, "Usage: !broadcast <message>")
+		return
+	}
+	streamUsers, err := a.DataStore.FindAllStreamUsers()
+	if err != nil {
+		return
+	}
+	for _, streamUser := range streamUsers {
+		if !streamUser.BotDisabled {
+			a.ClientIRC.Say(streamUser.Username, input)
+		}
+	}
+}
+
+// botadmin
+// !botadmin [add|remove <username>]
+func (a *AdminCommands) botadmin(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if !msgCtx.Admin {
+		return
+	}
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		a.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Bot admins: %s", strings.Join(a.adminNames(), ", ")))
+		return
+	}
+	if len(fields) != 2 || (fields[0] != "add" && fields[0] != "remove") {
+		a.ClientIRC.Say(msgCtx.Channel, "Usage: !botadmin [add|remove <username>]")
+		return
+	}
+	username := strings.ToLower(strings.TrimPrefix(fields[1], "@"))
+	user, ok := a.DataStore.FindUserByUsername(username)
+	if !ok {
+		a.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("I haven't seen %s in chat", fields[1]))
+		return
+	}
+
+	if fields[0] == "add" {
+		if err := a.DataStore.InsertBotAdmin(user.ID, msgCtx.MessageUser.ID); err != nil {
+			return
+		}
+		a.mu.Lock()
+		a.dbAdmins[user.ID] = true
+		a.mu.Unlock()
+		a.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s is now a bot admin", user.DisplayName))
+		return
+	}
+
+	a.mu.RLock()
+	fromEnv := a.envAdmins[user.ID]
+	a.mu.RUnlock()
+	if fromEnv {
+		a.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s is an admin in the bot's config, and can't be removed in chat", user.DisplayName))
+		return
+	}
+	if err := a.DataStore.DeleteBotAdmin(user.ID); err != nil {
+		return
+	}
+	a.mu.Lock()
+	delete(a.dbAdmins, user.ID)
+	a.mu.Unlock()
+	a.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s is no longer a bot admin", user.DisplayName))
+}
+
+func (a *AdminCommands) part(channel string) {
+	a.ClientIRC.Depart(channel)
+	for _, listener := range a.listeners {
+		listener.OnPart(channel)
+	}
+}
+
+// findChannel
+// Finds the registered channel named in an admin command
+func (a *AdminCommands) findChannel(msgCtx MessageContext, input string) (*db.StreamUser, bool) {
+	if !msgCtx.Admin {
+		return nil, false
+	}
+	channel := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(input), "#"))
+	if channel == "" {
+		a.ClientIRC.Say(msgCtx.Channel, "Which channel?")
+		return nil, false
+	}
+	streamUser, err := a.DataStore.FindStreamUserByUserName(channel)
+	if err != nil {
+		return nil, false
+	}
+	if streamUser == nil {
+		a.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s isn't registered with the bot", channel))
+		return nil, false
+	}
+	return streamUser, true
+}
+
+func (a *AdminCommands) adminNames() []string {
+	a.mu.RLock()
+	ids := []int{}
+	for id := range a.envAdmins {
+		ids = append(ids, id)
+	}
+	for id := range a.dbAdmins {
+		if !a.envAdmins[id] {
+			ids = append(ids, id)
+		}
+	}
+	a.mu.RUnlock()
+
+	names := []string{}
+	for _, id := range ids {
+		if user, ok := a.DataStore.FindUserByID(id); ok {
+			names = append(names, user.DisplayName)
+		} else {
+			names = append(names, strconv.Itoa(id))
+		}
+	}
+	return names
+}
@@ -0,0 +1,246 @@
+package irc
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/soulxburn/soulxbot/dice"
+)
+
+const DICE_CONFIG_USAGE = "Usage: !diceconfig <dice|sides|window|cooldown|title|even|odd> <value>, or !diceconfig reset"
+
+// Notation rolled by !roll on its own
+const DEFAULT_ROLL_NOTATION = "1d20"
+
+// Keeps messages under the 500 character chat limit, with room for a mention
+const MAX_CHAT_MESSAGE_LENGTH = 450
+
+// Number of outcomes listed by !dicestats
+const DICE_STATS_OUTCOMES = 5
+
+type DiceCommands struct {
+	DataStore *db.Database
+	ClientIRC *twitchirc.Client
+	DiceGames *dice.DiceGameManager
+}
+
+func (d *DiceCommands) GetCommands() []Command {
+	commands := []Command{
+		{"diceconfig", d.diceconfig},
+		{"roll", d.roll},
+		{"verifyroll", d.verifyroll},
+		{"dicestats", d.dicestats},
+		{"bet", d.bet},
+	}
+	return commands
+}
+
+// roll
+// !roll [notation], such as !roll 3d20+5, !roll 4d6kh3, !roll d% or !roll 2d6!
+func (d *DiceCommands) roll(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if msgCtx.MessageUser == nil {
+		return
+	}
+	if len(input) == 0 {
+		input = DEFAULT_ROLL_NOTATION
+	}
+
+	expression, err := dice.ParseNotation(input)
+	if err != nil {
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, %v. Try !roll 3d20+5 or !roll 4d6kh3", msgCtx.MessageUser.DisplayName, err))
+		return
+	}
+
+	prefix := fmt.Sprintf("%s rolled ", msgCtx.MessageUser.DisplayName)
+	result := expression.Roll()
+	d.ClientIRC.Say(msgCtx.Channel, prefix+result.Format(MAX_CHAT_MESSAGE_LENGTH-len(prefix)))
+}
+
+// verifyroll
+// !verifyroll <id>
+// Replays a finished !startroll from its revealed seed, checking it against the hash posted when it started
+func (d *DiceCommands) verifyroll(msgCtx MessageContext, command string, input string) {
+	if msgCtx.MessageUser == nil {
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(input, "#"))
+	if err != nil {
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, Usage: !verifyroll <id>", msgCtx.MessageUser.DisplayName))
+		return
+	}
+	record, ok := d.DataStore.FindPredictionByID(id)
+	if !ok {
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, there's no roll #%d", msgCtx.MessageUser.DisplayName, id))
+		return
+	}
+
+	verification, err := dice.VerifyPrediction(*record)
+	switch {
+	case errors.Is(err, dice.ErrSeedNotRevealed):
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Roll #%d is still open, its seed hash is %s", id, *record.SeedHash))
+		return
+	case errors.Is(err, dice.ErrNoSeed):
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Roll #%d was rolled before rolls could be verified", id))
+		return
+	case err != nil:
+		return
+	}
+
+	rolls := strings.ReplaceAll(dice.FormatRolls(verification.Rolls), ",", ", ")
+	switch {
+	case !verification.HashMatches:
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Roll #%d failed verification, its seed doesn't match the hash", id))
+	case verification.RecordedWinner == nil:
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Roll #%d was cancelled and refunded. Its seed matches the hash, and would have rolled %s for %s", id, rolls, verification.Winner))
+	case verification.Verified:
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Roll #%d verified! Its seed matches the hash, and rolls %s for %s", id, rolls, verification.Winner))
+	default:
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Roll #%d failed verification, its seed rolls %s for %s but %s won", id, rolls, verification.Winner, *verification.RecordedWinner))
+	}
+}
+
+// dicestats
+// !dicestats [mode]
+// Shows how often each outcome has won in the channel, the longest even and odd runs, and the biggest pot
+func (d *DiceCommands) dicestats(msgCtx MessageContext, command string, input string) {
+	if msgCtx.StreamUser == nil {
+		return
+	}
+	filter := db.DiceGameFilter{UserId: msgCtx.StreamUser.UserId, Limit: -1}
+	if mode := strings.ToLower(strings.TrimSpace(input)); mode != "" {
+		if !slices.Contains(dice.PredictionModes(), mode) {
+			d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Pick a mode: %s", strings.Join(dice.PredictionModes(), ", ")))
+			return
+		}
+		filter.Mode = &mode
+	}
+
+	games, _, err := d.DataStore.FindDiceGames(filter)
+	if err != nil {
+		return
+	}
+	if len(games) == 0 {
+		d.ClientIRC.Say(msgCtx.Channel, "No dice games have been played yet, start one with !startroll")
+		return
+	}
+
+	stats := dice.NewDiceStats(games)
+	outcomes := make([]string, 0, DICE_STATS_OUTCOMES)
+	for i, outcome := range stats.Outcomes {
+		if i == DICE_STATS_OUTCOMES {
+			break
+		}
+		outcomes = append(outcomes, fmt.Sprintf("%s %d (%d%%)", outcome.Outcome, outcome.Wins, outcome.Wins*100/stats.Games))
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "%d games: %s.", stats.Games, strings.Join(outcomes, ", "))
+	if stats.LongestEvenRun > 0 || stats.LongestOddRun > 0 {
+		fmt.Fprintf(&message, " Longest runs: %d even, %d odd.", stats.LongestEvenRun, stats.LongestOddRun)
+	}
+	if pot := stats.BiggestPot; pot != nil {
+		fmt.Fprintf(&message, " Biggest pot: %d points from %d chatters on %s, %s won.", pot.ChannelPoints, pot.Users, pot.PlayedAt.Format("Jan 2"), pot.WinningOutcome)
+	}
+	d.ClientIRC.Say(msgCtx.Channel, message.String())
+}
+
+// bet
+// !bet <outcome> <points|all>
+// Bets points on a roll in channels where the bot can't create Twitch predictions
+func (d *DiceCommands) bet(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if msgCtx.MessageUser == nil || msgCtx.StreamUser == nil {
+		return
+	}
+	fields := strings.Fields(input)
+	if len(fields) < 2 {
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, Usage: !bet <outcome> <points|all>", msgCtx.MessageUser.DisplayName))
+		return
+	}
+	outcome := strings.Join(fields[:len(fields)-1], " ")
+	amount := fields[len(fields)-1]
+
+	bet, err := d.DiceGames.Game(msgCtx.Channel).PlaceBet(*msgCtx.StreamUser, *msgCtx.MessageUser, outcome, amount)
+	switch {
+	case errors.Is(err, dice.ErrBetsClosed):
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, there's no roll to bet on", msgCtx.MessageUser.DisplayName))
+	case errors.Is(err, db.ErrInsufficientPoints):
+		balance, _ := d.DataStore.FindPointsBalance(msgCtx.StreamUser.UserId, msgCtx.MessageUser.ID)
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, you only have %d points", msgCtx.MessageUser.DisplayName, balance))
+	case errors.Is(err, dice.ErrUnknownOutcome), errors.Is(err, dice.ErrBetOtherOutcome), errors.Is(err, dice.ErrInvalidBet):
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, %v", msgCtx.MessageUser.DisplayName, err))
+	case err != nil:
+		log.Println("Failed to place bet: ", err)
+	default:
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s bet %d on %s", msgCtx.MessageUser.DisplayName, bet.Amount, bet.Outcome))
+	}
+}
+
+// diceconfig
+// !diceconfig [<setting> <value>|reset]
+// Shows or changes the channel's dice game settings, windows and cooldowns are in seconds
+func (d *DiceCommands) diceconfig(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if !msgCtx.IsBroadcaster() {
+		return
+	}
+
+	config, err := d.DataStore.FindDiceConfig(msgCtx.StreamUser.UserId)
+	if err != nil {
+		return
+	}
+	if len(input) == 0 {
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf(
+			"Dice: %dd%d, prediction window %ds, cooldown %ds, title \"%s\", outcomes %s/%s",
+			config.DiceCount, config.Sides, config.PredictionWindow, config.Cooldown, config.Title, config.EvenTitle, config.OddTitle))
+		return
+	}
+
+	setting, value, _ := strings.Cut(input, " ")
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(setting) {
+	case "reset":
+		config = db.DefaultDiceConfig(msgCtx.StreamUser.UserId)
+	case "title":
+		config.Title = value
+	case "even":
+		config.EvenTitle = value
+	case "odd":
+		config.OddTitle = value
+	case "dice", "sides", "window", "cooldown":
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			d.ClientIRC.Say(msgCtx.Channel, DICE_CONFIG_USAGE)
+			return
+		}
+		switch strings.ToLower(setting) {
+		case "dice":
+			config.DiceCount = number
+		case "sides":
+			config.Sides = number
+		case "window":
+			config.PredictionWindow = number
+		case "cooldown":
+			config.Cooldown = number
+		}
+	default:
+		d.ClientIRC.Say(msgCtx.Channel, DICE_CONFIG_USAGE)
+		return
+	}
+
+	if err := dice.ValidateConfig(config); err != nil {
+		d.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Dice config not updated, %v", err))
+		return
+	}
+	if err := d.DataStore.UpdateDiceConfig(config); err != nil {
+		return
+	}
+	d.ClientIRC.Say(msgCtx.Channel, "Dice config updated")
+}
@@ -96,7 +96,14 @@ func (q *FirstCommands) OnMessage(msgCtx MessageContext) {
 		q.IsEligibleForFirst(msgCtx.Stream, msgCtx.MessageUser) {
 
 		q.DataStore.UpdateFirstUser(msgCtx.Stream.ID, msgCtx.MessageUser.ID)
-		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Congratulations %s! You're first!", msgCtx.MessageUser.DisplayName))
+		q.DataStore.AddPoints(db.PointsEntry{
+			ChannelId: msgCtx.StreamUser.UserId,
+			UserId:    msgCtx.MessageUser.ID,
+			Amount:    POINTS_FOR_FIRST,
+			Reason:    db.POINTS_REASON_FIRST,
+			StreamId:  &msgCtx.Stream.ID,
+		})
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Congratulations %s! You're first! +%d points", msgCtx.MessageUser.DisplayName, POINTS_FOR_FIRST))
 	}
 }
 
@@ -0,0 +1,286 @@
+package irc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	"github.com/soulxburn/soulxbot/db"
+)
+
+// Points earned while a stream is live
+const (
+	POINTS_PER_MESSAGE     = 5
+	POINTS_FOR_FIRST       = 100
+	POINTS_FOR_QOTD_ANSWER = 25
+	POINTS_PER_WATCH       = 10
+)
+
+// Chatters earn message points at most once per cooldown, and watch points every interval.
+// Answers to the question of the day count for the window after it's asked.
+const (
+	POINTS_MESSAGE_COOLDOWN   = time.Minute
+	POINTS_WATCH_INTERVAL     = 10 * time.Minute
+	POINTS_QOTD_ANSWER_WINDOW = 10 * time.Minute
+)
+
+// Number of viewers shown by !top
+const POINTS_TOP_LIMIT = 5
+
+// PointsCommands runs the channel's loyalty points. Chatters earn points for messages and
+// answering the question of the day as a MessageListener, and for watching as a StreamTask.
+type PointsCommands struct {
+	DataStore *db.Database
+	ClientIRC *twitchirc.Client
+	// When each chatter last earned message points, by channel
+	lastMessagePoints map[string]map[int]time.Time
+	mu                sync.Mutex
+}
+
+// NewPointsCommands
+func NewPointsCommands(dataStore *db.Database, clientIRC *twitchirc.Client) *PointsCommands {
+	return &PointsCommands{
+		DataStore:         dataStore,
+		ClientIRC:         clientIRC,
+		lastMessagePoints: make(map[string]map[int]time.Time),
+	}
+}
+
+func (p *PointsCommands) GetCommands() []Command {
+	commands := []Command{
+		{"points", p.points},
+		{"give", p.give},
+		{"top", p.top},
+		{"addpoints", p.addpoints},
+	}
+	return commands
+}
+
+// points
+// !points [username]
+func (p *PointsCommands) points(msgCtx MessageContext, command string, input string) {
+	if msgCtx.MessageUser == nil || msgCtx.StreamUser == nil {
+		return
+	}
+	user := msgCtx.MessageUser
+	if len(input) > 0 {
+		found, ok := p.findUser(input)
+		if !ok {
+			p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, I haven't seen %s in chat", msgCtx.MessageUser.DisplayName, input))
+			return
+		}
+		user = found
+	}
+
+	balance, err := p.DataStore.FindPointsBalance(msgCtx.StreamUser.UserId, user.ID)
+	if err != nil {
+		return
+	}
+	p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s has %d points", user.DisplayName, balance))
+}
+
+// give
+// !give <username> <points>
+func (p *PointsCommands) give(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if msgCtx.MessageUser == nil || msgCtx.StreamUser == nil {
+		return
+	}
+	username, amount, ok := parseUserAmount(input)
+	if !ok || amount < 1 {
+		p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, Usage: !give <username> <points>", msgCtx.MessageUser.DisplayName))
+		return
+	}
+	recipient, ok := p.findUser(username)
+	if !ok {
+		p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, I haven't seen %s in chat", msgCtx.MessageUser.DisplayName, username))
+		return
+	}
+	if recipient.ID == msgCtx.MessageUser.ID {
+		return
+	}
+
+	err := p.DataStore.TransferPoints(msgCtx.StreamUser.UserId, msgCtx.MessageUser.ID, recipient.ID, amount)
+	if errors.Is(err, db.ErrInsufficientPoints) {
+		balance, _ := p.DataStore.FindPointsBalance(msgCtx.StreamUser.UserId, msgCtx.MessageUser.ID)
+		p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, you only have %d points", msgCtx.MessageUser.DisplayName, balance))
+		return
+	} else if err != nil {
+		return
+	}
+	p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s gave %d points to %s", msgCtx.MessageUser.DisplayName, amount, recipient.DisplayName))
+}
+
+// top
+// !top
+func (p *PointsCommands) top(msgCtx MessageContext, command string, input string) {
+	if msgCtx.StreamUser == nil {
+		return
+	}
+	leaders, err := p.DataStore.FindPointsLeaders(msgCtx.StreamUser.UserId, POINTS_TOP_LIMIT)
+	if err != nil {
+		return
+	}
+	if len(leaders) == 0 {
+		p.ClientIRC.Say(msgCtx.Channel, "Nobody has earned any points yet")
+		return
+	}
+
+	lines := make([]string, len(leaders))
+	for i, leader := range leaders {
+		lines[i] = fmt.Sprintf("%d. %s - %d points", i+1, leader.User.DisplayName, leader.Points)
+	}
+	p.ClientIRC.Say(msgCtx.Channel, strings.Join(lines, " | "))
+}
+
+// addpoints
+// !addpoints <username> <points>
+// Mods add points to a viewer, or take them away with a negative number
+func (p *PointsCommands) addpoints(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if msgCtx.MessageUser == nil || msgCtx.StreamUser == nil || !msgCtx.IsModerator() {
+		return
+	}
+	username, amount, ok := parseUserAmount(input)
+	if !ok || amount == 0 {
+		p.ClientIRC.Say(msgCtx.Channel, "Usage: !addpoints <username> <points>")
+		return
+	}
+	user, ok := p.findUser(username)
+	if !ok {
+		p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("I haven't seen %s in chat", username))
+		return
+	}
+
+	err := p.DataStore.AddPoints(db.PointsEntry{
+		ChannelId: msgCtx.StreamUser.UserId,
+		UserId:    user.ID,
+		Amount:    amount,
+		Reason:    db.POINTS_REASON_MOD,
+		ActorId:   &msgCtx.MessageUser.ID,
+	})
+	if err != nil {
+		return
+	}
+	balance, _ := p.DataStore.FindPointsBalance(msgCtx.StreamUser.UserId, user.ID)
+	p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s now has %d points", user.DisplayName, balance))
+}
+
+// OnMessage
+// Gives points for chatting, at most once per cooldown, and for the first reply in the window after the
+// question of the day is asked. Points are only earned while the stream is live.
+func (p *PointsCommands) OnMessage(msgCtx MessageContext) {
+	if msgCtx.Stream == nil || msgCtx.MessageUser == nil || msgCtx.StreamUser == nil || msgCtx.isOwner() {
+		return
+	}
+	if strings.HasPrefix(msgCtx.Message, "!") {
+		return
+	}
+	channelId := msgCtx.StreamUser.UserId
+	userId := msgCtx.MessageUser.ID
+
+	if p.canEarnMessagePoints(msgCtx.Channel, userId) {
+		p.DataStore.AddPoints(db.PointsEntry{
+			ChannelId: channelId,
+			UserId:    userId,
+			Amount:    POINTS_PER_MESSAGE,
+			Reason:    db.POINTS_REASON_MESSAGE,
+			StreamId:  &msgCtx.Stream.ID,
+		})
+	}
+
+	if answersQuestion(msgCtx.Stream, time.Now()) && !p.DataStore.HasStreamPoints(channelId, userId, msgCtx.Stream.ID, db.POINTS_REASON_QOTD) {
+		p.DataStore.AddPoints(db.PointsEntry{
+			ChannelId: channelId,
+			UserId:    userId,
+			Amount:    POINTS_FOR_QOTD_ANSWER,
+			Reason:    db.POINTS_REASON_QOTD,
+			StreamId:  &msgCtx.Stream.ID,
+		})
+	}
+}
+
+// answersQuestion
+// Whether a message sent now is in the window for answering the stream's question of the day
+func answersQuestion(stream *db.Stream, now time.Time) bool {
+	if stream.QOTDId == nil || stream.QOTDAskedAt == nil {
+		return false
+	}
+	return !now.Before(*stream.QOTDAskedAt) && now.Sub(*stream.QOTDAskedAt) <= POINTS_QOTD_ANSWER_WINDOW
+}
+
+func (p *PointsCommands) canEarnMessagePoints(channel string, userId int) bool {
+	channel = strings.ToLower(channel)
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	users, ok := p.lastMessagePoints[channel]
+	if !ok {
+		users = make(map[int]time.Time)
+		p.lastMessagePoints[channel] = users
+	}
+	if now.Sub(users[userId]) < POINTS_MESSAGE_COOLDOWN {
+		return false
+	}
+	users[userId] = now
+	return true
+}
+
+// RunStreamTask
+// Gives points to everyone in chat every interval, for as long as the stream is live.
+// Who is in chat comes from the watch time samples, which leave out the broadcaster and bots on the exclusion list.
+func (p *PointsCommands) RunStreamTask(ctx context.Context, stream *db.Stream, streamUser *db.User) {
+	ticker := time.NewTicker(POINTS_WATCH_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.giveWatchPoints(stream, streamUser, time.Now())
+		}
+	}
+}
+
+func (p *PointsCommands) giveWatchPoints(stream *db.Stream, streamUser *db.User, now time.Time) {
+	viewerIds, err := p.DataStore.FindStreamViewerIDs(stream.ID, now.Add(-WATCH_TIME_MAX_GAP))
+	if err != nil {
+		return
+	}
+	for _, userId := range viewerIds {
+		p.DataStore.AddPoints(db.PointsEntry{
+			ChannelId: streamUser.ID,
+			UserId:    userId,
+			Amount:    POINTS_PER_WATCH,
+			Reason:    db.POINTS_REASON_WATCH,
+			StreamId:  &stream.ID,
+		})
+	}
+}
+
+// findUser
+// Finds a user the bot has seen in chat, with or without an @
+func (p *PointsCommands) findUser(username string) (*db.User, bool) {
+	return p.DataStore.FindUserByUsername(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@")))
+}
+
+// parseUserAmount
+// Splits "<username> <number>"
+func parseUserAmount(input string) (string, int, bool) {
+	fields := strings.Fields(input)
+	if len(fields) != 2 {
+		return "", 0, false
+	}
+	amount, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return fields[0], amount, true
+}
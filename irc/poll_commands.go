@@ -0,0 +1,375 @@
+package irc
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/soulxburn/soulxbot/twitch"
+)
+
+// Twitch's limits on polls, which chat polls follow too
+const (
+	POLL_MIN_CHOICES       = 2
+	POLL_MAX_CHOICES       = 5
+	POLL_MAX_TITLE_LENGTH  = 60
+	POLL_MAX_CHOICE_LENGTH = 25
+	POLL_MIN_SECONDS       = 15
+	POLL_MAX_SECONDS       = 1800
+	POLL_DEFAULT_SECONDS   = 60
+)
+
+// Seconds to wait after a Twitch poll ends before fetching its results
+const POLL_RESULTS_DELAY_SECONDS = 3
+
+// PollCommands runs polls. Channels that can create Twitch polls get one, and everyone
+// else votes in chat with !vote. Results are announced in chat and kept with the stream.
+type PollCommands struct {
+	DataStore *db.Database
+	ClientIRC *twitchirc.Client
+	TwitchAPI twitch.ITwitchAPI
+	// The running poll in each channel, by channel user id
+	polls map[int]*activePoll
+	mu    sync.Mutex
+}
+
+// activePoll is a running poll, with the votes cast in chat when it isn't a Twitch poll
+type activePoll struct {
+	record     *db.Poll
+	channel    string
+	streamUser db.StreamUser
+	twitchPoll *twitch.TwitchPoll
+	votes      map[int]int
+	timer      *time.Timer
+}
+
+// NewPollCommands
+// Finishes the Twitch polls that were running when the bot was last shut down.
+// Votes cast in chat aren't kept, so chat polls that were running are canceled.
+func NewPollCommands(dataStore *db.Database, clientIRC *twitchirc.Client, twitchAPI twitch.ITwitchAPI) *PollCommands {
+	p := &PollCommands{
+		DataStore: dataStore,
+		ClientIRC: clientIRC,
+		TwitchAPI: twitchAPI,
+		polls:     make(map[int]*activePoll),
+	}
+
+	records, err := dataStore.FindActivePolls()
+	if err != nil {
+		return p
+	}
+	for i := range records {
+		record := &records[i]
+		streamUser, err := dataStore.FindStreamUserByUserID(record.ChannelId)
+		if record.TwitchID == nil || err != nil || streamUser == nil {
+			dataStore.EndPoll(record.ID, db.POLL_STATUS_CANCELED, record.Choices)
+			continue
+		}
+		poll := &activePoll{
+			record:     record,
+			channel:    streamUser.Username,
+			streamUser: *streamUser,
+			twitchPoll: &twitch.TwitchPoll{ID: *record.TwitchID, BroadcasterID: strconv.Itoa(record.ChannelId)},
+		}
+		p.polls[record.ChannelId] = poll
+		endsAt := record.StartedAt.Add(time.Duration(record.Duration+POLL_RESULTS_DELAY_SECONDS) * time.Second)
+		p.scheduleEnd(poll, time.Until(endsAt))
+	}
+	return p
+}
+
+func (p *PollCommands) GetCommands() []Command {
+	commands := []Command{
+		{"poll", p.poll},
+		{"vote", p.vote},
+	}
+	return commands
+}
+
+// poll
+// !poll "Question" choice 1 | choice 2 | choice 3 [seconds]
+// !poll end
+func (p *PollCommands) poll(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if msgCtx.StreamUser == nil {
+		return
+	}
+	if len(input) == 0 {
+		p.status(msgCtx)
+		return
+	}
+	if !msgCtx.IsModerator() {
+		return
+	}
+	if strings.EqualFold(input, "end") {
+		p.mu.Lock()
+		poll, ok := p.polls[msgCtx.StreamUser.UserId]
+		p.mu.Unlock()
+		if ok {
+			p.endPoll(poll, true)
+		}
+		return
+	}
+
+	title, choices, duration, ok := parsePoll(input)
+	if !ok {
+		p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Usage: !poll \"Question\" choice 1 | choice 2 [seconds], with %d-%d choices and %d-%d seconds",
+			POLL_MIN_CHOICES, POLL_MAX_CHOICES, POLL_MIN_SECONDS, POLL_MAX_SECONDS))
+		return
+	}
+	p.startPoll(msgCtx, title, choices, duration)
+}
+
+// parsePoll
+// Reads a quoted question, choices separated by |, and how many seconds the poll runs for.
+// A number at the end is only the seconds when the last choice has other words, so choices can be numbers.
+func parsePoll(input string) (string, []string, int, bool) {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, "\"") {
+		return "", nil, 0, false
+	}
+	title, rest, ok := strings.Cut(input[1:], "\"")
+	title = strings.TrimSpace(title)
+	if !ok || title == "" || len(title) > POLL_MAX_TITLE_LENGTH {
+		return "", nil, 0, false
+	}
+
+	choices := strings.Split(rest, "|")
+	duration := POLL_DEFAULT_SECONDS
+	last := strings.Fields(choices[len(choices)-1])
+	if len(last) > 1 {
+		if seconds, err := strconv.Atoi(last[len(last)-1]); err == nil {
+			duration = seconds
+			choices[len(choices)-1] = strings.Join(last[:len(last)-1], " ")
+		}
+	}
+	for i, choice := range choices {
+		choices[i] = strings.TrimSpace(choice)
+		if choices[i] == "" || len(choices[i]) > POLL_MAX_CHOICE_LENGTH {
+			return "", nil, 0, false
+		}
+	}
+	if len(choices) < POLL_MIN_CHOICES || len(choices) > POLL_MAX_CHOICES || duration < POLL_MIN_SECONDS || duration > POLL_MAX_SECONDS {
+		return "", nil, 0, false
+	}
+	return title, choices, duration, true
+}
+
+// startPoll
+// Creates a Twitch poll, or runs the poll in chat if the channel can't create one
+func (p *PollCommands) startPoll(msgCtx MessageContext, title string, choices []string, duration int) {
+	channelId := msgCtx.StreamUser.UserId
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.polls[channelId]; ok {
+		p.ClientIRC.Say(msgCtx.Channel, "A poll is already running, end it with !poll end")
+		return
+	}
+
+	record := db.Poll{
+		ChannelId: channelId,
+		Title:     title,
+		Choices:   make([]db.PollChoice, len(choices)),
+		Duration:  duration,
+	}
+	for i, choice := range choices {
+		record.Choices[i].Title = choice
+	}
+	if msgCtx.Stream != nil {
+		record.StreamId = &msgCtx.Stream.ID
+	}
+
+	twitchPoll, err := p.TwitchAPI.CreatePoll(*msgCtx.StreamUser, title, choices, duration)
+	if err != nil {
+		log.Printf("Unable to create a twitch poll in %s, voting in chat instead: %v", msgCtx.Channel, err)
+		twitchPoll = nil
+	} else {
+		record.TwitchID = &twitchPoll.ID
+	}
+
+	inserted, err := p.DataStore.InsertPoll(record)
+	if err != nil {
+		if twitchPoll != nil {
+			p.TwitchAPI.EndPoll(*msgCtx.StreamUser, twitchPoll)
+		}
+		return
+	}
+	poll := &activePoll{
+		record:     inserted,
+		channel:    msgCtx.Channel,
+		streamUser: *msgCtx.StreamUser,
+		twitchPoll: twitchPoll,
+		votes:      make(map[int]int),
+	}
+	p.polls[channelId] = poll
+
+	if twitchPoll != nil {
+		p.scheduleEnd(poll, time.Duration(duration+POLL_RESULTS_DELAY_SECONDS)*time.Second)
+		p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Poll: %s Vote in the poll at the top of chat, it closes in %d seconds", title, duration))
+		return
+	}
+	p.scheduleEnd(poll, time.Duration(duration)*time.Second)
+	p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Poll: %s Vote with !vote <number> in the next %d seconds. %s", title, duration, numberedChoices(choices)))
+}
+
+func numberedChoices(choices []string) string {
+	numbered := make([]string, len(choices))
+	for i, choice := range choices {
+		numbered[i] = fmt.Sprintf("%d) %s", i+1, choice)
+	}
+	return strings.Join(numbered, " ")
+}
+
+func (p *PollCommands) scheduleEnd(poll *activePoll, delay time.Duration) {
+	poll.timer = time.AfterFunc(max(delay, 0), func() {
+		p.endPoll(poll, false)
+	})
+}
+
+// endPoll
+// Ends the poll, ending the Twitch poll first when a mod ends it early, then announces and keeps the results
+func (p *PollCommands) endPoll(poll *activePoll, early bool) {
+	p.mu.Lock()
+	if p.polls[poll.record.ChannelId] != poll {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.polls, poll.record.ChannelId)
+	poll.timer.Stop()
+	votes := poll.votes
+	p.mu.Unlock()
+
+	choices := poll.record.Choices
+	if poll.twitchPoll != nil {
+		results, err := p.twitchResults(poll, early)
+		if err != nil {
+			log.Printf("Unable to get the results of twitch poll %s: %v", poll.twitchPoll.ID, err)
+			p.DataStore.EndPoll(poll.record.ID, db.POLL_STATUS_CANCELED, choices)
+			p.ClientIRC.Say(poll.channel, fmt.Sprintf("Couldn't get the results of the poll: %s", poll.record.Title))
+			return
+		}
+		choices = results
+	} else {
+		for _, choice := range votes {
+			choices[choice].Votes++
+		}
+	}
+
+	p.DataStore.EndPoll(poll.record.ID, db.POLL_STATUS_ENDED, choices)
+	p.ClientIRC.Say(poll.channel, pollResultsMessage(poll.record.Title, choices))
+}
+
+// twitchResults
+// Gets the Twitch poll's votes, ending it if it's still running
+func (p *PollCommands) twitchResults(poll *activePoll, early bool) ([]db.PollChoice, error) {
+	var twitchPoll *twitch.TwitchPoll
+	var err error
+	if early {
+		twitchPoll, err = p.TwitchAPI.EndPoll(poll.streamUser, poll.twitchPoll)
+	} else {
+		var polls []*twitch.TwitchPoll
+		polls, err = p.TwitchAPI.GetPolls(poll.streamUser, []string{poll.twitchPoll.ID})
+		if err == nil && len(polls) == 0 {
+			err = fmt.Errorf("poll %s not found", poll.twitchPoll.ID)
+		}
+		if err == nil {
+			twitchPoll = polls[0]
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	choices := make([]db.PollChoice, len(twitchPoll.Choices))
+	for i, choice := range twitchPoll.Choices {
+		choices[i] = db.PollChoice{Title: choice.Title, Votes: choice.Votes}
+	}
+	return choices, nil
+}
+
+// pollResultsMessage
+// Lists each choice's votes, and the winner or the choices that tied
+func pollResultsMessage(title string, choices []db.PollChoice) string {
+	total, most := 0, 0
+	results := make([]string, len(choices))
+	for i, choice := range choices {
+		total += choice.Votes
+		most = max(most, choice.Votes)
+		results[i] = fmt.Sprintf("%s - %d", choice.Title, choice.Votes)
+	}
+	if total == 0 {
+		return fmt.Sprintf("Poll closed: %s Nobody voted", title)
+	}
+
+	winners := []string{}
+	for _, choice := range choices {
+		if choice.Votes == most {
+			winners = append(winners, choice.Title)
+		}
+	}
+	outcome := fmt.Sprintf("%s wins with %d%% of %d votes", winners[0], most*100/total, total)
+	if len(winners) > 1 {
+		outcome = fmt.Sprintf("It's a tie between %s", strings.Join(winners, " and "))
+	}
+	return fmt.Sprintf("Poll closed: %s %s | %s", title, strings.Join(results, " | "), outcome)
+}
+
+// status
+// Says how to vote in the running poll, or the results of the stream's last poll
+func (p *PollCommands) status(msgCtx MessageContext) {
+	p.mu.Lock()
+	poll, ok := p.polls[msgCtx.StreamUser.UserId]
+	p.mu.Unlock()
+	if ok {
+		if poll.twitchPoll != nil {
+			p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Poll: %s Vote in the poll at the top of chat", poll.record.Title))
+			return
+		}
+		choices := make([]string, len(poll.record.Choices))
+		for i, choice := range poll.record.Choices {
+			choices[i] = choice.Title
+		}
+		p.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Poll: %s Vote with !vote <number>. %s", poll.record.Title, numberedChoices(choices)))
+		return
+	}
+
+	if msgCtx.Stream == nil {
+		return
+	}
+	polls, err := p.DataStore.FindStreamPolls(msgCtx.Stream.ID)
+	if err != nil || len(polls) == 0 {
+		p.ClientIRC.Say(msgCtx.Channel, "There hasn't been a poll this stream")
+		return
+	}
+	last := polls[len(polls)-1]
+	if last.Status != db.POLL_STATUS_ENDED {
+		return
+	}
+	p.ClientIRC.Say(msgCtx.Channel, pollResultsMessage(last.Title, last.Choices))
+}
+
+// vote
+// !vote <number>
+// Votes in a chat poll. Chatters can change their vote until the poll closes.
+func (p *PollCommands) vote(msgCtx MessageContext, command string, input string) {
+	if msgCtx.StreamUser == nil || msgCtx.MessageUser == nil {
+		return
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	poll, ok := p.polls[msgCtx.StreamUser.UserId]
+	if !ok || poll.twitchPoll != nil || choice < 1 || choice > len(poll.record.Choices) {
+		return
+	}
+	poll.votes[msgCtx.MessageUser.ID] = choice - 1
+}
@@ -0,0 +1,45 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePoll(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		title    string
+		choices  []string
+		duration int
+	}{
+		{`"Best starter?" Bulbasaur | Charmander | Squirtle`, "Best starter?", []string{"Bulbasaur", "Charmander", "Squirtle"}, POLL_DEFAULT_SECONDS},
+		{`"Best starter?" Bulbasaur | Charmander 120`, "Best starter?", []string{"Bulbasaur", "Charmander"}, 120},
+		{`"Pick" 1 | 2`, "Pick", []string{"1", "2"}, POLL_DEFAULT_SECONDS},
+		{`"Pick" 1 | 2 30`, "Pick", []string{"1", "2"}, 30},
+		{`  "Pick"  a b |  c d  `, "Pick", []string{"a b", "c d"}, POLL_DEFAULT_SECONDS},
+	} {
+		title, choices, duration, ok := parsePoll(test.input)
+		if assert.True(t, ok, test.input) {
+			assert.Equal(t, test.title, title, test.input)
+			assert.Equal(t, test.choices, choices, test.input)
+			assert.Equal(t, test.duration, duration, test.input)
+		}
+	}
+
+	for _, input := range []string{
+		``,
+		`Pick a | b`,
+		`"Pick a | b`,
+		`"" a | b`,
+		`"Pick" a`,
+		`"Pick" a | | b`,
+		`"Pick" a | b 5`,
+		`"Pick" a | b 5000`,
+		`"Pick" a | b | c | d | e | f`,
+		`"Pick" a | this choice is far too long to fit`,
+	} {
+		_, _, _, ok := parsePoll(input)
+		assert.False(t, ok, input)
+	}
+}
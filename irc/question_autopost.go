@@ -0,0 +1,121 @@
+package irc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	"github.com/soulxburn/soulxbot/db"
+)
+
+// QuestionAutoPoster posts the question of the day without anyone asking for it.
+// It runs as a StreamTask for the timed posts, and as a MessageListener
+// to repeat the question after a number of chat messages.
+type QuestionAutoPoster struct {
+	DataStore     *db.Database
+	ClientIRC     *twitchirc.Client
+	messageCounts map[int]int
+	mu            sync.Mutex
+}
+
+// NewQuestionAutoPoster
+func NewQuestionAutoPoster(dataStore *db.Database, clientIRC *twitchirc.Client) *QuestionAutoPoster {
+	return &QuestionAutoPoster{
+		DataStore:     dataStore,
+		ClientIRC:     clientIRC,
+		messageCounts: make(map[int]int),
+	}
+}
+
+// RunStreamTask
+// Posts the qotd once the configured delay after going live has passed,
+// then repeats it every configured interval until the stream ends.
+func (q *QuestionAutoPoster) RunStreamTask(ctx context.Context, stream *db.Stream, streamUser *db.User) {
+	config, err := q.DataStore.FindStreamUserByUserID(streamUser.ID)
+	if err != nil || config == nil || !config.QotdEnabled {
+		return
+	}
+	if config.QotdAutoDelay <= 0 && config.QotdRepeatMinutes <= 0 && config.QotdRepeatMessages <= 0 {
+		return
+	}
+
+	q.mu.Lock()
+	q.messageCounts[stream.ID] = 0
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.messageCounts, stream.ID)
+		q.mu.Unlock()
+	}()
+
+	if config.QotdAutoDelay > 0 {
+		// A negative delay means the bot restarted after the question was already posted
+		delay := time.Until(stream.StartedAt.Add(time.Duration(config.QotdAutoDelay) * time.Minute))
+		if delay >= 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+				q.post(stream.ID, streamUser.ID)
+			}
+		}
+	}
+
+	if config.QotdRepeatMinutes <= 0 {
+		<-ctx.Done()
+		return
+	}
+
+	tick := time.NewTicker(time.Duration(config.QotdRepeatMinutes) * time.Minute)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			q.post(stream.ID, streamUser.ID)
+		}
+	}
+}
+
+// OnMessage
+// Counts chat messages for live streams, and posts the qotd every configured number of messages.
+func (q *QuestionAutoPoster) OnMessage(msgCtx MessageContext) {
+	if msgCtx.Stream == nil || msgCtx.StreamUser == nil || msgCtx.StreamUser.QotdRepeatMessages <= 0 {
+		return
+	}
+
+	q.mu.Lock()
+	count, ok := q.messageCounts[msgCtx.Stream.ID]
+	if !ok {
+		q.mu.Unlock()
+		return
+	}
+	count++
+	if count >= msgCtx.StreamUser.QotdRepeatMessages {
+		count = 0
+	}
+	q.messageCounts[msgCtx.Stream.ID] = count
+	q.mu.Unlock()
+
+	if count == 0 {
+		q.post(msgCtx.Stream.ID, msgCtx.StreamUser.UserId)
+	}
+}
+
+func (q *QuestionAutoPoster) post(streamId int, userId int) {
+	streamUser, err := q.DataStore.FindStreamUserByUserID(userId)
+	if err != nil || streamUser == nil || !streamUser.QotdEnabled || streamUser.BotDisabled {
+		return
+	}
+	stream := q.DataStore.FindStreamById(streamId)
+	if stream == nil || stream.EndedAt != nil {
+		return
+	}
+
+	question := questionOfTheDay(q.DataStore, streamUser, stream)
+	if question != nil {
+		q.ClientIRC.Say(streamUser.Username, question.Text)
+	}
+}
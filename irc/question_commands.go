@@ -2,6 +2,10 @@ package irc
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	twitchirc "github.com/gempir/go-twitch-irc/v2"
 	"github.com/soulxburn/soulxbot/db"
@@ -23,24 +27,68 @@ type MessageContext struct {
 	MessageUser *db.User
 	StreamUser  *db.StreamUser
 	Stream      *db.Stream
+	Badges      map[string]int
+	Message     string
+	// Set when the message was sent by a bot admin
+	Admin bool
+}
+
+// IsBroadcaster
+// Reports if the message was sent by the owner of the channel, or a bot admin who can run any command anywhere
+func (m MessageContext) IsBroadcaster() bool {
+	return m.Admin || m.isOwner()
+}
+
+func (m MessageContext) isOwner() bool {
+	return m.StreamUser != nil && m.MessageUser != nil && m.StreamUser.UserId == m.MessageUser.ID
+}
+
+// FullInput
+// Returns everything in the message after the command. Commands are given the first word after
+// the command as their input, so commands that take more than one word read it from here.
+func (m MessageContext) FullInput() string {
+	_, input, _ := strings.Cut(m.Message, " ")
+	return strings.TrimSpace(input)
+}
+
+// Default number of skips before a question is disabled
+const DEFAULT_SKIP_DISABLE_THRESHOLD = 3
+
+// IsModerator
+// Reports if the message was sent by a moderator, or the owner of the channel
+func (m MessageContext) IsModerator() bool {
+	return m.IsBroadcaster() || m.Badges["moderator"] > 0 || m.Badges["broadcaster"] > 0
 }
 
 type QuestionCommands struct {
 	DataStore *db.Database
 	ClientIRC *twitchirc.Client
+	Chatters  *ActiveChatters
+	// Number of skips across all channels before a question is disabled
+	SkipDisableThreshold int
+	skipVotes            map[int]*skipVote
+	mu                   sync.Mutex
 }
 
 func (q *QuestionCommands) GetCommands() []Command {
 	commands := []Command{
 		{"qotd", q.qotd},
 		{"skipqotd", q.skipqotd},
+		{"skipvote", q.skipvote},
+		{"skipvoteshare", q.skipvoteshare},
+		{"qotdauto", q.qotdauto},
+		{"qotd-reset", q.qotdReset},
+		{"qotd-set", q.qotdSet},
+		{"qotddaily", q.qotddaily},
+		{"findq", q.findq},
+		{"disableq", q.disableq},
 	}
 	return commands
 }
 
 func (q *QuestionCommands) qotd(msgCtx MessageContext, command string, input string) {
 	if msgCtx.Stream != nil && msgCtx.StreamUser != nil && msgCtx.StreamUser.QotdEnabled {
-		question := questionOfTheDay(q.DataStore, msgCtx.Stream)
+		question := questionOfTheDay(q.DataStore, msgCtx.StreamUser, msgCtx.Stream)
 		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s", question.Text))
 	}
 }
@@ -52,25 +100,232 @@ func (q *QuestionCommands) skipqotd(msgCtx MessageContext, command string, input
 		msgCtx.StreamUser != nil &&
 		msgCtx.StreamUser.QotdEnabled {
 
-		if skipCount, _ := q.DataStore.IncrementQuestionSkip(*msgCtx.Stream.QOTDId); skipCount > 2 {
-			q.DataStore.DisableQuestion(*msgCtx.Stream.QOTDId)
+		q.skipQuestion(msgCtx)
+	}
+}
+
+// skipQuestion
+// Records the skip, and disables the question once it has been skipped too many times
+func (q *QuestionCommands) skipQuestion(msgCtx MessageContext) {
+	threshold := q.SkipDisableThreshold
+	if threshold <= 0 {
+		threshold = DEFAULT_SKIP_DISABLE_THRESHOLD
+	}
+
+	questionId := *msgCtx.Stream.QOTDId
+	if skipCount, err := q.DataStore.IncrementQuestionSkip(questionId, msgCtx.Stream.ID, msgCtx.StreamUser.UserId); err == nil && skipCount >= threshold {
+		q.DataStore.DisableQuestion(questionId)
+	}
+
+	q.DataStore.UpdateStreamQuestion(msgCtx.Stream.ID, nil)
+	if msgCtx.StreamUser.QotdDaily {
+		date := msgCtx.StreamUser.QotdDate(time.Now())
+		if daily, ok := q.DataStore.FindDailyQuestion(msgCtx.StreamUser.UserId, date); ok && daily.ID == questionId {
+			q.DataStore.SetDailyQuestion(msgCtx.StreamUser.UserId, date, nil)
 		}
+	}
+	q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Question of the day skipped, enter !qotd to get a new question"))
+}
 
-		q.DataStore.UpdateStreamQuestion(msgCtx.Stream.ID, nil)
-		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Question of the day skipped, enter !qotd to get a new question"))
+// findq
+// !findq <words>
+// Shows the ids of the questions that best match the words
+func (q *QuestionCommands) findq(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if !msgCtx.IsModerator() || len(input) == 0 {
+		return
 	}
+
+	results, err := q.DataStore.SearchQuestions(input, 5)
+	if err != nil {
+		return
+	}
+	if len(results) == 0 {
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, no questions found", msgCtx.MessageUser.DisplayName))
+		return
+	}
+
+	matches := make([]string, len(results))
+	for i, result := range results {
+		disabled := ""
+		if result.Disabled {
+			disabled = " (disabled)"
+		}
+		matches[i] = fmt.Sprintf("#%d%s %s", result.ID, disabled, truncate(result.Text, 60))
+	}
+	q.ClientIRC.Say(msgCtx.Channel, truncate(strings.Join(matches, " | "), 450))
 }
 
-func questionOfTheDay(dataStore *db.Database, stream *db.Stream) *db.Question {
-	var question *db.Question
-	if stream == nil {
-		return nil
+// qotdSet
+// !qotd-set <id> [next|YYYY-MM-DD]
+// Sets the qotd now when live, otherwise queues it for the next stream or a date
+func (q *QuestionCommands) qotdSet(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if !msgCtx.IsModerator() || !msgCtx.StreamUser.QotdEnabled {
+		return
+	}
+
+	args := strings.Fields(input)
+	if len(args) == 0 || len(args) > 2 {
+		q.ClientIRC.Say(msgCtx.Channel, "Usage: !qotd-set <question id> [next|YYYY-MM-DD]")
+		return
+	}
+	questionId, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		q.ClientIRC.Say(msgCtx.Channel, "Usage: !qotd-set <question id> [next|YYYY-MM-DD]")
+		return
+	}
+	question, ok := q.DataStore.FindQuestionByID(questionId)
+	if !ok {
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Question #%d does not exist", questionId))
+		return
+	}
+
+	if len(args) == 1 && msgCtx.Stream != nil {
+		if err := q.DataStore.UpdateStreamQuestion(msgCtx.Stream.ID, &question.ID); err != nil {
+			return
+		}
+		if msgCtx.StreamUser.QotdDaily {
+			date := msgCtx.StreamUser.QotdDate(time.Now())
+			q.DataStore.SetDailyQuestion(msgCtx.StreamUser.UserId, date, &question.ID)
+		}
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Question of the day set to #%d: %s", question.ID, question.Text))
+		return
+	}
+
+	var date *string
+	if len(args) == 2 && args[1] != "next" {
+		if _, err := time.Parse(db.SCHEDULE_DATE_FORMAT, args[1]); err != nil {
+			q.ClientIRC.Say(msgCtx.Channel, "Usage: !qotd-set <question id> [next|YYYY-MM-DD]")
+			return
+		}
+		date = &args[1]
+	}
+
+	if _, err := q.DataStore.ScheduleQuestion(msgCtx.StreamUser.UserId, question.ID, date); err != nil {
+		return
 	}
-	if stream.QOTDId != nil {
-		question, _ = dataStore.FindQuestionByID(*stream.QOTDId)
+	if date != nil {
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Question #%d scheduled for %s", question.ID, *date))
 	} else {
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Question #%d queued for the next stream", question.ID))
+	}
+}
+
+// disableq
+// !disableq <id>
+// Questions are shared by every channel, so only bot admins can disable them
+func (q *QuestionCommands) disableq(msgCtx MessageContext, command string, input string) {
+	if !msgCtx.Admin {
+		return
+	}
+
+	questionId, err := strconv.Atoi(strings.TrimPrefix(input, "#"))
+	if err != nil {
+		q.ClientIRC.Say(msgCtx.Channel, "Usage: !disableq <question id>")
+		return
+	}
+	if _, ok := q.DataStore.FindQuestionByID(questionId); !ok {
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Question #%d does not exist", questionId))
+		return
+	}
+	if err := q.DataStore.DisableQuestion(questionId); err != nil {
+		return
+	}
+	q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Question #%d disabled", questionId))
+}
+
+// qotdReset
+// Allows every question to be asked again in the channel
+func (q *QuestionCommands) qotdReset(msgCtx MessageContext, command string, input string) {
+	if !msgCtx.IsBroadcaster() || !msgCtx.StreamUser.QotdEnabled {
+		return
+	}
+	if err := q.DataStore.ResetQotdEpoch(msgCtx.StreamUser.UserId); err != nil {
+		return
+	}
+	q.ClientIRC.Say(msgCtx.Channel, "Question of the day pool reset, every question can be asked again")
+}
+
+// qotdauto
+// !qotdauto <delay minutes> [repeat minutes] [repeat messages]
+// Configures posting the qotd automatically after going live, zero disables an option.
+func (q *QuestionCommands) qotdauto(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if !msgCtx.IsBroadcaster() {
+		return
+	}
+
+	if len(input) == 0 {
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf(
+			"QOTD auto post: %d minutes after going live, repeat every %d minutes, repeat every %d messages",
+			msgCtx.StreamUser.QotdAutoDelay, msgCtx.StreamUser.QotdRepeatMinutes, msgCtx.StreamUser.QotdRepeatMessages))
+		return
+	}
+
+	settings := [3]int{}
+	if input != "off" {
+		args := strings.Fields(input)
+		if len(args) > len(settings) {
+			q.ClientIRC.Say(msgCtx.Channel, "Usage: !qotdauto <delay minutes> [repeat minutes] [repeat messages]")
+			return
+		}
+		for i, arg := range args {
+			value, err := strconv.Atoi(arg)
+			if err != nil || value < 0 {
+				q.ClientIRC.Say(msgCtx.Channel, "Usage: !qotdauto <delay minutes> [repeat minutes] [repeat messages]")
+				return
+			}
+			settings[i] = value
+		}
+	}
+
+	if err := q.DataStore.UpdateQotdAutoPost(msgCtx.StreamUser.UserId, settings[0], settings[1], settings[2]); err != nil {
+		return
+	}
+	q.ClientIRC.Say(msgCtx.Channel, "QOTD auto post updated, changes take effect next stream")
+}
+
+// truncate
+// Shortens text to at most length characters, marking it with an ellipsis
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length-1]) + "…"
+}
+
+// questionOfTheDay
+// Finds the stream's question, choosing one if it hasn't been asked yet.
+// In daily mode the question belongs to the channel's local day instead of the stream.
+func questionOfTheDay(dataStore *db.Database, streamUser *db.StreamUser, stream *db.Stream) *db.Question {
+	if stream == nil || streamUser == nil {
+		return nil
+	}
+
+	date := streamUser.QotdDate(time.Now())
+	if streamUser.QotdDaily {
+		if question, ok := dataStore.FindDailyQuestion(streamUser.UserId, date); ok {
+			if stream.QOTDId == nil || *stream.QOTDId != question.ID {
+				dataStore.UpdateStreamQuestion(stream.ID, &question.ID)
+			}
+			return question
+		}
+	} else if stream.QOTDId != nil {
+		question, _ := dataStore.FindQuestionByID(*stream.QOTDId)
+		return question
+	}
+
+	question, scheduled := dataStore.UseScheduledQuestion(stream, date)
+	if !scheduled {
 		question, _ = dataStore.FindRandomQuestion(stream.UserId)
+	}
+	if question.ID != 0 {
 		dataStore.UpdateStreamQuestion(stream.ID, &question.ID)
+		if streamUser.QotdDaily {
+			dataStore.SetDailyQuestion(streamUser.UserId, date, &question.ID)
+		}
 	}
 	return question
 }
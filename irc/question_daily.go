@@ -0,0 +1,109 @@
+package irc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	"github.com/soulxburn/soulxbot/db"
+)
+
+// qotddaily
+// !qotddaily [on|off|<timezone>]
+// Shares one qotd between every stream on the same day, in the channel's timezone
+func (q *QuestionCommands) qotddaily(msgCtx MessageContext, command string, input string) {
+	if !msgCtx.IsBroadcaster() {
+		return
+	}
+
+	config := msgCtx.StreamUser
+	if len(input) == 0 {
+		if config.QotdDaily {
+			q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("QOTD daily mode is on, a new question every day at midnight %s", config.QotdTimezone))
+		} else {
+			q.ClientIRC.Say(msgCtx.Channel, "QOTD daily mode is off, every stream gets its own question")
+		}
+		return
+	}
+
+	daily, timezone := true, config.QotdTimezone
+	switch strings.ToLower(input) {
+	case "off":
+		daily = false
+	case "on":
+	default:
+		if _, err := time.LoadLocation(input); err != nil {
+			q.ClientIRC.Say(msgCtx.Channel, "Usage: !qotddaily [on|off|<timezone>], for example !qotddaily America/New_York")
+			return
+		}
+		timezone = input
+	}
+
+	if err := q.DataStore.UpdateQotdDaily(config.UserId, daily, timezone); err != nil {
+		return
+	}
+	if daily {
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("QOTD daily mode on, a new question every day at midnight %s", timezone))
+	} else {
+		q.ClientIRC.Say(msgCtx.Channel, "QOTD daily mode off, every stream gets its own question")
+	}
+}
+
+// DailyQuestionRollover posts the new question of the day at local midnight
+// for live channels in daily mode.
+type DailyQuestionRollover struct {
+	DataStore *db.Database
+	ClientIRC *twitchirc.Client
+}
+
+// NewDailyQuestionRollover
+func NewDailyQuestionRollover(dataStore *db.Database, clientIRC *twitchirc.Client) *DailyQuestionRollover {
+	return &DailyQuestionRollover{
+		DataStore: dataStore,
+		ClientIRC: clientIRC,
+	}
+}
+
+// RunStreamTask
+// Waits for each local midnight until the stream ends.
+// The config is read again every day, so timezone changes apply from the next midnight.
+func (r *DailyQuestionRollover) RunStreamTask(ctx context.Context, stream *db.Stream, streamUser *db.User) {
+	for {
+		config, err := r.DataStore.FindStreamUserByUserID(streamUser.ID)
+		if err != nil || config == nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(untilMidnight(time.Now(), config.QotdLocation())):
+			r.post(stream.ID, streamUser.ID)
+		}
+	}
+}
+
+func (r *DailyQuestionRollover) post(streamId int, userId int) {
+	streamUser, err := r.DataStore.FindStreamUserByUserID(userId)
+	if err != nil || streamUser == nil || !streamUser.QotdEnabled || !streamUser.QotdDaily || streamUser.BotDisabled {
+		return
+	}
+	stream := r.DataStore.FindStreamById(streamId)
+	if stream == nil || stream.EndedAt != nil {
+		return
+	}
+
+	question := questionOfTheDay(r.DataStore, streamUser, stream)
+	if question != nil && question.ID != 0 {
+		r.ClientIRC.Say(streamUser.Username, fmt.Sprintf("It's a new day! Question of the day: %s", question.Text))
+	}
+}
+
+// untilMidnight
+// Returns the time left until the next midnight in the location
+func untilMidnight(now time.Time, location *time.Location) time.Duration {
+	year, month, day := now.In(location).Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, location).Sub(now)
+}
@@ -0,0 +1,109 @@
+package irc
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// How long a vote to skip the qotd stays open after the first vote
+const SKIP_VOTE_WINDOW = 2 * time.Minute
+
+type skipVote struct {
+	questionId int
+	expiresAt  time.Time
+	voters     map[int]bool
+}
+
+// skipvote
+// Any viewer may vote to skip the qotd, it is skipped once enough of the active chatters agree.
+func (q *QuestionCommands) skipvote(msgCtx MessageContext, command string, input string) {
+	if msgCtx.Stream == nil ||
+		msgCtx.Stream.QOTDId == nil ||
+		msgCtx.StreamUser == nil ||
+		msgCtx.MessageUser == nil ||
+		!msgCtx.StreamUser.QotdEnabled ||
+		msgCtx.StreamUser.QotdSkipVoteShare <= 0 {
+		return
+	}
+
+	required := q.requiredSkipVotes(msgCtx)
+
+	q.mu.Lock()
+	if q.skipVotes == nil {
+		q.skipVotes = make(map[int]*skipVote)
+	}
+	vote, ok := q.skipVotes[msgCtx.Stream.ID]
+	isNewVote := !ok || time.Now().After(vote.expiresAt) || vote.questionId != *msgCtx.Stream.QOTDId
+	if isNewVote {
+		vote = &skipVote{
+			questionId: *msgCtx.Stream.QOTDId,
+			expiresAt:  time.Now().Add(SKIP_VOTE_WINDOW),
+			voters:     make(map[int]bool),
+		}
+		q.skipVotes[msgCtx.Stream.ID] = vote
+	}
+	if vote.voters[msgCtx.MessageUser.ID] {
+		q.mu.Unlock()
+		return
+	}
+	vote.voters[msgCtx.MessageUser.ID] = true
+	votes := len(vote.voters)
+	if votes >= required {
+		delete(q.skipVotes, msgCtx.Stream.ID)
+	}
+	q.mu.Unlock()
+
+	switch {
+	case votes >= required:
+		q.skipQuestion(msgCtx)
+	case isNewVote:
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf(
+			"%s started a vote to skip the question of the day! Type !skipvote within %d minutes to agree (%d/%d)",
+			msgCtx.MessageUser.DisplayName, int(SKIP_VOTE_WINDOW.Minutes()), votes, required))
+	default:
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s voted to skip the question of the day (%d/%d)",
+			msgCtx.MessageUser.DisplayName, votes, required))
+	}
+}
+
+// requiredSkipVotes
+// Number of votes needed to skip, based on the channel's share of recent active chatters
+func (q *QuestionCommands) requiredSkipVotes(msgCtx MessageContext) int {
+	active := 1
+	if q.Chatters != nil {
+		active = q.Chatters.Count(msgCtx.Channel)
+	}
+	required := int(math.Ceil(float64(active) * float64(msgCtx.StreamUser.QotdSkipVoteShare) / 100))
+	if required < 1 {
+		required = 1
+	}
+	return required
+}
+
+// skipvoteshare
+// !skipvoteshare <percent>
+// Sets the percent of active chatters that must vote to skip the qotd, zero disables !skipvote.
+func (q *QuestionCommands) skipvoteshare(msgCtx MessageContext, command string, input string) {
+	if !msgCtx.IsBroadcaster() {
+		return
+	}
+
+	if len(input) == 0 {
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%d%% of active chatters must !skipvote to skip the question of the day",
+			msgCtx.StreamUser.QotdSkipVoteShare))
+		return
+	}
+
+	share, err := strconv.Atoi(input)
+	if err != nil || share < 0 || share > 100 {
+		q.ClientIRC.Say(msgCtx.Channel, "Usage: !skipvoteshare <percent 0-100>")
+		return
+	}
+
+	if err := q.DataStore.UpdateQotdSkipVoteShare(msgCtx.StreamUser.UserId, share); err != nil {
+		return
+	}
+	q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Skip vote share set to %d%%", share))
+}
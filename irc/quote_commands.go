@@ -0,0 +1,202 @@
+package irc
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/soulxburn/soulxbot/twitch"
+)
+
+// The longest quote that can be added, so it fits in a chat message with its details
+const QUOTE_MAX_LENGTH = 400
+
+// QuoteCommands keeps memorable lines from each channel, with the game and stream title when they were said
+type QuoteCommands struct {
+	DataStore *db.Database
+	ClientIRC *twitchirc.Client
+	TwitchAPI twitch.ITwitchAPI
+}
+
+func (q *QuoteCommands) GetCommands() []Command {
+	commands := []Command{
+		{"quote", q.quote},
+	}
+	return commands
+}
+
+// quote
+// !quote [<number>]
+// !quote add "text" [@username]
+// !quote search <words>
+// !quote del <number>
+func (q *QuoteCommands) quote(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if msgCtx.StreamUser == nil {
+		return
+	}
+	action, rest, _ := strings.Cut(input, " ")
+	rest = strings.TrimSpace(rest)
+	switch strings.ToLower(action) {
+	case "":
+		q.random(msgCtx)
+	case "add":
+		q.add(msgCtx, rest)
+	case "search":
+		q.search(msgCtx, rest)
+	case "del":
+		q.delete(msgCtx, rest)
+	default:
+		number, err := strconv.Atoi(strings.TrimPrefix(action, "#"))
+		if err != nil {
+			q.ClientIRC.Say(msgCtx.Channel, "Usage: !quote [number], !quote add \"text\" [@username], or !quote search <words>")
+			return
+		}
+		q.show(msgCtx, number)
+	}
+}
+
+func (q *QuoteCommands) random(msgCtx MessageContext) {
+	quote, err := q.DataStore.FindRandomQuote(msgCtx.StreamUser.UserId)
+	if err != nil {
+		return
+	}
+	if quote == nil {
+		q.ClientIRC.Say(msgCtx.Channel, "There are no quotes yet, add one with !quote add \"text\"")
+		return
+	}
+	q.ClientIRC.Say(msgCtx.Channel, formatQuote(*quote))
+}
+
+func (q *QuoteCommands) show(msgCtx MessageContext, number int) {
+	quote, err := q.DataStore.FindQuote(msgCtx.StreamUser.UserId, number)
+	if err != nil {
+		return
+	}
+	if quote == nil {
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("There's no quote #%d", number))
+		return
+	}
+	q.ClientIRC.Say(msgCtx.Channel, formatQuote(*quote))
+}
+
+// add
+// Quotes the broadcaster unless someone else is named, keeping the game and title the stream has now
+func (q *QuoteCommands) add(msgCtx MessageContext, input string) {
+	text, quotee := parseQuote(input)
+	if text == "" || len([]rune(text)) > QUOTE_MAX_LENGTH {
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Usage: !quote add \"text\" [@username], up to %d characters", QUOTE_MAX_LENGTH))
+		return
+	}
+	if quotee == "" {
+		quotee = msgCtx.StreamUser.DisplayName
+	}
+
+	quote := db.Quote{
+		ChannelId: msgCtx.StreamUser.UserId,
+		Text:      text,
+		Quotee:    quotee,
+	}
+	if msgCtx.MessageUser != nil {
+		quote.AddedBy = &msgCtx.MessageUser.ID
+	}
+	if msgCtx.Stream != nil {
+		quote.StreamId = &msgCtx.Stream.ID
+		quote.StreamTitle = msgCtx.Stream.Title
+	}
+	streamInfo, err := q.TwitchAPI.GetStream(msgCtx.StreamUser.Username)
+	if err != nil {
+		log.Printf("Unable to get the game for a quote in %s: %v", msgCtx.Channel, err)
+	} else if streamInfo != nil {
+		if streamInfo.GameName != "" {
+			quote.Game = &streamInfo.GameName
+		}
+		if quote.StreamTitle == nil {
+			quote.StreamTitle = &streamInfo.Title
+		}
+	}
+
+	inserted, err := q.DataStore.InsertQuote(quote)
+	if err != nil {
+		return
+	}
+	q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Added quote #%d", inserted.Number))
+}
+
+// parseQuote
+// Reads the quote's text, in quotes or not, and who said it when they're named after the text,
+// as "text" @username, "text" - name or text @username
+func parseQuote(input string) (string, string) {
+	input = strings.TrimSpace(input)
+	var text, rest string
+	if quoted, ok := strings.CutPrefix(input, "\""); ok {
+		text, rest, _ = strings.Cut(quoted, "\"")
+	} else {
+		text = input
+		if i := strings.LastIndex(input, " @"); i >= 0 && !strings.Contains(input[i+2:], " ") {
+			text, rest = input[:i], input[i+1:]
+		}
+	}
+
+	quotee := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), "-"))
+	quotee = strings.TrimPrefix(quotee, "@")
+	return strings.TrimSpace(text), quotee
+}
+
+func (q *QuoteCommands) search(msgCtx MessageContext, input string) {
+	words := strings.Fields(input)
+	if len(words) == 0 {
+		q.ClientIRC.Say(msgCtx.Channel, "Usage: !quote search <words>")
+		return
+	}
+	quotes, err := q.DataStore.SearchQuotes(msgCtx.StreamUser.UserId, words)
+	if err != nil {
+		return
+	}
+
+	switch len(quotes) {
+	case 0:
+		q.ClientIRC.Say(msgCtx.Channel, "No quotes found")
+	case 1:
+		q.ClientIRC.Say(msgCtx.Channel, formatQuote(quotes[0]))
+	default:
+		numbers := make([]string, len(quotes))
+		for i, quote := range quotes {
+			numbers[i] = fmt.Sprintf("#%d", quote.Number)
+		}
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Quotes found: %s", strings.Join(numbers, ", ")))
+	}
+}
+
+func (q *QuoteCommands) delete(msgCtx MessageContext, input string) {
+	if !msgCtx.IsModerator() {
+		return
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(input, "#"))
+	if err != nil {
+		q.ClientIRC.Say(msgCtx.Channel, "Usage: !quote del <number>")
+		return
+	}
+	deleted, err := q.DataStore.DeleteQuote(msgCtx.StreamUser.UserId, number)
+	if err != nil {
+		return
+	}
+	if !deleted {
+		q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("There's no quote #%d", number))
+		return
+	}
+	q.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Deleted quote #%d", number))
+}
+
+// formatQuote
+// #12: "text" - Quotee [Game] 2024-05-01
+func formatQuote(quote db.Quote) string {
+	message := fmt.Sprintf("#%d: \"%s\" - %s", quote.Number, quote.Text, quote.Quotee)
+	if quote.Game != nil {
+		message += fmt.Sprintf(" [%s]", *quote.Game)
+	}
+	return message + " " + quote.CreatedAt.Format("2006-01-02")
+}
@@ -0,0 +1,592 @@
+package irc
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/soulxburn/soulxbot/twitch"
+)
+
+// Seconds a raffle winner has to reply in chat to claim the prize
+const RAFFLE_REPLY_SECONDS = 60
+
+// RaffleCommands runs giveaways. Mods open a raffle with a keyword, viewers enter by typing it or !join,
+// and winners are drawn with subscribers getting an extra entry for each tier.
+// Raffles, entries and winners are kept in the database so every draw can be audited.
+type RaffleCommands struct {
+	DataStore *db.Database
+	ClientIRC *twitchirc.Client
+	TwitchAPI twitch.ITwitchAPI
+	// The running raffle in each channel, by channel user id
+	raffles map[int]*activeRaffle
+	mu      sync.Mutex
+}
+
+// activeRaffle is a raffle taking entries or being drawn, and the winner waiting to reply
+type activeRaffle struct {
+	raffle     db.Raffle
+	channel    string
+	closeTimer *time.Timer
+	winner     *db.RaffleWinner
+	winnerName string
+	replyTimer *time.Timer
+	// Chatters checked for a follower-only raffle, so each is only looked up once
+	follows map[int]followCheck
+}
+
+// followCheck is whether a chatter follows the channel, or if it's still being looked up
+type followCheck int
+
+const (
+	followUnknown followCheck = iota
+	followChecking
+	following
+	notFollowing
+)
+
+// NewRaffleCommands
+// Picks up the raffles that were running when the bot was last shut down
+func NewRaffleCommands(dataStore *db.Database, clientIRC *twitchirc.Client, twitchAPI twitch.ITwitchAPI) *RaffleCommands {
+	r := &RaffleCommands{
+		DataStore: dataStore,
+		ClientIRC: clientIRC,
+		TwitchAPI: twitchAPI,
+		raffles:   make(map[int]*activeRaffle),
+	}
+
+	raffles, err := dataStore.FindActiveRaffles()
+	if err != nil {
+		return r
+	}
+	for _, raffle := range raffles {
+		channel, ok := dataStore.FindUserByID(raffle.ChannelId)
+		if !ok {
+			continue
+		}
+		active := &activeRaffle{raffle: raffle, channel: channel.Username}
+		r.raffles[raffle.ChannelId] = active
+		if raffle.Status == db.RAFFLE_OPEN && raffle.ClosesAt != nil {
+			r.scheduleClose(active, time.Until(*raffle.ClosesAt))
+		}
+		r.restoreWinner(active)
+	}
+	return r
+}
+
+// restoreWinner
+// Gives the last winner drawn the rest of their time to claim the prize.
+// Earlier winners still waiting, or a winner whose time ran out while the bot was down, missed it.
+func (r *RaffleCommands) restoreWinner(active *activeRaffle) {
+	winners, err := r.DataStore.FindRaffleWinners(active.raffle.ID)
+	if err != nil {
+		return
+	}
+	for i, winner := range winners {
+		if winner.Status != db.RAFFLE_WINNER_PENDING {
+			continue
+		}
+		remaining := time.Until(winner.DrawnAt.Add(RAFFLE_REPLY_SECONDS * time.Second))
+		user, ok := r.DataStore.FindUserByID(winner.UserId)
+		if i < len(winners)-1 || remaining <= 0 || !ok {
+			r.DataStore.UpdateRaffleWinnerStatus(winner.ID, db.RAFFLE_WINNER_MISSED)
+			continue
+		}
+		r.waitForClaim(active, &winners[i], user.DisplayName, remaining)
+	}
+}
+
+func (r *RaffleCommands) GetCommands() []Command {
+	commands := []Command{
+		{"raffle", r.raffle},
+		{"join", r.join},
+	}
+	return commands
+}
+
+// raffle
+// !raffle [open <keyword> [duration] [subs] [followers] [minwatch=<minutes>]|close|draw|reroll|cancel]
+func (r *RaffleCommands) raffle(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if msgCtx.StreamUser == nil || msgCtx.MessageUser == nil {
+		return
+	}
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		r.status(msgCtx)
+		return
+	}
+	if !msgCtx.IsModerator() {
+		return
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "open":
+		r.open(msgCtx, fields[1:])
+	case "close":
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if active, ok := r.raffles[msgCtx.StreamUser.UserId]; ok && active.raffle.Status == db.RAFFLE_OPEN {
+			r.closeRaffle(active)
+		}
+	case "draw":
+		r.draw(msgCtx, false)
+	case "reroll":
+		r.draw(msgCtx, true)
+	case "cancel":
+		r.cancel(msgCtx)
+	default:
+		r.ClientIRC.Say(msgCtx.Channel, "Usage: !raffle [open <keyword> [duration] [subs] [followers] [minwatch=<minutes>]|close|draw|reroll|cancel]")
+	}
+}
+
+// status
+// Tells chat how to enter the running raffle
+func (r *RaffleCommands) status(msgCtx MessageContext) {
+	r.mu.Lock()
+	active, ok := r.raffles[msgCtx.StreamUser.UserId]
+	var raffle db.Raffle
+	if ok {
+		raffle = active.raffle
+	}
+	r.mu.Unlock()
+
+	if !ok || raffle.Status != db.RAFFLE_OPEN {
+		r.ClientIRC.Say(msgCtx.Channel, "There is no raffle open right now")
+		return
+	}
+	entries, err := r.DataStore.FindRaffleEntries(raffle.ID)
+	if err != nil {
+		return
+	}
+	r.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Type %s or !join to enter the raffle%s. %d entered so far", raffle.Keyword, raffleRules(raffle), len(entries)))
+}
+
+// open
+// Starts a raffle, closing it after the duration if one is given
+func (r *RaffleCommands) open(msgCtx MessageContext, args []string) {
+	if len(args) == 0 {
+		r.ClientIRC.Say(msgCtx.Channel, "Usage: !raffle open <keyword> [duration] [subs] [followers] [minwatch=<minutes>]")
+		return
+	}
+	raffle := db.Raffle{
+		ChannelId: msgCtx.StreamUser.UserId,
+		Keyword:   strings.ToLower(args[0]),
+		OpenedBy:  msgCtx.MessageUser.ID,
+	}
+	if msgCtx.Stream != nil {
+		raffle.StreamId = &msgCtx.Stream.ID
+	}
+
+	var duration time.Duration
+	for _, arg := range args[1:] {
+		arg = strings.ToLower(arg)
+		switch {
+		case arg == "subs":
+			raffle.SubOnly = true
+		case arg == "followers":
+			raffle.FollowerOnly = true
+		case strings.HasPrefix(arg, "minwatch="):
+			minutes, err := strconv.Atoi(strings.TrimPrefix(arg, "minwatch="))
+			if err != nil || minutes < 0 {
+				r.ClientIRC.Say(msgCtx.Channel, "minwatch is a number of minutes, like minwatch=30")
+				return
+			}
+			raffle.MinWatchMinutes = minutes
+		default:
+			parsed, ok := parseRaffleDuration(arg)
+			if !ok {
+				r.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Unknown raffle option %s", arg))
+				return
+			}
+			duration = parsed
+		}
+	}
+	if duration > 0 {
+		closesAt := time.Now().Add(duration)
+		raffle.ClosesAt = &closesAt
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.raffles[raffle.ChannelId]; ok {
+		r.ClientIRC.Say(msgCtx.Channel, "A raffle is already running, draw a winner or !raffle cancel it first")
+		return
+	}
+	inserted, err := r.DataStore.InsertRaffle(raffle)
+	if err != nil {
+		return
+	}
+	active := &activeRaffle{raffle: *inserted, channel: msgCtx.Channel}
+	r.raffles[raffle.ChannelId] = active
+	if duration > 0 {
+		r.scheduleClose(active, duration)
+	}
+
+	message := fmt.Sprintf("A raffle is open! Type %s or !join to enter%s", raffle.Keyword, raffleRules(raffle))
+	if duration > 0 {
+		message += fmt.Sprintf(". Entries close in %s", duration)
+	}
+	r.ClientIRC.Say(msgCtx.Channel, message)
+}
+
+// parseRaffleDuration
+// Reads a duration like 5m or 90s, or a number of seconds
+func parseRaffleDuration(input string) (time.Duration, bool) {
+	if seconds, err := strconv.Atoi(input); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	duration, err := time.ParseDuration(input)
+	if err != nil || duration <= 0 {
+		return 0, false
+	}
+	return duration, true
+}
+
+// raffleRules
+// Describes who can enter the raffle
+func raffleRules(raffle db.Raffle) string {
+	rules := []string{}
+	if raffle.SubOnly {
+		rules = append(rules, "subscribers only")
+	}
+	if raffle.FollowerOnly {
+		rules = append(rules, "followers only")
+	}
+	if raffle.MinWatchMinutes > 0 {
+		rules = append(rules, fmt.Sprintf("at least %d minutes watched", raffle.MinWatchMinutes))
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(rules, ", ") + ")"
+}
+
+// scheduleClose
+// Closes the raffle after the delay, unless it has already been closed
+func (r *RaffleCommands) scheduleClose(active *activeRaffle, delay time.Duration) {
+	active.closeTimer = time.AfterFunc(max(delay, 0), func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.raffles[active.raffle.ChannelId] == active && active.raffle.Status == db.RAFFLE_OPEN {
+			r.closeRaffle(active)
+		}
+	})
+}
+
+// closeRaffle
+// Stops taking entries. Callers must hold the lock.
+func (r *RaffleCommands) closeRaffle(active *activeRaffle) {
+	if active.closeTimer != nil {
+		active.closeTimer.Stop()
+	}
+	if err := r.DataStore.UpdateRaffleStatus(active.raffle.ID, db.RAFFLE_CLOSED); err != nil {
+		return
+	}
+	active.raffle.Status = db.RAFFLE_CLOSED
+
+	entries, _ := r.DataStore.FindRaffleEntries(active.raffle.ID)
+	r.ClientIRC.Say(active.channel, fmt.Sprintf("The raffle is closed with %d entries. Mods can draw a winner with !raffle draw", len(entries)))
+}
+
+// draw
+// Closes the raffle and draws a winner from the entries that haven't already been drawn.
+// Rerolling passes over a winner who hasn't claimed the prize yet.
+func (r *RaffleCommands) draw(msgCtx MessageContext, reroll bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	active, ok := r.raffles[msgCtx.StreamUser.UserId]
+	if !ok {
+		r.ClientIRC.Say(msgCtx.Channel, "There is no raffle to draw, start one with !raffle open <keyword>")
+		return
+	}
+	if active.winner != nil {
+		if !reroll {
+			r.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s still has time to claim the prize, or draw someone else with !raffle reroll", active.winnerName))
+			return
+		}
+		r.endWinner(active, db.RAFFLE_WINNER_REROLLED)
+	}
+	if active.raffle.Status == db.RAFFLE_OPEN {
+		r.closeRaffle(active)
+	}
+
+	entries, err := r.DataStore.FindRaffleEntries(active.raffle.ID)
+	if err != nil {
+		return
+	}
+	winners, err := r.DataStore.FindRaffleWinners(active.raffle.ID)
+	if err != nil {
+		return
+	}
+	drawn := make(map[int]bool)
+	for _, winner := range winners {
+		drawn[winner.UserId] = true
+	}
+
+	entry, ok := drawEntry(entries, drawn)
+	if !ok {
+		r.ClientIRC.Say(msgCtx.Channel, "There is nobody left to draw. Cancel the raffle with !raffle cancel")
+		return
+	}
+	winner, err := r.DataStore.InsertRaffleWinner(active.raffle.ID, entry.User.ID)
+	if err != nil {
+		return
+	}
+	r.waitForClaim(active, winner, entry.User.DisplayName, RAFFLE_REPLY_SECONDS*time.Second)
+
+	r.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("@%s won the raffle! Say something in chat in the next %d seconds to claim it",
+		entry.User.DisplayName, RAFFLE_REPLY_SECONDS))
+}
+
+// waitForClaim
+// Waits for the winner to reply, marking them as having missed the prize once the time runs out
+func (r *RaffleCommands) waitForClaim(active *activeRaffle, winner *db.RaffleWinner, winnerName string, timeout time.Duration) {
+	active.winner = winner
+	active.winnerName = winnerName
+	active.replyTimer = time.AfterFunc(timeout, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if active.winner != winner {
+			return
+		}
+		r.endWinner(active, db.RAFFLE_WINNER_MISSED)
+		r.ClientIRC.Say(active.channel, fmt.Sprintf("%s didn't reply in time. Mods can draw again with !raffle reroll", winnerName))
+	})
+}
+
+// drawEntry
+// Picks an entry at random, weighted by the number of entries each viewer has, skipping anyone already drawn
+func drawEntry(entries []db.RaffleEntry, drawn map[int]bool) (db.RaffleEntry, bool) {
+	eligible := []db.RaffleEntry{}
+	total := 0
+	for _, entry := range entries {
+		if !drawn[entry.User.ID] {
+			eligible = append(eligible, entry)
+			total += entry.Weight
+		}
+	}
+	if total <= 0 {
+		return db.RaffleEntry{}, false
+	}
+
+	pick := rand.Intn(total)
+	for _, entry := range eligible {
+		pick -= entry.Weight
+		if pick < 0 {
+			return entry, true
+		}
+	}
+	return eligible[len(eligible)-1], true
+}
+
+// endWinner
+// Stops waiting for the winner to reply. Callers must hold the lock.
+func (r *RaffleCommands) endWinner(active *activeRaffle, status string) {
+	if active.replyTimer != nil {
+		active.replyTimer.Stop()
+	}
+	r.DataStore.UpdateRaffleWinnerStatus(active.winner.ID, status)
+	active.winner = nil
+	active.winnerName = ""
+}
+
+// cancel
+// Ends the raffle without a winner
+func (r *RaffleCommands) cancel(msgCtx MessageContext) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	active, ok := r.raffles[msgCtx.StreamUser.UserId]
+	if !ok {
+		return
+	}
+	if active.closeTimer != nil {
+		active.closeTimer.Stop()
+	}
+	if active.winner != nil {
+		r.endWinner(active, db.RAFFLE_WINNER_REROLLED)
+	}
+	if err := r.DataStore.UpdateRaffleStatus(active.raffle.ID, db.RAFFLE_CANCELLED); err != nil {
+		return
+	}
+	delete(r.raffles, msgCtx.StreamUser.UserId)
+	r.ClientIRC.Say(msgCtx.Channel, "The raffle was cancelled")
+}
+
+// join
+// !join
+func (r *RaffleCommands) join(msgCtx MessageContext, command string, input string) {
+	if msgCtx.StreamUser == nil {
+		return
+	}
+	if raffle, ok := r.openRaffle(msgCtx.StreamUser.UserId); ok {
+		r.enter(msgCtx, raffle)
+	}
+}
+
+// OnMessage
+// Enters chatters who type the keyword, and lets the winner claim the prize by replying
+func (r *RaffleCommands) OnMessage(msgCtx MessageContext) {
+	if msgCtx.StreamUser == nil || msgCtx.MessageUser == nil {
+		return
+	}
+
+	r.mu.Lock()
+	active, ok := r.raffles[msgCtx.StreamUser.UserId]
+	if ok && active.winner != nil && active.winner.UserId == msgCtx.MessageUser.ID {
+		if err := r.DataStore.UpdateRaffleStatus(active.raffle.ID, db.RAFFLE_DRAWN); err == nil {
+			r.endWinner(active, db.RAFFLE_WINNER_CLAIMED)
+			delete(r.raffles, msgCtx.StreamUser.UserId)
+			r.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Congratulations %s, the prize is yours!", msgCtx.MessageUser.DisplayName))
+		}
+		r.mu.Unlock()
+		return
+	}
+	r.mu.Unlock()
+
+	raffle, ok := r.openRaffle(msgCtx.StreamUser.UserId)
+	if ok && strings.EqualFold(strings.TrimSpace(msgCtx.Message), raffle.Keyword) {
+		r.enter(msgCtx, raffle)
+	}
+}
+
+func (r *RaffleCommands) openRaffle(channelId int) (db.Raffle, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	active, ok := r.raffles[channelId]
+	if !ok || active.raffle.Status != db.RAFFLE_OPEN {
+		return db.Raffle{}, false
+	}
+	return active.raffle, true
+}
+
+// enter
+// Enters the chatter in the raffle if they can. Chatters aren't told when they can't enter,
+// so a busy raffle doesn't flood chat.
+// Followers are looked up on Twitch away from the chat callback, once per chatter each raffle.
+func (r *RaffleCommands) enter(msgCtx MessageContext, raffle db.Raffle) {
+	user := msgCtx.MessageUser
+	if user == nil || msgCtx.isOwner() || r.DataStore.HasRaffleEntry(raffle.ID, user.ID) {
+		return
+	}
+	if r.DataStore.IsUserOnExclusionList(&raffle.ChannelId, user.Username) {
+		return
+	}
+	tier := subscriberTier(msgCtx.Badges)
+	if raffle.SubOnly && tier == 0 {
+		return
+	}
+	if !raffle.FollowerOnly {
+		r.enterWatcher(raffle, user, tier)
+		return
+	}
+
+	switch r.followCheck(raffle, user.ID) {
+	case following:
+		r.enterWatcher(raffle, user, tier)
+	case followUnknown:
+		go r.enterFollower(*msgCtx.StreamUser, raffle, user, tier)
+	}
+}
+
+// followCheck
+// Returns what's known about the chatter following the channel. When it's unknown, they're marked
+// as being checked so only the caller looks them up.
+func (r *RaffleCommands) followCheck(raffle db.Raffle, userId int) followCheck {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	active, ok := r.raffles[raffle.ChannelId]
+	if !ok || active.raffle.ID != raffle.ID {
+		return notFollowing
+	}
+	if active.follows == nil {
+		active.follows = make(map[int]followCheck)
+	}
+	check := active.follows[userId]
+	if check == followUnknown {
+		active.follows[userId] = followChecking
+	}
+	return check
+}
+
+// setFollowCheck
+// Remembers if the chatter follows the channel, or forgets them when unknown so they're checked again
+func (r *RaffleCommands) setFollowCheck(raffle db.Raffle, userId int, check followCheck) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	active, ok := r.raffles[raffle.ChannelId]
+	if !ok || active.raffle.ID != raffle.ID || active.follows == nil {
+		return
+	}
+	if check == followUnknown {
+		delete(active.follows, userId)
+		return
+	}
+	active.follows[userId] = check
+}
+
+// enterFollower
+// Looks up if the chatter follows the channel, then enters them if the raffle is still open
+func (r *RaffleCommands) enterFollower(streamUser db.StreamUser, raffle db.Raffle, user *db.User, tier int) {
+	follower, err := r.TwitchAPI.IsFollower(streamUser, user.ID)
+	if err != nil {
+		log.Printf("Unable to check if %s follows %s: %v", user.Username, streamUser.Username, err)
+		r.setFollowCheck(raffle, user.ID, followUnknown)
+		return
+	}
+	if !follower {
+		r.setFollowCheck(raffle, user.ID, notFollowing)
+		return
+	}
+	r.setFollowCheck(raffle, user.ID, following)
+	if open, ok := r.openRaffle(raffle.ChannelId); ok && open.ID == raffle.ID {
+		r.enterWatcher(raffle, user, tier)
+	}
+}
+
+// enterWatcher
+// Enters the chatter if they've watched the channel long enough for the raffle
+func (r *RaffleCommands) enterWatcher(raffle db.Raffle, user *db.User, tier int) {
+	if raffle.MinWatchMinutes > 0 {
+		streams, err := r.DataStore.FindUserWatchTime(user.ID, &raffle.ChannelId)
+		if err != nil {
+			return
+		}
+		seconds := 0
+		for _, stream := range streams {
+			seconds += stream.Seconds
+		}
+		if seconds < raffle.MinWatchMinutes*60 {
+			return
+		}
+	}
+
+	r.DataStore.InsertRaffleEntry(raffle.ID, user.ID, tier, 1+tier)
+}
+
+// subscriberTier
+// Reads the chatter's subscriber tier from their badges, zero if they aren't subscribed.
+// Tier 2 and 3 subscriber badge versions start at 2000 and 3000.
+func subscriberTier(badges map[string]int) int {
+	version, ok := badges["subscriber"]
+	if !ok {
+		if _, founder := badges["founder"]; founder {
+			return 1
+		}
+		return 0
+	}
+	switch {
+	case version >= 3000:
+		return 3
+	case version >= 2000:
+		return 2
+	default:
+		return 1
+	}
+}
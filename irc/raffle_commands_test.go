@@ -0,0 +1,52 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriberTier(t *testing.T) {
+	for _, test := range []struct {
+		badges map[string]int
+		tier   int
+	}{
+		{nil, 0},
+		{map[string]int{"moderator": 1}, 0},
+		{map[string]int{"subscriber": 0}, 1},
+		{map[string]int{"subscriber": 12}, 1},
+		{map[string]int{"subscriber": 2006}, 2},
+		{map[string]int{"subscriber": 3024}, 3},
+		{map[string]int{"founder": 0}, 1},
+		{map[string]int{"founder": 0, "subscriber": 3000}, 3},
+	} {
+		assert.Equal(t, test.tier, subscriberTier(test.badges), "%v", test.badges)
+	}
+}
+
+func TestDrawEntry(t *testing.T) {
+	entries := []db.RaffleEntry{
+		{User: db.User{ID: 1}, Weight: 1},
+		{User: db.User{ID: 2}, Weight: 3},
+	}
+
+	draws := map[int]int{}
+	for i := 0; i < 4000; i++ {
+		entry, ok := drawEntry(entries, map[int]bool{})
+		assert.True(t, ok)
+		draws[entry.User.ID]++
+	}
+	assert.InDelta(t, 3000, draws[2], 300, "an entry with 3 tickets is drawn about 3 times as often")
+
+	for i := 0; i < 100; i++ {
+		entry, ok := drawEntry(entries, map[int]bool{2: true})
+		assert.True(t, ok)
+		assert.Equal(t, 1, entry.User.ID, "viewers already drawn are skipped")
+	}
+
+	_, ok := drawEntry(entries, map[int]bool{1: true, 2: true})
+	assert.False(t, ok, "nobody is left to draw")
+	_, ok = drawEntry(nil, map[int]bool{})
+	assert.False(t, ok)
+}
@@ -0,0 +1,55 @@
+package irc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/soulxburn/soulxbot/db"
+)
+
+// StreamTask is run by the StreamScheduler for as long as a stream is live.
+// The context is cancelled once the stream has ended.
+type StreamTask interface {
+	RunStreamTask(ctx context.Context, stream *db.Stream, streamUser *db.User)
+}
+
+// StreamScheduler starts each of its tasks when a stream goes live,
+// and stops them when the stream poller sees the stream end.
+type StreamScheduler struct {
+	tasks   []StreamTask
+	running map[int]context.CancelFunc
+	mu      sync.Mutex
+}
+
+// NewStreamScheduler
+func NewStreamScheduler(tasks ...StreamTask) *StreamScheduler {
+	return &StreamScheduler{
+		tasks:   tasks,
+		running: make(map[int]context.CancelFunc),
+	}
+}
+
+// OnStreamStart
+func (s *StreamScheduler) OnStreamStart(stream *db.Stream, streamUser *db.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.running[stream.ID]; ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.running[stream.ID] = cancel
+	for _, task := range s.tasks {
+		go task.RunStreamTask(ctx, stream, streamUser)
+	}
+}
+
+// OnStreamEnd
+func (s *StreamScheduler) OnStreamEnd(stream *db.Stream, streamUser *db.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.running[stream.ID]; ok {
+		cancel()
+		delete(s.running, stream.ID)
+	}
+}
@@ -1,10 +1,13 @@
 package irc
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	twitchirc "github.com/gempir/go-twitch-irc/v2"
@@ -12,10 +15,51 @@ import (
 	"github.com/soulxburn/soulxbot/twitch"
 )
 
+// Seconds the broadcaster has to confirm a snap
+const THANOS_CONFIRM_SECONDS = 30
+
+// Twitch timeouts last at most two weeks
+const THANOS_MAX_DURATION = 14 * 24 * 60 * 60
+
+// Timeouts are spaced out to stay under twitch's rate limits. When twitch rate limits
+// a timeout anyway, the snap waits for the limit to reset, unless that would take too long.
+const (
+	THANOS_TIMEOUT_INTERVAL    = 300 * time.Millisecond
+	THANOS_MAX_RATE_LIMIT_WAIT = time.Minute
+)
+
+// ThanosCommand times out a random half of chat. The broadcaster turns it on for their channel,
+// and must confirm each snap. Mods, VIPs, the broadcaster and bots are never snapped.
 type ThanosCommand struct {
-	DataStore *db.Database
-	ClientIRC *twitchirc.Client
-	TwitchAPI twitch.ITwitchAPI
+	DataStore   *db.Database
+	ClientIRC   *twitchirc.Client
+	TwitchAPI   twitch.ITwitchAPI
+	BotUsername string
+	// Snaps waiting to be confirmed, and channels being snapped
+	pending  map[string]*thanosSnap
+	snapping map[string]bool
+	mu       sync.Mutex
+}
+
+// thanosSnap is the chatters chosen to be timed out
+type thanosSnap struct {
+	chosen    []string
+	chatters  int
+	spared    int
+	duration  int
+	expiresAt time.Time
+}
+
+// NewThanosCommand
+func NewThanosCommand(dataStore *db.Database, clientIRC *twitchirc.Client, twitchAPI twitch.ITwitchAPI, botUsername string) *ThanosCommand {
+	return &ThanosCommand{
+		DataStore:   dataStore,
+		ClientIRC:   clientIRC,
+		TwitchAPI:   twitchAPI,
+		BotUsername: strings.ToLower(botUsername),
+		pending:     make(map[string]*thanosSnap),
+		snapping:    make(map[string]bool),
+	}
 }
 
 func (t *ThanosCommand) GetCommands() []Command {
@@ -25,48 +69,248 @@ func (t *ThanosCommand) GetCommands() []Command {
 	return commands
 }
 
+// thanos
+// !thanos [preview|confirm|cancel|on [seconds]|off]
 func (t *ThanosCommand) thanos(msgCtx MessageContext, command string, input string) {
-	if !IsSouLxBurN(msgCtx.MessageUser.DisplayName) && !IsSouLxBurN(msgCtx.Channel) {
+	input = msgCtx.FullInput()
+	if msgCtx.StreamUser == nil {
+		return
+	}
+	if !msgCtx.IsBroadcaster() {
 		t.ClientIRC.Say(msgCtx.Channel, "You are not Thanos")
 		return
 	}
-	usernames, err := t.ClientIRC.Userlist(msgCtx.Channel)
+
+	fields := strings.Fields(strings.ToLower(input))
+	subcommand := ""
+	if len(fields) > 0 {
+		subcommand = fields[0]
+	}
+	switch subcommand {
+	case "on":
+		t.enable(msgCtx, fields[1:])
+		return
+	case "off":
+		if err := t.DataStore.UpdateThanos(msgCtx.StreamUser.UserId, false, msgCtx.StreamUser.ThanosDuration); err == nil {
+			t.ClientIRC.Say(msgCtx.Channel, "Thanos is off")
+		}
+		return
+	}
+
+	if !msgCtx.StreamUser.ThanosEnabled {
+		t.ClientIRC.Say(msgCtx.Channel, "Thanos is off in this channel, turn it on with !thanos on [seconds]")
+		return
+	}
+	switch subcommand {
+	case "":
+		t.prepare(msgCtx)
+	case "preview":
+		t.preview(msgCtx)
+	case "confirm":
+		t.confirm(msgCtx)
+	case "cancel":
+		t.mu.Lock()
+		delete(t.pending, strings.ToLower(msgCtx.Channel))
+		t.mu.Unlock()
+		t.ClientIRC.Say(msgCtx.Channel, "Thanos lowers the gauntlet")
+	default:
+		t.ClientIRC.Say(msgCtx.Channel, "Usage: !thanos [preview|confirm|cancel|on [seconds]|off]")
+	}
+}
+
+// enable
+// Turns thanos on, optionally changing how many seconds chatters are timed out for
+func (t *ThanosCommand) enable(msgCtx MessageContext, args []string) {
+	duration := msgCtx.StreamUser.ThanosDuration
+	if len(args) > 0 {
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil || parsed < 1 || parsed > THANOS_MAX_DURATION {
+			t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Usage: !thanos on [seconds 1-%d]", THANOS_MAX_DURATION))
+			return
+		}
+		duration = parsed
+	}
+	if err := t.DataStore.UpdateThanos(msgCtx.StreamUser.UserId, true, duration); err != nil {
+		return
+	}
+	t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Thanos is on, a snap times out half of chat for %d seconds", duration))
+}
+
+// preview
+// Says how many chatters a snap would time out, without choosing anyone
+func (t *ThanosCommand) preview(msgCtx MessageContext) {
+	snap, err := t.planSnap(msgCtx)
+	if err != nil {
+		t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Thanos can't see who is in chat: %v", err))
+		return
+	}
+	t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("A snap would time out %d of %d chatters for %d seconds. %d mods, VIPs and bots would be spared",
+		len(snap.chosen), snap.chatters, snap.duration, snap.spared))
+}
+
+// prepare
+// Chooses who will be snapped, and waits for the broadcaster to confirm
+func (t *ThanosCommand) prepare(msgCtx MessageContext) {
+	channel := strings.ToLower(msgCtx.Channel)
+	t.mu.Lock()
+	snapping := t.snapping[channel]
+	t.mu.Unlock()
+	if snapping {
+		t.ClientIRC.Say(msgCtx.Channel, "Thanos is already snapping")
+		return
+	}
+
+	snap, err := t.planSnap(msgCtx)
 	if err != nil {
-		log.Println("Thanos was unable to fetch the user list", err)
+		t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Thanos can't see who is in chat: %v", err))
 		return
 	}
+	if len(snap.chosen) == 0 {
+		t.ClientIRC.Say(msgCtx.Channel, "There is nobody for Thanos to snap")
+		return
+	}
+
+	t.mu.Lock()
+	t.pending[channel] = snap
+	t.mu.Unlock()
+	t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Thanos is ready to time out %d of %d chatters for %d seconds. Type !thanos confirm in the next %d seconds",
+		len(snap.chosen), snap.chatters, snap.duration, THANOS_CONFIRM_SECONDS))
+}
+
+// confirm
+// Snaps the chatters chosen by !thanos, if it was used recently enough
+func (t *ThanosCommand) confirm(msgCtx MessageContext) {
+	channel := strings.ToLower(msgCtx.Channel)
+	t.mu.Lock()
+	snap, ok := t.pending[channel]
+	delete(t.pending, channel)
+	if ok && time.Now().Before(snap.expiresAt) && !t.snapping[channel] {
+		t.snapping[channel] = true
+	} else {
+		ok = false
+	}
+	t.mu.Unlock()
+
+	if !ok {
+		t.ClientIRC.Say(msgCtx.Channel, "There is no snap to confirm, start one with !thanos")
+		return
+	}
+	t.ClientIRC.Say(msgCtx.Channel, "*SNAP*")
+	go func() {
+		t.snap(*msgCtx.StreamUser, msgCtx.Channel, snap)
+		t.mu.Lock()
+		delete(t.snapping, channel)
+		t.mu.Unlock()
+	}()
+}
 
-	for len(usernames) > 0 {
-		chunksize := len(usernames)
-		if chunksize > 100 {
-			chunksize = 100
+// planSnap
+// Chooses who to snap from the chatters in the channel
+func (t *ThanosCommand) planSnap(msgCtx MessageContext) (*thanosSnap, error) {
+	usernames, err := t.ClientIRC.Userlist(msgCtx.Channel)
+	if err != nil {
+		return nil, err
+	}
+	return t.chooseSnap(msgCtx, usernames)
+}
+
+// chooseSnap
+// Chooses a random half of the chatters who aren't mods, VIPs, the broadcaster or bots
+func (t *ThanosCommand) chooseSnap(msgCtx MessageContext, usernames []string) (*thanosSnap, error) {
+	exempt, err := t.exemptUsers(msgCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &thanosSnap{
+		chatters:  len(usernames),
+		duration:  msgCtx.StreamUser.ThanosDuration,
+		expiresAt: time.Now().Add(THANOS_CONFIRM_SECONDS * time.Second),
+	}
+	candidates := []string{}
+	for _, username := range usernames {
+		username = strings.ToLower(username)
+		if exempt[username] || t.DataStore.IsUserOnExclusionList(&msgCtx.StreamUser.UserId, username) {
+			snap.spared++
+			continue
 		}
-		userChunk := usernames[:chunksize]
-		usernames = usernames[chunksize:]
+		candidates = append(candidates, username)
+	}
 
-		userInfoList, err := t.TwitchAPI.GetUsers(userChunk)
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	snap.chosen = candidates[:(len(candidates)+1)/2]
+	return snap, nil
+}
+
+// exemptUsers
+// Returns the logins that can't be snapped. Fails rather than risk snapping mods or VIPs.
+func (t *ThanosCommand) exemptUsers(msgCtx MessageContext) (map[string]bool, error) {
+	exempt := map[string]bool{
+		strings.ToLower(msgCtx.Channel): true,
+		t.BotUsername:                   true,
+	}
+	if msgCtx.MessageUser != nil {
+		exempt[msgCtx.MessageUser.Username] = true
+	}
+	mods, err := t.TwitchAPI.GetModerators(*msgCtx.StreamUser)
+	if err != nil {
+		return nil, err
+	}
+	vips, err := t.TwitchAPI.GetVIPs(*msgCtx.StreamUser)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range append(mods, vips...) {
+		exempt[strings.ToLower(member.UserLogin)] = true
+	}
+	return exempt, nil
+}
+
+// snap
+// Times out the chosen chatters, then posts how it went
+func (t *ThanosCommand) snap(streamUser db.StreamUser, channel string, snap *thanosSnap) {
+	timedOut, failed := 0, 0
+	for start := 0; start < len(snap.chosen); start += TWITCH_USERS_PER_REQUEST {
+		end := min(start+TWITCH_USERS_PER_REQUEST, len(snap.chosen))
+		users, err := t.TwitchAPI.GetUsers(snap.chosen[start:end])
 		if err != nil {
+			log.Println("Thanos was unable to look up chatters: ", err)
+			failed += end - start
 			continue
 		}
+		failed += end - start - len(users)
 
-		rand.Shuffle(len(userInfoList), func(i, j int) { userInfoList[i], userInfoList[j] = userInfoList[j], userInfoList[i] })
-		theChosen := []*twitch.TwitchUserInfo{}
-
-		for i, usr := range userInfoList {
-			if i%2 == 0 {
-				theChosen = append(theChosen, usr)
+		for _, user := range users {
+			if err := t.timeout(streamUser, user, snap.duration); err != nil {
+				log.Printf("[%s] Unable to time out %s: %v", channel, user.Login, err)
+				failed++
+			} else {
+				timedOut++
 			}
+			time.Sleep(THANOS_TIMEOUT_INTERVAL)
 		}
+	}
 
-		for _, usr := range theChosen {
-			// AppCtx.TwitchAPI.TimeoutUser(fmt.Sprint(streamUser.User.ID), usr.Id, 60, "*SNAP*")
-			fmt.Printf("[%s]Timed Out: %s\n", msgCtx.Channel, usr.DisplayName)
-			time.Sleep(time.Millisecond * 200)
-		}
+	summary := fmt.Sprintf("%d chatters were timed out for %d seconds, %d mods, VIPs and bots were spared", timedOut, snap.duration, snap.spared)
+	if failed > 0 {
+		summary += fmt.Sprintf(". %d escaped", failed)
 	}
+	t.ClientIRC.Say(channel, summary)
 }
 
-// isSouLxBurN
-func IsSouLxBurN(username string) bool {
-	return strings.ToLower(username) == "soulxburn"
+// timeout
+// Times out the user, waiting for twitch's rate limit to reset and trying again if needed
+func (t *ThanosCommand) timeout(streamUser db.StreamUser, user *twitch.TwitchUserInfo, duration int) error {
+	err := t.TwitchAPI.TimeoutUser(streamUser, user.Id, duration, "*SNAP*")
+	var rateLimited *twitch.RateLimitError
+	if !errors.As(err, &rateLimited) {
+		return err
+	}
+
+	wait := time.Until(rateLimited.Reset)
+	if wait > THANOS_MAX_RATE_LIMIT_WAIT {
+		return err
+	}
+	time.Sleep(wait)
+	return t.TwitchAPI.TimeoutUser(streamUser, user.Id, duration, "*SNAP*")
 }
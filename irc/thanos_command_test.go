@@ -0,0 +1,99 @@
+package irc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/soulxburn/soulxbot/twitch"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTwitchAPI returns the channel's mods and VIPs, or fails to
+type fakeTwitchAPI struct {
+	twitch.ITwitchAPI
+	mods    []twitch.TwitchChannelMember
+	vips    []twitch.TwitchChannelMember
+	modsErr error
+	vipsErr error
+}
+
+func (f *fakeTwitchAPI) GetModerators(user db.StreamUser) ([]twitch.TwitchChannelMember, error) {
+	return f.mods, f.modsErr
+}
+
+func (f *fakeTwitchAPI) GetVIPs(user db.StreamUser) ([]twitch.TwitchChannelMember, error) {
+	return f.vips, f.vipsErr
+}
+
+func newTestDatabase(t *testing.T) *db.Database {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	t.Cleanup(func() { os.Chdir(wd) })
+	return db.InitDatabase()
+}
+
+func newTestThanos(t *testing.T, api *fakeTwitchAPI) (*ThanosCommand, MessageContext) {
+	dataStore := newTestDatabase(t)
+	channelId := 31568083
+	dataStore.InsertExcludedUser(&channelId, "lurkbot")
+	msgCtx := MessageContext{
+		Channel:     "soulxburn",
+		MessageUser: &db.User{ID: channelId, Username: "soulxburn"},
+		StreamUser:  &db.StreamUser{StreamConfig: db.StreamConfig{UserId: channelId, ThanosDuration: 60}},
+	}
+	return NewThanosCommand(dataStore, twitchirc.NewClient("soulxbot", "oauth"), api, "SouLxBot"), msgCtx
+}
+
+func TestChooseSnap(t *testing.T) {
+	api := &fakeTwitchAPI{
+		mods: []twitch.TwitchChannelMember{{UserLogin: "a_mod"}},
+		vips: []twitch.TwitchChannelMember{{UserLogin: "a_vip"}},
+	}
+	spared := []string{"soulxburn", "soulxbot", "a_mod", "a_vip", "lurkbot"}
+
+	for _, test := range []struct {
+		viewers int
+		chosen  int
+	}{
+		{0, 0},
+		{1, 1},
+		{2, 1},
+		{5, 3},
+		{10, 5},
+	} {
+		thanos, msgCtx := newTestThanos(t, api)
+		usernames := []string{"SouLxBurN", "soulxbot", "A_Mod", "a_vip", "lurkbot"}
+		for i := 0; i < test.viewers; i++ {
+			usernames = append(usernames, fmt.Sprintf("viewer%d", i))
+		}
+
+		snap, err := thanos.chooseSnap(msgCtx, usernames)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Len(t, snap.chosen, test.chosen, "%d viewers", test.viewers)
+		assert.Equal(t, len(usernames), snap.chatters)
+		assert.Equal(t, len(spared), snap.spared)
+		assert.Equal(t, 60, snap.duration)
+		for _, username := range spared {
+			assert.NotContains(t, snap.chosen, username)
+		}
+	}
+}
+
+func TestChooseSnapWithoutModsOrVIPs(t *testing.T) {
+	for _, api := range []*fakeTwitchAPI{
+		{modsErr: errors.New("twitch is down")},
+		{vipsErr: errors.New("twitch is down")},
+	} {
+		thanos, msgCtx := newTestThanos(t, api)
+		snap, err := thanos.chooseSnap(msgCtx, []string{"viewer1", "viewer2"})
+		assert.Error(t, err, "the snap is called off rather than risk timing out mods or VIPs")
+		assert.Nil(t, snap)
+	}
+}
@@ -0,0 +1,291 @@
+package irc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	"github.com/soulxburn/soulxbot/db"
+)
+
+// How often each channel's scheduler checks for a timed message to post
+const TIMER_CHECK_INTERVAL = 30 * time.Second
+
+// The least time between any two timed messages in a channel, so messages that are due
+// at the same time are posted one after another instead of all at once
+const TIMER_MIN_GAP = 2 * time.Minute
+
+// How much of each message !timer list shows
+const TIMER_LIST_MESSAGE_LENGTH = 40
+
+// TimerCommands posts each channel's timed messages, taking turns between them.
+// Messages are read from the database on each check, so changes made through the API are picked up.
+type TimerCommands struct {
+	DataStore *db.Database
+	ClientIRC *twitchirc.Client
+	// Each channel's scheduler, by channel name
+	Timers   map[string]*time.Timer
+	channels map[string]*channelTimers
+	mu       sync.Mutex
+}
+
+// channelTimers is what a channel's scheduler remembers between checks
+type channelTimers struct {
+	startedAt time.Time
+	// Lines said in chat since the scheduler started
+	lines        int
+	lastPostedAt time.Time
+	lastPostedId int
+	posts        map[int]timerPost
+}
+
+// timerPost is when a timed message was last posted, and how many lines had been said by then
+type timerPost struct {
+	at    time.Time
+	lines int
+}
+
+// NewTimerCommands
+// Schedulers are kept in timers, by channel name
+func NewTimerCommands(dataStore *db.Database, clientIRC *twitchirc.Client, timers map[string]*time.Timer) *TimerCommands {
+	return &TimerCommands{
+		DataStore: dataStore,
+		ClientIRC: clientIRC,
+		Timers:    timers,
+		channels:  make(map[string]*channelTimers),
+	}
+}
+
+func (t *TimerCommands) GetCommands() []Command {
+	commands := []Command{
+		{"timer", t.timer},
+	}
+	return commands
+}
+
+// Start
+// Starts the channel's scheduler, if it isn't already running
+func (t *TimerCommands) Start(channel string) {
+	channel = strings.ToLower(channel)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.Timers[channel]; ok {
+		return
+	}
+
+	state := &channelTimers{startedAt: time.Now(), posts: make(map[int]timerPost)}
+	t.channels[channel] = state
+	t.Timers[channel] = time.AfterFunc(TIMER_CHECK_INTERVAL, func() {
+		t.check(channel, state)
+	})
+}
+
+// Stop
+// Stops the channel's scheduler
+func (t *TimerCommands) Stop(channel string) {
+	channel = strings.ToLower(channel)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if timer, ok := t.Timers[channel]; ok {
+		timer.Stop()
+		delete(t.Timers, channel)
+		delete(t.channels, channel)
+	}
+}
+
+// OnJoin
+func (t *TimerCommands) OnJoin(channel string) {
+	t.Start(channel)
+}
+
+// OnPart
+func (t *TimerCommands) OnPart(channel string) {
+	t.Stop(channel)
+}
+
+// OnMessage
+// Counts the lines said in chat, for messages that wait for chat to be active
+func (t *TimerCommands) OnMessage(msgCtx MessageContext) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state, ok := t.channels[strings.ToLower(msgCtx.Channel)]; ok {
+		state.lines++
+	}
+}
+
+// check
+// Posts the channel's next timed message that is due, then waits for the next check
+func (t *TimerCommands) check(channel string, state *channelTimers) {
+	t.mu.Lock()
+	running := t.channels[channel] == state
+	t.mu.Unlock()
+	if !running {
+		return
+	}
+
+	t.postNext(channel)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.channels[channel] == state {
+		t.Timers[channel].Reset(TIMER_CHECK_INTERVAL)
+	}
+}
+
+func (t *TimerCommands) postNext(channel string) {
+	streamUser, err := t.DataStore.FindStreamUserByUserName(channel)
+	if err != nil || streamUser == nil || streamUser.BotDisabled {
+		return
+	}
+	messages, err := t.DataStore.FindTimedMessages(streamUser.UserId)
+	if err != nil || len(messages) == 0 {
+		return
+	}
+	live := t.DataStore.FindCurrentStream(streamUser.UserId) != nil
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.channels[channel]
+	now := time.Now()
+	if !ok || now.Sub(state.lastPostedAt) < TIMER_MIN_GAP {
+		return
+	}
+
+	// Take turns, starting after the last message posted
+	start := 0
+	for i, message := range messages {
+		if message.ID > state.lastPostedId {
+			start = i
+			break
+		}
+	}
+	for i := range messages {
+		message := messages[(start+i)%len(messages)]
+		if !state.isDue(message, live, now) {
+			continue
+		}
+		t.ClientIRC.Say(channel, message.Message)
+		state.lastPostedAt = now
+		state.lastPostedId = message.ID
+		state.posts[message.ID] = timerPost{at: now, lines: state.lines}
+		return
+	}
+}
+
+// isDue
+// Whether the message can be posted, based on its interval, the lines said since it was last posted and if the channel is live
+func (c *channelTimers) isDue(message db.TimedMessage, live bool, now time.Time) bool {
+	if (message.Mode == db.TIMED_MESSAGE_ONLINE && !live) || (message.Mode == db.TIMED_MESSAGE_OFFLINE && live) {
+		return false
+	}
+	post, ok := c.posts[message.ID]
+	if !ok {
+		post = timerPost{at: c.startedAt}
+	}
+	return now.Sub(post.at) >= time.Duration(message.IntervalMinutes)*time.Minute && c.lines-post.lines >= message.MinLines
+}
+
+// timer
+// !timer add <minutes> [lines=<count>] [online|offline] <message>
+// !timer remove <id>
+// !timer list
+func (t *TimerCommands) timer(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if msgCtx.StreamUser == nil || !msgCtx.IsModerator() {
+		return
+	}
+	action, rest, _ := strings.Cut(input, " ")
+	switch strings.ToLower(action) {
+	case "add":
+		t.add(msgCtx, strings.TrimSpace(rest))
+	case "remove":
+		t.remove(msgCtx, strings.TrimSpace(rest))
+	case "list":
+		t.list(msgCtx)
+	default:
+		t.ClientIRC.Say(msgCtx.Channel, "Usage: !timer add <minutes> [lines=<count>] [online|offline] <message>, !timer remove <id>, or !timer list")
+	}
+}
+
+func (t *TimerCommands) add(msgCtx MessageContext, input string) {
+	message := db.TimedMessage{ChannelId: msgCtx.StreamUser.UserId, Mode: db.TIMED_MESSAGE_ALWAYS}
+	if msgCtx.MessageUser != nil {
+		message.CreatedBy = &msgCtx.MessageUser.ID
+	}
+
+	fields := strings.Fields(input)
+	if len(fields) > 0 {
+		message.IntervalMinutes, _ = strconv.Atoi(fields[0])
+		fields = fields[1:]
+	}
+	for len(fields) > 0 {
+		option := strings.ToLower(fields[0])
+		if lines, ok := strings.CutPrefix(option, "lines="); ok {
+			message.MinLines, _ = strconv.Atoi(lines)
+		} else if option == db.TIMED_MESSAGE_ONLINE || option == db.TIMED_MESSAGE_OFFLINE {
+			message.Mode = option
+		} else {
+			break
+		}
+		fields = fields[1:]
+	}
+	message.Message = strings.Join(fields, " ")
+
+	if problem := db.ValidateTimedMessage(message); problem != "" {
+		t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Usage: !timer add <minutes> [lines=<count>] [online|offline] <message>, %s", problem))
+		return
+	}
+	inserted, err := t.DataStore.InsertTimedMessage(message)
+	if err != nil {
+		return
+	}
+	t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Added timer #%d, every %d minutes", inserted.ID, inserted.IntervalMinutes))
+}
+
+func (t *TimerCommands) remove(msgCtx MessageContext, input string) {
+	id, err := strconv.Atoi(strings.TrimPrefix(input, "#"))
+	if err != nil {
+		t.ClientIRC.Say(msgCtx.Channel, "Usage: !timer remove <id>")
+		return
+	}
+	deleted, err := t.DataStore.DeleteTimedMessage(msgCtx.StreamUser.UserId, id)
+	if err != nil {
+		return
+	}
+	if !deleted {
+		t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("There's no timer #%d", id))
+		return
+	}
+	t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Removed timer #%d", id))
+}
+
+func (t *TimerCommands) list(msgCtx MessageContext) {
+	messages, err := t.DataStore.FindTimedMessages(msgCtx.StreamUser.UserId)
+	if err != nil {
+		return
+	}
+	if len(messages) == 0 {
+		t.ClientIRC.Say(msgCtx.Channel, "There are no timers, add one with !timer add <minutes> <message>")
+		return
+	}
+
+	timers := make([]string, len(messages))
+	for i, message := range messages {
+		text := message.Message
+		if runes := []rune(text); len(runes) > TIMER_LIST_MESSAGE_LENGTH {
+			text = string(runes[:TIMER_LIST_MESSAGE_LENGTH]) + "..."
+		}
+		details := fmt.Sprintf("%dm", message.IntervalMinutes)
+		if message.MinLines > 0 {
+			details += fmt.Sprintf(", %d lines", message.MinLines)
+		}
+		if message.Mode != db.TIMED_MESSAGE_ALWAYS {
+			details += ", " + message.Mode
+		}
+		timers[i] = fmt.Sprintf("#%d (%s) %s", message.ID, details, text)
+	}
+	t.ClientIRC.Say(msgCtx.Channel, strings.Join(timers, " | "))
+}
@@ -0,0 +1,44 @@
+package irc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimedMessageIsDue(t *testing.T) {
+	startedAt := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	every10 := db.TimedMessage{ID: 1, IntervalMinutes: 10, Mode: db.TIMED_MESSAGE_ALWAYS}
+	chatty := db.TimedMessage{ID: 2, IntervalMinutes: 10, MinLines: 5, Mode: db.TIMED_MESSAGE_ALWAYS}
+	online := db.TimedMessage{ID: 3, IntervalMinutes: 10, Mode: db.TIMED_MESSAGE_ONLINE}
+	offline := db.TimedMessage{ID: 4, IntervalMinutes: 10, Mode: db.TIMED_MESSAGE_OFFLINE}
+
+	for _, test := range []struct {
+		name    string
+		message db.TimedMessage
+		posts   map[int]timerPost
+		lines   int
+		live    bool
+		after   time.Duration
+		due     bool
+	}{
+		{"waits for the interval after starting", every10, nil, 0, true, 9 * time.Minute, false},
+		{"is due once the interval passes", every10, nil, 0, true, 10 * time.Minute, true},
+		{"waits for the interval after posting", every10, map[int]timerPost{1: {at: startedAt.Add(5 * time.Minute)}}, 0, true, 14 * time.Minute, false},
+		{"is due the interval after posting", every10, map[int]timerPost{1: {at: startedAt.Add(5 * time.Minute)}}, 0, true, 15 * time.Minute, true},
+		{"waits for chat lines", chatty, map[int]timerPost{2: {at: startedAt, lines: 10}}, 14, true, 20 * time.Minute, false},
+		{"is due after enough chat lines", chatty, map[int]timerPost{2: {at: startedAt, lines: 10}}, 15, true, 20 * time.Minute, true},
+		{"online messages wait for the stream", online, nil, 0, false, time.Hour, false},
+		{"online messages post while live", online, nil, 0, true, time.Hour, true},
+		{"offline messages wait for the stream to end", offline, nil, 0, true, time.Hour, false},
+		{"offline messages post while offline", offline, nil, 0, false, time.Hour, true},
+	} {
+		state := &channelTimers{startedAt: startedAt, lines: test.lines, posts: test.posts}
+		if state.posts == nil {
+			state.posts = map[int]timerPost{}
+		}
+		assert.Equal(t, test.due, state.isDue(test.message, test.live, startedAt.Add(test.after)), test.name)
+	}
+}
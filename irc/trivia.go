@@ -0,0 +1,263 @@
+package irc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	"github.com/soulxburn/soulxbot/db"
+)
+
+const (
+	TRIVIA_ROUND_TIME     = 30 * time.Second
+	TRIVIA_ROUND_PAUSE    = 5 * time.Second
+	DEFAULT_TRIVIA_ROUNDS = 5
+	MAX_TRIVIA_ROUNDS     = 20
+)
+
+// TriviaGame asks timed trivia rounds in chat. As a MessageListener
+// it watches chat for the first correct answer to the current round.
+type TriviaGame struct {
+	DataStore *db.Database
+	ClientIRC *twitchirc.Client
+	sessions  map[string]*triviaSession
+	mu        sync.Mutex
+}
+
+type triviaSession struct {
+	streamUserId int
+	cancel       context.CancelFunc
+	round        *triviaRound
+	points       map[int]int
+	names        map[int]string
+}
+
+type triviaRound struct {
+	question *db.TriviaQuestion
+	done     chan struct{}
+}
+
+// NewTriviaGame
+func NewTriviaGame(dataStore *db.Database, clientIRC *twitchirc.Client) *TriviaGame {
+	return &TriviaGame{
+		DataStore: dataStore,
+		ClientIRC: clientIRC,
+		sessions:  make(map[string]*triviaSession),
+	}
+}
+
+func (t *TriviaGame) GetCommands() []Command {
+	commands := []Command{
+		{"trivia", t.trivia},
+	}
+	return commands
+}
+
+// trivia
+// !trivia start [rounds] [category] | stop | top
+func (t *TriviaGame) trivia(msgCtx MessageContext, command string, input string) {
+	input = msgCtx.FullInput()
+	if msgCtx.StreamUser == nil {
+		return
+	}
+
+	args := strings.Fields(input)
+	if len(args) == 0 {
+		t.ClientIRC.Say(msgCtx.Channel, "Usage: !trivia start [rounds] [category], !trivia stop, !trivia top")
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "start":
+		if msgCtx.IsModerator() {
+			t.start(msgCtx, args[1:])
+		}
+	case "stop":
+		if msgCtx.IsModerator() {
+			t.stop(msgCtx)
+		}
+	case "top":
+		t.top(msgCtx)
+	}
+}
+
+func (t *TriviaGame) start(msgCtx MessageContext, args []string) {
+	rounds := DEFAULT_TRIVIA_ROUNDS
+	if len(args) > 0 {
+		if value, err := strconv.Atoi(args[0]); err == nil {
+			if value < 1 || value > MAX_TRIVIA_ROUNDS {
+				t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("Trivia can be 1 to %d rounds", MAX_TRIVIA_ROUNDS))
+				return
+			}
+			rounds = value
+			args = args[1:]
+		}
+	}
+	category := strings.ToLower(strings.Join(args, " "))
+
+	t.mu.Lock()
+	if _, ok := t.sessions[msgCtx.Channel]; ok {
+		t.mu.Unlock()
+		t.ClientIRC.Say(msgCtx.Channel, "Trivia is already running, !trivia stop to end it")
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &triviaSession{
+		streamUserId: msgCtx.StreamUser.UserId,
+		cancel:       cancel,
+		points:       make(map[int]int),
+		names:        make(map[int]string),
+	}
+	t.sessions[msgCtx.Channel] = session
+	t.mu.Unlock()
+
+	go t.run(ctx, msgCtx.Channel, session, rounds, category)
+}
+
+func (t *TriviaGame) stop(msgCtx MessageContext) {
+	t.mu.Lock()
+	session, ok := t.sessions[msgCtx.Channel]
+	delete(t.sessions, msgCtx.Channel)
+	t.mu.Unlock()
+
+	if ok {
+		session.cancel()
+		t.ClientIRC.Say(msgCtx.Channel, "Trivia stopped")
+	}
+}
+
+func (t *TriviaGame) top(msgCtx MessageContext) {
+	leaders, err := t.DataStore.FindTriviaLeaders(msgCtx.StreamUser.UserId, 3)
+	if err != nil {
+		return
+	}
+	if len(leaders) == 0 {
+		t.ClientIRC.Say(msgCtx.Channel, "Nobody has answered a trivia question yet")
+		return
+	}
+
+	lines := make([]string, len(leaders))
+	for i, leader := range leaders {
+		lines[i] = fmt.Sprintf("%d. %s - %d points", i+1, leader.User.DisplayName, leader.Points)
+	}
+	t.ClientIRC.Say(msgCtx.Channel, strings.Join(lines, " | "))
+}
+
+// run
+// Asks each round in turn until the rounds, or the questions, run out
+func (t *TriviaGame) run(ctx context.Context, channel string, session *triviaSession, rounds int, category string) {
+	defer func() {
+		t.mu.Lock()
+		if t.sessions[channel] == session {
+			delete(t.sessions, channel)
+		}
+		t.mu.Unlock()
+		session.cancel()
+	}()
+
+	asked := []int{}
+	for i := 1; i <= rounds; i++ {
+		question, ok := t.DataStore.FindRandomTrivia(category, asked)
+		if !ok {
+			if i == 1 {
+				t.ClientIRC.Say(channel, "No trivia questions found")
+				return
+			}
+			break
+		}
+		asked = append(asked, question.ID)
+
+		round := &triviaRound{question: question, done: make(chan struct{})}
+		t.mu.Lock()
+		session.round = round
+		t.mu.Unlock()
+
+		label := question.Difficulty
+		if question.Category != "" {
+			label = question.Category + ", " + label
+		}
+		t.ClientIRC.Say(channel, fmt.Sprintf("Trivia %d/%d [%s, %d points]: %s",
+			i, rounds, label, db.TriviaPoints[question.Difficulty], question.Question))
+
+		timer := time.NewTimer(TRIVIA_ROUND_TIME)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-round.done:
+			timer.Stop()
+		case <-timer.C:
+			t.mu.Lock()
+			unanswered := session.round == round
+			if unanswered {
+				session.round = nil
+			}
+			t.mu.Unlock()
+			if unanswered {
+				t.ClientIRC.Say(channel, fmt.Sprintf("Time's up! The answer was %s", question.Answers[0]))
+			}
+		}
+
+		if i < rounds {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(TRIVIA_ROUND_PAUSE):
+			}
+		}
+	}
+
+	t.ClientIRC.Say(channel, triviaSummary(session))
+}
+
+// OnMessage
+// Checks chat messages against the current round, the first correct answer wins it
+func (t *TriviaGame) OnMessage(msgCtx MessageContext) {
+	if msgCtx.MessageUser == nil || strings.HasPrefix(msgCtx.Message, "!") {
+		return
+	}
+
+	t.mu.Lock()
+	session, ok := t.sessions[msgCtx.Channel]
+	if !ok || session.round == nil || !session.round.question.IsCorrect(msgCtx.Message) {
+		t.mu.Unlock()
+		return
+	}
+	round := session.round
+	session.round = nil
+	points := db.TriviaPoints[round.question.Difficulty]
+	session.points[msgCtx.MessageUser.ID] += points
+	session.names[msgCtx.MessageUser.ID] = msgCtx.MessageUser.DisplayName
+	close(round.done)
+	t.mu.Unlock()
+
+	t.DataStore.AddTriviaScore(session.streamUserId, msgCtx.MessageUser.ID, points)
+	t.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s got it! The answer was %s (+%d points)",
+		msgCtx.MessageUser.DisplayName, round.question.Answers[0], points))
+}
+
+func triviaSummary(session *triviaSession) string {
+	if len(session.points) == 0 {
+		return "Trivia over! Nobody scored this time"
+	}
+
+	userIds := make([]int, 0, len(session.points))
+	for userId := range session.points {
+		userIds = append(userIds, userId)
+	}
+	sort.Slice(userIds, func(i, j int) bool { return session.points[userIds[i]] > session.points[userIds[j]] })
+
+	scores := make([]string, 0, 3)
+	for i, userId := range userIds {
+		if i == 3 {
+			break
+		}
+		scores = append(scores, fmt.Sprintf("%s - %d", session.names[userId], session.points[userId]))
+	}
+	return "Trivia over! " + strings.Join(scores, " | ")
+}
@@ -0,0 +1,184 @@
+package irc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	twitchirc "github.com/gempir/go-twitch-irc/v2"
+	"github.com/soulxburn/soulxbot/db"
+	"github.com/soulxburn/soulxbot/twitch"
+)
+
+// How often chat is sampled for watch time. Viewers are only credited for the time between samples
+// up to the max gap, so time the bot was restarting or the viewer was away isn't counted.
+const (
+	WATCH_TIME_SAMPLE_INTERVAL = 2 * time.Minute
+	WATCH_TIME_MAX_GAP         = 2 * WATCH_TIME_SAMPLE_INTERVAL
+)
+
+// Number of viewers shown by !watchtime-top
+const WATCH_TIME_TOP_LIMIT = 5
+
+// Twitch looks up at most this many users at once
+const TWITCH_USERS_PER_REQUEST = 100
+
+// WatchTimeCommands tracks how long viewers watch, lurkers included, by sampling the channel's
+// chatter list as a StreamTask
+type WatchTimeCommands struct {
+	DataStore *db.Database
+	ClientIRC *twitchirc.Client
+	TwitchAPI twitch.ITwitchAPI
+}
+
+func (w *WatchTimeCommands) GetCommands() []Command {
+	commands := []Command{
+		{"watchtime", w.watchtime},
+		{"watchtime-top", w.watchtimeTop},
+	}
+	return commands
+}
+
+// watchtime
+// !watchtime [username]
+func (w *WatchTimeCommands) watchtime(msgCtx MessageContext, command string, input string) {
+	if msgCtx.MessageUser == nil || msgCtx.StreamUser == nil {
+		return
+	}
+	user := msgCtx.MessageUser
+	if username := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(input), "@")); username != "" {
+		found, ok := w.DataStore.FindUserByUsername(username)
+		if !ok {
+			w.ClientIRC.Say(msgCtx.Channel, fmt.Sprintf("%s, I haven't seen %s in chat", msgCtx.MessageUser.DisplayName, input))
+			return
+		}
+		user = found
+	}
+
+	streams, err := w.DataStore.FindUserWatchTime(user.ID, &msgCtx.StreamUser.UserId)
+	if err != nil {
+		return
+	}
+	total, current := 0, 0
+	for _, stream := range streams {
+		total += stream.Seconds
+		if msgCtx.Stream != nil && stream.StreamId == msgCtx.Stream.ID {
+			current = stream.Seconds
+		}
+	}
+
+	message := fmt.Sprintf("%s has watched for %s", user.DisplayName, formatWatchTime(total))
+	if msgCtx.Stream != nil {
+		message += fmt.Sprintf(" (%s this stream)", formatWatchTime(current))
+	}
+	w.ClientIRC.Say(msgCtx.Channel, message)
+}
+
+// watchtimeTop
+// !watchtime-top
+func (w *WatchTimeCommands) watchtimeTop(msgCtx MessageContext, command string, input string) {
+	if msgCtx.StreamUser == nil {
+		return
+	}
+	leaders, err := w.DataStore.FindWatchTimeLeaders(msgCtx.StreamUser.UserId, WATCH_TIME_TOP_LIMIT)
+	if err != nil {
+		return
+	}
+	if len(leaders) == 0 {
+		w.ClientIRC.Say(msgCtx.Channel, "Nobody has any watch time yet")
+		return
+	}
+
+	lines := make([]string, len(leaders))
+	for i, leader := range leaders {
+		lines[i] = fmt.Sprintf("%d. %s - %s", i+1, leader.User.DisplayName, formatWatchTime(leader.Seconds))
+	}
+	w.ClientIRC.Say(msgCtx.Channel, strings.Join(lines, " | "))
+}
+
+// RunStreamTask
+// Samples the chatter list every interval, for as long as the stream is live
+func (w *WatchTimeCommands) RunStreamTask(ctx context.Context, stream *db.Stream, streamUser *db.User) {
+	ticker := time.NewTicker(WATCH_TIME_SAMPLE_INTERVAL)
+	defer ticker.Stop()
+
+	w.sampleChatters(stream, streamUser, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.sampleChatters(stream, streamUser, now)
+		}
+	}
+}
+
+func (w *WatchTimeCommands) sampleChatters(stream *db.Stream, streamUser *db.User, now time.Time) {
+	usernames, err := w.ClientIRC.Userlist(streamUser.Username)
+	if err != nil {
+		return
+	}
+
+	var unknown []string
+	var ids []int
+	for _, username := range usernames {
+		username = strings.ToLower(username)
+		if username == streamUser.Username || w.DataStore.IsUserOnExclusionList(&streamUser.ID, username) {
+			continue
+		}
+		if user, ok := w.DataStore.FindUserByUsername(username); ok {
+			ids = append(ids, user.ID)
+		} else {
+			unknown = append(unknown, username)
+		}
+	}
+	for _, user := range w.lookupUsers(unknown) {
+		ids = append(ids, user.ID)
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+	if err := w.DataStore.AddWatchTime(stream.ID, ids, now, WATCH_TIME_MAX_GAP); err != nil {
+		log.Printf("Error adding watch time for %d chatters in %s: %v", len(ids), streamUser.Username, err)
+	}
+}
+
+// lookupUsers
+// Adds lurkers who have never chatted to the user table, so their watch time can be kept
+func (w *WatchTimeCommands) lookupUsers(usernames []string) []db.User {
+	users := []db.User{}
+	for start := 0; start < len(usernames); start += TWITCH_USERS_PER_REQUEST {
+		end := min(start+TWITCH_USERS_PER_REQUEST, len(usernames))
+		infos, err := w.TwitchAPI.GetUsers(usernames[start:end])
+		if err != nil {
+			log.Println("Error looking up chatters: ", err)
+			continue
+		}
+		for _, info := range infos {
+			id, err := strconv.Atoi(info.Id)
+			if err != nil {
+				continue
+			}
+			if user := w.DataStore.InsertUser(id, info.Login, info.DisplayName); user != nil {
+				users = append(users, *user)
+			}
+		}
+	}
+	return users
+}
+
+// formatWatchTime
+// Formats seconds as hours and minutes, like 3h 25m
+func formatWatchTime(seconds int) string {
+	duration := time.Duration(seconds) * time.Second
+	hours := int(duration.Hours())
+	minutes := int(duration.Minutes()) % 60
+	if hours == 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
@@ -20,7 +20,7 @@ type EndPredictionBody struct {
 	ID               string  `json:"id"`
 	BroadcasterID    string  `json:"broadcaster_id"`
 	Status           string  `json:"status"`
-	WinningOutcomeID *string `json:"winning_outcome_id"`
+	WinningOutcomeID *string `json:"winning_outcome_id,omitempty"`
 }
 
 type TwitchPredictionResponse struct {
@@ -51,6 +51,46 @@ type Outcome struct {
 	Color         string          `json:"color"`
 }
 
+type CreatePollBody struct {
+	BroadcasterID string          `json:"broadcaster_id"`
+	Title         string          `json:"title"`
+	Choices       []NewPollChoice `json:"choices"`
+	Duration      int             `json:"duration"`
+}
+
+type NewPollChoice struct {
+	Title string `json:"title"`
+}
+
+type EndPollBody struct {
+	ID            string `json:"id"`
+	BroadcasterID string `json:"broadcaster_id"`
+	Status        string `json:"status"`
+}
+
+type TwitchPollResponse struct {
+	Data []*TwitchPoll `json:"data"`
+}
+
+type TwitchPoll struct {
+	ID              string       `json:"id"`
+	BroadcasterID   string       `json:"broadcaster_id"`
+	BroadcasterName string       `json:"broadcaster_name"`
+	Title           string       `json:"title"`
+	Choices         []PollChoice `json:"choices"`
+	Status          string       `json:"status"`
+	Duration        int          `json:"duration"`
+	StartedAt       time.Time    `json:"started_at"`
+	EndedAt         *time.Time   `json:"ended_at"`
+}
+
+type PollChoice struct {
+	ID                 string `json:"id"`
+	Title              string `json:"title"`
+	Votes              int    `json:"votes"`
+	ChannelPointsVotes int    `json:"channel_points_votes"`
+}
+
 type TokenResponse struct {
 	AccessToken  string   `json:"access_token"`
 	IDToken      *string  `json:"id_token"`
@@ -98,6 +138,20 @@ type TwitchUserInfo struct {
 	CreatedAt       string `json:"string"`
 }
 
+// TwitchChannelMembersResponse lists a channel's moderators, VIPs or followers, a page at a time
+type TwitchChannelMembersResponse struct {
+	Data       []TwitchChannelMember `json:"data"`
+	Pagination struct {
+		Cursor string `json:"cursor"`
+	} `json:"pagination"`
+}
+
+type TwitchChannelMember struct {
+	UserID    string `json:"user_id"`
+	UserLogin string `json:"user_login"`
+	UserName  string `json:"user_name"`
+}
+
 type BanUserRequest struct {
 	Data TwitchBanUserRequestData `json:"data"`
 }
@@ -107,3 +161,10 @@ type TwitchBanUserRequestData struct {
 	Duration int    `json:"duration,omitempty"`
 	Reason   string `json:"reason,omitempty"`
 }
+
+// TwitchErrorResponse is the body twitch responds with when a helix request fails
+type TwitchErrorResponse struct {
+	Error   string `json:"error"`
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/soulxburn/soulxbot/db"
 )
@@ -19,20 +20,110 @@ const (
 	TWITCH_HELIX_API = "https://api.twitch.tv/helix"
 	TWITCH_OAUTH_API = "https://id.twitch.tv/oauth2"
 	BANS             = "/moderation/bans"
+	MODERATORS       = "/moderation/moderators"
+	VIPS             = "/channels/vips"
+	FOLLOWERS        = "/channels/followers"
 	TOKEN            = "/token"
 	PREDICTIONS      = "/predictions"
+	POLLS            = "/polls"
 	STREAMS          = "/streams"
 	USERS            = "/users"
 	VALIDATE         = "/validate"
 )
 
+// Twitch poll statuses
+const (
+	POLL_ACTIVE     = "ACTIVE"
+	POLL_COMPLETED  = "COMPLETED"
+	POLL_TERMINATED = "TERMINATED"
+	POLL_ARCHIVED   = "ARCHIVED"
+)
+
+// Twitch prediction statuses
+const (
+	PREDICTION_ACTIVE   = "ACTIVE"
+	PREDICTION_LOCKED   = "LOCKED"
+	PREDICTION_RESOLVED = "RESOLVED"
+	PREDICTION_CANCELED = "CANCELED"
+)
+
 type ITwitchAPI interface {
 	GetStream(string) (*TwitchStreamInfo, error)
 	GetUsers([]string) ([]*TwitchUserInfo, error)
 	GetAuthenticatedUser(string) (*TokenResponse, error)
 	CreatePrediction(db.StreamUser, string, int, []string) (*TwitchPrediction, error)
 	EndPrediction(db.StreamUser, *TwitchPrediction, string) error
+	CancelPrediction(db.StreamUser, *TwitchPrediction) error
+	LockPrediction(db.StreamUser, *TwitchPrediction) error
+	GetPredictions(db.StreamUser, []string) ([]*TwitchPrediction, error)
 	TimeoutUser(db.StreamUser, string, int, string) error
+	GetModerators(db.StreamUser) ([]TwitchChannelMember, error)
+	GetVIPs(db.StreamUser) ([]TwitchChannelMember, error)
+	IsFollower(db.StreamUser, int) (bool, error)
+	CreatePoll(db.StreamUser, string, []string, int) (*TwitchPoll, error)
+	EndPoll(db.StreamUser, *TwitchPoll) (*TwitchPoll, error)
+	GetPolls(db.StreamUser, []string) ([]*TwitchPoll, error)
+}
+
+// RateLimitError is returned when twitch rate limits a request, with when the limit resets
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("Rate limited by twitch until %s", e.Reset.Format(time.TimeOnly))
+}
+
+// newRateLimitError
+// Reads when the rate limit resets from the response, or waits a minute if twitch doesn't say
+func newRateLimitError(response *http.Response) *RateLimitError {
+	reset := time.Now().Add(time.Minute)
+	if seconds, err := strconv.ParseInt(response.Header.Get("Ratelimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(seconds, 0)
+	}
+	return &RateLimitError{Reset: reset}
+}
+
+// HelixError is returned when twitch responds to a helix request with an error status
+type HelixError struct {
+	Endpoint   string
+	Status     string
+	StatusCode int
+	Message    string
+}
+
+func (e *HelixError) Error() string {
+	return fmt.Sprintf("Request to %s failed: %s", e.Endpoint, e.Status)
+}
+
+// newHelixError
+// Reads twitch's message about the failed request from the response body
+func newHelixError(endpoint string, response *http.Response, respBody []byte) *HelixError {
+	errorResp := new(TwitchErrorResponse)
+	_ = json.Unmarshal(respBody, errorResp)
+	return &HelixError{
+		Endpoint:   endpoint,
+		Status:     response.Status,
+		StatusCode: response.StatusCode,
+		Message:    errorResp.Message,
+	}
+}
+
+var ErrMissingAuthToken = errors.New("Missing Auth Token")
+
+// PredictionsUnavailable
+// Reports if the error means the channel can't use predictions at all, because it isn't an
+// affiliate or partner, or the broadcaster hasn't given the bot a token with the predictions scope
+func PredictionsUnavailable(err error) bool {
+	if errors.Is(err, ErrMissingAuthToken) {
+		return true
+	}
+	var helixErr *HelixError
+	if !errors.As(err, &helixErr) {
+		return false
+	}
+	return helixErr.StatusCode == http.StatusForbidden ||
+		(helixErr.StatusCode == http.StatusUnauthorized && strings.Contains(strings.ToLower(helixErr.Message), "scope"))
 }
 
 type TwitchAPI struct {
@@ -161,80 +252,200 @@ func (a *TwitchAPI) CreatePrediction(user db.StreamUser, title string, window in
 		requestBody.Outcomes[i] = o
 	}
 
-	body, err := json.Marshal(requestBody)
+	predictions, err := a.predictionRequest(user, http.MethodPost, nil, requestBody)
 	if err != nil {
-		log.Println("Failed to Marshal Body")
+		return nil, err
 	}
+	return predictions[0], nil
+}
 
-	authToken, err := a.getUserAuthToken(user)
+// EndPrediction
+// Ends a twitch prediction
+func (a *TwitchAPI) EndPrediction(user db.StreamUser, prediction *TwitchPrediction, winningID string) error {
+	requestBody := EndPredictionBody{
+		ID:               prediction.ID,
+		BroadcasterID:    prediction.BroadcasterID,
+		Status:           PREDICTION_RESOLVED,
+		WinningOutcomeID: &winningID,
+	}
+
+	_, err := a.predictionRequest(user, http.MethodPatch, nil, requestBody)
+	return err
+}
+
+// CancelPrediction
+// Cancels a twitch prediction, refunding the channel points
+func (a *TwitchAPI) CancelPrediction(user db.StreamUser, prediction *TwitchPrediction) error {
+	requestBody := EndPredictionBody{
+		ID:            prediction.ID,
+		BroadcasterID: prediction.BroadcasterID,
+		Status:        PREDICTION_CANCELED,
+	}
+
+	_, err := a.predictionRequest(user, http.MethodPatch, nil, requestBody)
+	return err
+}
+
+// LockPrediction
+// Stops a twitch prediction from taking any more predictions
+func (a *TwitchAPI) LockPrediction(user db.StreamUser, prediction *TwitchPrediction) error {
+	requestBody := EndPredictionBody{
+		ID:            prediction.ID,
+		BroadcasterID: prediction.BroadcasterID,
+		Status:        PREDICTION_LOCKED,
+	}
+
+	_, err := a.predictionRequest(user, http.MethodPatch, nil, requestBody)
+	return err
+}
+
+// GetPredictions
+// Gets the channel's twitch predictions by id
+func (a *TwitchAPI) GetPredictions(user db.StreamUser, ids []string) ([]*TwitchPrediction, error) {
+	q := url.Values{}
+	q.Add("broadcaster_id", strconv.Itoa(user.UserId))
+	for _, id := range ids {
+		q.Add("id", id)
+	}
+
+	return a.predictionRequest(user, http.MethodGet, q, nil)
+}
+
+// CreatePoll
+// Starts a twitch poll that runs for duration seconds
+func (a *TwitchAPI) CreatePoll(user db.StreamUser, title string, choices []string, duration int) (*TwitchPoll, error) {
+	requestBody := CreatePollBody{
+		BroadcasterID: strconv.Itoa(user.UserId),
+		Title:         title,
+		Choices:       make([]NewPollChoice, len(choices)),
+		Duration:      duration,
+	}
+	for i, choice := range choices {
+		requestBody.Choices[i] = NewPollChoice{Title: choice}
+	}
+
+	polls, err := a.pollRequest(user, http.MethodPost, nil, requestBody)
 	if err != nil {
 		return nil, err
 	}
+	return polls[0], nil
+}
 
-	req, err := http.NewRequest("POST", TWITCH_HELIX_API+PREDICTIONS, bytes.NewReader(body))
-	req.Header.Add("content-type", "application/json")
-	req.Header.Add("Client-Id", a.clientID)
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *authToken))
+// EndPoll
+// Ends a twitch poll early, keeping it visible in the channel with its results
+func (a *TwitchAPI) EndPoll(user db.StreamUser, poll *TwitchPoll) (*TwitchPoll, error) {
+	requestBody := EndPollBody{
+		ID:            poll.ID,
+		BroadcasterID: poll.BroadcasterID,
+		Status:        POLL_TERMINATED,
+	}
 
-	response, err := http.DefaultClient.Do(req)
+	polls, err := a.pollRequest(user, http.MethodPatch, nil, requestBody)
 	if err != nil {
-		if response != nil {
-			response.Body.Close()
-		}
 		return nil, err
 	}
-	defer response.Body.Close()
+	return polls[0], nil
+}
 
-	respBody := make([]byte, response.ContentLength)
-	response.Body.Read(respBody)
+// GetPolls
+// Gets the channel's twitch polls by id
+func (a *TwitchAPI) GetPolls(user db.StreamUser, ids []string) ([]*TwitchPoll, error) {
+	q := url.Values{}
+	q.Add("broadcaster_id", strconv.Itoa(user.UserId))
+	for _, id := range ids {
+		q.Add("id", id)
+	}
+
+	return a.pollRequest(user, http.MethodGet, q, nil)
+}
+
+// predictionRequest
+// Sends a request to the predictions endpoint, returning an error unless twitch responds with predictions
+func (a *TwitchAPI) predictionRequest(user db.StreamUser, method string, query url.Values, requestBody interface{}) ([]*TwitchPrediction, error) {
+	respBody, err := a.helixUserRequest(user, method, PREDICTIONS, query, requestBody)
+	if err != nil {
+		return nil, err
+	}
 
 	predictionResp := new(TwitchPredictionResponse)
-	json.Unmarshal(respBody, predictionResp)
+	if err := json.Unmarshal(respBody, predictionResp); err != nil {
+		return nil, err
+	}
+	if len(predictionResp.Data) == 0 {
+		return nil, errors.New("Prediction request returned no predictions")
+	}
 
-	return predictionResp.Data[0], nil
+	return predictionResp.Data, nil
 }
 
-// EndPrediction
-// Ends a twitch prediction
-func (a *TwitchAPI) EndPrediction(user db.StreamUser, prediction *TwitchPrediction, winningID string) error {
-	requestBody := EndPredictionBody{
-		ID:               prediction.ID,
-		BroadcasterID:    prediction.BroadcasterID,
-		Status:           "RESOLVED",
-		WinningOutcomeID: &winningID,
+// pollRequest
+// Sends a request to the polls endpoint, returning an error unless twitch responds with polls
+func (a *TwitchAPI) pollRequest(user db.StreamUser, method string, query url.Values, requestBody interface{}) ([]*TwitchPoll, error) {
+	respBody, err := a.helixUserRequest(user, method, POLLS, query, requestBody)
+	if err != nil {
+		return nil, err
 	}
 
-	body, err := json.Marshal(requestBody)
-	if err != nil {
-		log.Println("Failed to Marshal Body")
+	pollResp := new(TwitchPollResponse)
+	if err := json.Unmarshal(respBody, pollResp); err != nil {
+		return nil, err
+	}
+	if len(pollResp.Data) == 0 {
+		return nil, errors.New("Poll request returned no polls")
+	}
+
+	return pollResp.Data, nil
+}
+
+// helixUserRequest
+// Sends a request to a helix endpoint as the broadcaster, returning the response body unless twitch responds with an error
+func (a *TwitchAPI) helixUserRequest(user db.StreamUser, method string, endpoint string, query url.Values, requestBody interface{}) ([]byte, error) {
+	body := []byte{}
+	if requestBody != nil {
+		var err error
+		if body, err = json.Marshal(requestBody); err != nil {
+			return nil, err
+		}
 	}
 
 	authToken, err := a.getUserAuthToken(user)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := http.NewRequest("PATCH", TWITCH_HELIX_API+PREDICTIONS, bytes.NewReader(body))
+	req, err := http.NewRequest(method, TWITCH_HELIX_API+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Add("content-type", "application/json")
 	req.Header.Add("Client-Id", a.clientID)
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", *authToken))
+	if query != nil {
+		req.URL.RawQuery = query.Encode()
+	}
 
 	response, err := http.DefaultClient.Do(req)
 	if err != nil {
 		if response != nil {
 			response.Body.Close()
 		}
-		return err
+		return nil, err
 	}
 	defer response.Body.Close()
 
-	respBody := make([]byte, response.ContentLength)
-	response.Body.Read(respBody)
-
-	predictionResp := new(TwitchPredictionResponse)
-	json.Unmarshal(respBody, predictionResp)
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusTooManyRequests {
+		return nil, newRateLimitError(response)
+	}
+	if response.StatusCode != http.StatusOK {
+		log.Printf("%s %s returned non-200 status %s | %s", method, endpoint, response.Status, respBody)
+		return nil, newHelixError(endpoint, response, respBody)
+	}
 
-	return nil
+	return respBody, nil
 }
 
 func (a *TwitchAPI) TimeoutUser(user db.StreamUser, userID string, duration int, reason string) error {
@@ -269,20 +480,85 @@ func (a *TwitchAPI) TimeoutUser(user db.StreamUser, userID string, duration int,
 		}
 		return err
 	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusTooManyRequests {
+		return newRateLimitError(response)
+	}
 	if response.StatusCode != 200 {
 		body, _ := io.ReadAll(response.Body)
 		log.Printf("Timeout user returned non-200 status %s | %s", response.Status, body)
+		return fmt.Errorf("Timeout user failed: %s", response.Status)
 	}
-	defer response.Body.Close()
 
 	return nil
 }
 
+// GetModerators
+// Returns every moderator of the channel
+func (a *TwitchAPI) GetModerators(user db.StreamUser) ([]TwitchChannelMember, error) {
+	return a.channelMembersRequest(user, MODERATORS)
+}
+
+// GetVIPs
+// Returns every VIP of the channel
+func (a *TwitchAPI) GetVIPs(user db.StreamUser) ([]TwitchChannelMember, error) {
+	return a.channelMembersRequest(user, VIPS)
+}
+
+// IsFollower
+// Reports if the user follows the channel
+func (a *TwitchAPI) IsFollower(user db.StreamUser, userID int) (bool, error) {
+	q := url.Values{}
+	q.Add("broadcaster_id", strconv.Itoa(user.UserId))
+	q.Add("user_id", strconv.Itoa(userID))
+
+	respBody, err := a.helixUserRequest(user, http.MethodGet, FOLLOWERS, q, nil)
+	if err != nil {
+		return false, err
+	}
+
+	followers := new(TwitchChannelMembersResponse)
+	if err := json.Unmarshal(respBody, followers); err != nil {
+		return false, err
+	}
+	return len(followers.Data) > 0, nil
+}
+
+// channelMembersRequest
+// Pages through the channel's moderators or VIPs
+func (a *TwitchAPI) channelMembersRequest(user db.StreamUser, endpoint string) ([]TwitchChannelMember, error) {
+	members := []TwitchChannelMember{}
+	cursor := ""
+	for {
+		q := url.Values{}
+		q.Add("broadcaster_id", strconv.Itoa(user.UserId))
+		q.Add("first", "100")
+		if cursor != "" {
+			q.Add("after", cursor)
+		}
+
+		respBody, err := a.helixUserRequest(user, http.MethodGet, endpoint, q, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		page := new(TwitchChannelMembersResponse)
+		if err := json.Unmarshal(respBody, page); err != nil {
+			return nil, err
+		}
+		members = append(members, page.Data...)
+		if page.Pagination.Cursor == "" || len(page.Data) == 0 {
+			return members, nil
+		}
+		cursor = page.Pagination.Cursor
+	}
+}
+
 // getUserAuthToken
 func (a *TwitchAPI) getUserAuthToken(user db.StreamUser) (*string, error) {
 	if user.TwitchAuthToken == nil {
 		log.Printf("User=%d is missing auth token", user.User.ID)
-		return nil, errors.New("Missing Auth Token")
+		return nil, ErrMissingAuthToken
 	}
 
 	authToken, err := db.DecryptToken(*user.TwitchAuthToken, a.keyPhrase)